@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/database"
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/handlers"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/notify"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/reminder"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/pr"
+	sqlitepr "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/sqlite/pr"
+	sqlitestats "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/sqlite/stats"
+	sqliteteam "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/sqlite/team"
+	sqliteuser "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/sqlite/user"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/stats"
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/team"
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository/user"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/selection"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/sla"
 	"github.com/spf13/viper"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -24,11 +37,83 @@ func initConfig() error {
 	viper.AddConfigPath("configs")
 	viper.SetConfigName("config")
 	viper.SetConfigType("yml")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	return viper.ReadInConfig()
 }
 
+type repositories struct {
+	user  repository.UserRepository
+	team  repository.TeamRepository
+	pr    repository.PullRequestRepository
+	stats repository.StatsRepository
+}
+
+// initStorage opens the database selected by db.driver and builds the
+// matching repository implementations.
+func initStorage() (*sql.DB, *repositories, error) {
+	selectionCfg := selection.Config{
+		Mode:          viper.GetString("selection.mode"),
+		HistoryWindow: viper.GetInt("selection.history_window"),
+	}
+	if err := selectionCfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	switch driver := viper.GetString("db.driver"); driver {
+	case "", "postgres":
+		db, err := database.NewPostgresDB()
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, &repositories{
+			user:  user.NewRepository(db),
+			team:  team.NewRepository(db),
+			pr:    pr.NewRepository(db, selectionCfg),
+			stats: stats.NewRepository(db),
+		}, nil
+	case "sqlite":
+		db, err := database.NewSQLiteDB(viper.GetString("db.path"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, &repositories{
+			user:  sqliteuser.NewRepository(db),
+			team:  sqliteteam.NewRepository(db),
+			pr:    sqlitepr.NewRepository(db, selectionCfg),
+			stats: sqlitestats.NewRepository(db),
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown db driver %q", driver)
+	}
+}
+
+// initNotifier builds the channels notifications are delivered through.
+// Chat messages go to the teams with a webhook; email goes out once
+// notifications.smtp.host is set.
+func initNotifier(logger slog.Logger, repos *repositories) (notify.Notifier, error) {
+	channels := notify.Multi{
+		&notify.LogNotifier{Logger: logger},
+		notify.NewChatNotifier(repos.team, repos.user, viper.GetDuration("notifications.chat.timeout")),
+	}
+	if host := viper.GetString("notifications.smtp.host"); host != "" {
+		smtpNotifier, err := notify.NewSMTPNotifier(notify.SMTPConfig{
+			Host:     host,
+			Port:     viper.GetInt("notifications.smtp.port"),
+			Username: viper.GetString("notifications.smtp.username"),
+			Password: viper.GetString("notifications.smtp.password"),
+			From:     viper.GetString("notifications.smtp.from"),
+			Timeout:  viper.GetDuration("notifications.smtp.timeout"),
+		}, repos.user)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, smtpNotifier)
+	}
+	return channels, nil
+}
+
 func main() {
 	if err := initConfig(); err != nil {
 		log.Fatalf("failed to initialize configs: %v", err.Error())
@@ -36,33 +121,72 @@ func main() {
 
 	logger := *slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	db, err := database.NewPostgresDB()
+	db, repos, err := initStorage()
 	if err != nil {
 		log.Fatalf("cannot connect to DB: %v", err)
 	}
 	defer db.Close()
 
-	userRepo := user.NewRepository(db)
-	teamRepo := team.NewRepository(db)
-	prRepo := pr.NewRepository(db)
-	userHandler := handlers.NewUserHandler(logger, userRepo)
-	teamHandler := handlers.NewTeamHandler(logger, teamRepo)
-	prHandler := handlers.NewPullRequestHandler(logger, prRepo)
+	notifier, err := initNotifier(logger, repos)
+	if err != nil {
+		log.Fatalf("failed to initialize notifications: %v", err)
+	}
+
+	userHandler := handlers.NewUserHandler(logger, repos.user)
+	teamHandler := handlers.NewTeamHandler(logger, repos.team)
+	prHandler := handlers.NewPullRequestHandler(logger, repos.pr, notifier)
+	statsHandler := handlers.NewStatsHandler(logger, repos.stats)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"status":"ok"}`))
 	})
+	mux.HandleFunc("POST /users/create", userHandler.Create)
+	mux.HandleFunc("GET /users/get", userHandler.Get)
+	mux.HandleFunc("GET /users/list", userHandler.List)
+	mux.HandleFunc("POST /users/delete", userHandler.Delete)
 	mux.HandleFunc("POST /users/setIsActive", userHandler.SetIsActive)
 	mux.HandleFunc("GET /users/getReview", userHandler.GetReview)
+	mux.HandleFunc("POST /users/update", userHandler.Update)
+	mux.HandleFunc("POST /users/setReviewerPreferences", userHandler.SetReviewerPreferences)
+	mux.HandleFunc("GET /users/getReviewerPreferences", userHandler.GetReviewerPreferences)
+	mux.HandleFunc("POST /users/setSchedule", userHandler.SetSchedule)
+	mux.HandleFunc("GET /users/getSchedule", userHandler.GetSchedule)
+	mux.HandleFunc("POST /users/setNotifications", userHandler.SetNotifications)
+	mux.HandleFunc("GET /users/getNotifications", userHandler.GetNotifications)
 
 	mux.HandleFunc("POST /team/add", teamHandler.Add)
 	mux.HandleFunc("GET /team/get", teamHandler.Get)
+	mux.HandleFunc("GET /team/list", teamHandler.List)
+	mux.HandleFunc("POST /team/update", teamHandler.Update)
+	mux.HandleFunc("POST /team/delete", teamHandler.Delete)
+	mux.HandleFunc("POST /team/setOwners", teamHandler.SetOwners)
+	mux.HandleFunc("GET /team/getOwners", teamHandler.GetOwners)
+	mux.HandleFunc("POST /team/setFallbacks", teamHandler.SetFallbacks)
+	mux.HandleFunc("GET /team/getFallbacks", teamHandler.GetFallbacks)
+	mux.HandleFunc("POST /team/setSla", teamHandler.SetSla)
+	mux.HandleFunc("GET /team/getSla", teamHandler.GetSla)
+	mux.HandleFunc("POST /team/setHolidays", teamHandler.SetHolidays)
+	mux.HandleFunc("GET /team/getHolidays", teamHandler.GetHolidays)
+	mux.HandleFunc("POST /team/setChat", teamHandler.SetChat)
+	mux.HandleFunc("GET /team/getChat", teamHandler.GetChat)
+	mux.HandleFunc("POST /team/import", teamHandler.Import)
 
 	mux.HandleFunc("POST /pullRequest/create", prHandler.Create)
 	mux.HandleFunc("POST /pullRequest/merge", prHandler.Merge)
 	mux.HandleFunc("POST /pullRequest/reassign", prHandler.Reassign)
+	mux.HandleFunc("POST /pullRequest/addReviewer", prHandler.AddReviewer)
+	mux.HandleFunc("POST /pullRequest/removeReviewer", prHandler.RemoveReviewer)
+	mux.HandleFunc("GET /pullRequest/get", prHandler.Get)
+	mux.HandleFunc("GET /pullRequest/list", prHandler.List)
+	mux.HandleFunc("POST /pullRequest/submitReview", prHandler.SubmitReview)
+	mux.HandleFunc("GET /pullRequest/overdue", prHandler.Overdue)
+
+	mux.HandleFunc("GET /stats/reviewers", statsHandler.Reviewers)
+	mux.HandleFunc("GET /stats/pullRequests", statsHandler.PullRequests)
+	mux.HandleFunc("GET /export/assignments", statsHandler.ExportAssignments)
+	mux.HandleFunc("GET /export/pullRequests", statsHandler.ExportPullRequests)
 
 	srv := &http.Server{
 		Addr:         ":" + viper.GetString("server.port"),
@@ -72,6 +196,22 @@ func main() {
 		IdleTimeout:  viper.GetDuration("server.idle_timeout"),
 	}
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if interval := viper.GetDuration("sla.check_interval"); interval > 0 {
+		worker := sla.NewWorker(logger, repos.pr, &sla.LogEmitter{Logger: logger}, interval)
+		worker.Notifier = notifier
+		go worker.Run(workerCtx)
+	}
+	if spec := viper.GetString("reminders.cron"); spec != "" {
+		scheduler, err := reminder.NewScheduler(logger, repos.user, notifier, spec,
+			viper.GetDuration("reminders.stale_after"), viper.GetDuration("reminders.min_interval"))
+		if err != nil {
+			log.Fatalf("failed to initialize reminders: %v", err)
+		}
+		go scheduler.Run(workerCtx)
+	}
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("listen: %s\n", err)
@@ -82,6 +222,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutdown Server ...")
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
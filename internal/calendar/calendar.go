@@ -0,0 +1,137 @@
+// Package calendar measures business time. A Calendar combines a user's
+// timezone and working hours with their team's holidays, so that SLA
+// deadlines only count time the reviewer is expected to be at work.
+package calendar
+
+import (
+	"fmt"
+	"time"
+	// the runtime image ships without a zoneinfo database
+	_ "time/tzdata"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+// DateLayout is how holidays are written.
+const DateLayout = "2006-01-02"
+
+// Calendar tells working time from time off. Without working hours every
+// day but the holidays is worked around the clock; with them only Monday to
+// Friday between the two clock times is.
+type Calendar struct {
+	loc        *time.Location
+	hours      bool
+	start, end int
+	holidays   map[string]bool
+}
+
+// New builds the calendar of a schedule and a list of holidays written as
+// YYYY-MM-DD. The holidays are days in the schedule's timezone.
+func New(schedule model.WorkSchedule, holidays []string) (*Calendar, error) {
+	if err := Validate(schedule); err != nil {
+		return nil, err
+	}
+
+	c := &Calendar{loc: time.UTC, holidays: make(map[string]bool, len(holidays))}
+	if schedule.Timezone != "" {
+		c.loc, _ = time.LoadLocation(schedule.Timezone)
+	}
+	if schedule.WorkStart != "" {
+		c.hours = true
+		c.start, _ = ParseClock(schedule.WorkStart)
+		c.end, _ = ParseClock(schedule.WorkEnd)
+	}
+	for _, day := range holidays {
+		c.holidays[day] = true
+	}
+	return c, nil
+}
+
+// Validate checks that the timezone is known and that the working hours are
+// either both empty or a non-empty span within one day.
+func Validate(schedule model.WorkSchedule) error {
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", schedule.Timezone)
+	}
+	if schedule.WorkStart == "" && schedule.WorkEnd == "" {
+		return nil
+	}
+
+	start, err := ParseClock(schedule.WorkStart)
+	if err != nil {
+		return err
+	}
+	end, err := ParseClock(schedule.WorkEnd)
+	if err != nil {
+		return err
+	}
+	if start >= end {
+		return fmt.Errorf("working hours %s-%s end before they start", schedule.WorkStart, schedule.WorkEnd)
+	}
+	return nil
+}
+
+// ParseClock turns an HH:MM clock time into minutes since midnight.
+func ParseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time %q", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Working tells whether t falls into working time. A nil Calendar is always
+// at work.
+func (c *Calendar) Working(t time.Time) bool {
+	if c == nil {
+		return true
+	}
+	start, end, ok := c.window(t.In(c.loc))
+	return ok && !t.Before(start) && t.Before(end)
+}
+
+// Add returns the moment d of working time after from. Time off in between
+// is skipped, so a deadline never falls on a weekend or a holiday. A nil
+// Calendar counts wall clock time.
+func (c *Calendar) Add(from time.Time, d time.Duration) time.Time {
+	if c == nil {
+		return from.Add(d)
+	}
+	if d <= 0 {
+		return from
+	}
+
+	t := from.In(c.loc)
+	for {
+		start, end, ok := c.window(t)
+		if ok && t.Before(end) {
+			if t.Before(start) {
+				t = start
+			}
+			if left := end.Sub(t); d <= left {
+				return t.Add(d)
+			}
+			d -= end.Sub(t)
+		}
+		y, m, day := t.Date()
+		t = time.Date(y, m, day+1, 0, 0, 0, 0, c.loc)
+	}
+}
+
+// window returns the working time of the day t falls on, in the calendar's
+// timezone. ok is false for days off.
+func (c *Calendar) window(t time.Time) (start, end time.Time, ok bool) {
+	y, m, day := t.Date()
+	if c.holidays[t.Format(DateLayout)] {
+		return start, end, false
+	}
+	if !c.hours {
+		return time.Date(y, m, day, 0, 0, 0, 0, c.loc), time.Date(y, m, day+1, 0, 0, 0, 0, c.loc), true
+	}
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return start, end, false
+	}
+	start = time.Date(y, m, day, c.start/60, c.start%60, 0, 0, c.loc)
+	end = time.Date(y, m, day, c.end/60, c.end%60, 0, 0, c.loc)
+	return start, end, true
+}
@@ -0,0 +1,84 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func TestAddSkipsNightsAndWeekends(t *testing.T) {
+	cal, err := New(model.WorkSchedule{Timezone: "Europe/Berlin", WorkStart: "09:00", WorkEnd: "17:00"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	// Friday 16:00 plus two working hours ends Monday 10:00
+	from := time.Date(2024, 3, 1, 16, 0, 0, 0, berlin)
+	got := cal.Add(from, 2*time.Hour)
+	if want := time.Date(2024, 3, 4, 10, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// assigned at night, the clock starts at 09:00
+	from = time.Date(2024, 3, 5, 3, 0, 0, 0, berlin)
+	got = cal.Add(from, 30*time.Minute)
+	if want := time.Date(2024, 3, 5, 9, 30, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAddSkipsHolidays(t *testing.T) {
+	cal, err := New(model.WorkSchedule{}, []string{"2024-03-02"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	from := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	got := cal.Add(from, 2*time.Hour)
+	if want := time.Date(2024, 3, 3, 1, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWorking(t *testing.T) {
+	cal, err := New(model.WorkSchedule{Timezone: "Asia/Tokyo", WorkStart: "10:00", WorkEnd: "19:00"}, []string{"2024-03-20"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2024, 3, 19, 2, 0, 0, 0, time.UTC), true},   // Tuesday 11:00 in Tokyo
+		{time.Date(2024, 3, 19, 12, 0, 0, 0, time.UTC), false}, // Tuesday 21:00 in Tokyo
+		{time.Date(2024, 3, 20, 2, 0, 0, 0, time.UTC), false},  // holiday
+		{time.Date(2024, 3, 23, 2, 0, 0, 0, time.UTC), false},  // Saturday
+	}
+	for _, tt := range tests {
+		if got := cal.Working(tt.at); got != tt.want {
+			t.Errorf("Working(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		schedule model.WorkSchedule
+		ok       bool
+	}{
+		{model.WorkSchedule{}, true},
+		{model.WorkSchedule{Timezone: "America/New_York", WorkStart: "08:30", WorkEnd: "16:30"}, true},
+		{model.WorkSchedule{Timezone: "Mars/Olympus"}, false},
+		{model.WorkSchedule{WorkStart: "09:00"}, false},
+		{model.WorkSchedule{WorkStart: "18:00", WorkEnd: "09:00"}, false},
+		{model.WorkSchedule{WorkStart: "9am", WorkEnd: "17:00"}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.schedule); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v, want ok %v", tt.schedule, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package cycletime summarizes how long pull requests take: from creation to
+// the first submitted review and to the merge. Durations are bucketed by the
+// week the pull request was created in and summarized as percentiles.
+package cycletime
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/calendar"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+// Summarize groups the pull requests by team or by author and then by week.
+// Groups and weeks come in ascending order.
+func Summarize(prs []*model.PullRequestTimes, groupBy string) []*model.CycleTimeGroup {
+	type week struct {
+		prs                int
+		firstReview, merge []time.Duration
+	}
+	groups := make(map[string]map[string]*week)
+
+	for _, pr := range prs {
+		key := pr.TeamName
+		if groupBy == model.CycleTimeByAuthor {
+			key = pr.AuthorID
+		}
+		if groups[key] == nil {
+			groups[key] = make(map[string]*week)
+		}
+		start := WeekStart(pr.CreatedAt).Format(calendar.DateLayout)
+		w := groups[key][start]
+		if w == nil {
+			w = &week{}
+			groups[key][start] = w
+		}
+
+		w.prs++
+		if pr.FirstReviewAt != nil {
+			w.firstReview = append(w.firstReview, pr.FirstReviewAt.Sub(pr.CreatedAt))
+		}
+		if pr.MergedAt != nil {
+			w.merge = append(w.merge, pr.MergedAt.Sub(pr.CreatedAt))
+		}
+	}
+
+	summary := make([]*model.CycleTimeGroup, 0, len(groups))
+	for key, weeks := range groups {
+		group := &model.CycleTimeGroup{Key: key, Weeks: make([]*model.CycleTimeWeek, 0, len(weeks))}
+		for start, w := range weeks {
+			group.Weeks = append(group.Weeks, &model.CycleTimeWeek{
+				WeekStart:         start,
+				PullRequests:      w.prs,
+				TimeToFirstReview: Percentiles(w.firstReview),
+				TimeToMerge:       Percentiles(w.merge),
+			})
+		}
+		sort.Slice(group.Weeks, func(i, j int) bool { return group.Weeks[i].WeekStart < group.Weeks[j].WeekStart })
+		summary = append(summary, group)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].Key < summary[j].Key })
+	return summary
+}
+
+// WeekStart returns the midnight of the Monday that starts t's week, in UTC.
+func WeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.Date()
+	return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// Percentiles takes the nearest-rank p50, p90 and p99 of the durations,
+// rounded down to seconds. It sorts durations in place.
+func Percentiles(durations []time.Duration) model.DurationPercentiles {
+	if len(durations) == 0 {
+		return model.DurationPercentiles{}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	rank := func(p float64) int64 {
+		i := int(math.Ceil(p/100*float64(len(durations)))) - 1
+		return int64(durations[max(i, 0)] / time.Second)
+	}
+	return model.DurationPercentiles{
+		Count: len(durations),
+		P50:   rank(50),
+		P90:   rank(90),
+		P99:   rank(99),
+	}
+}
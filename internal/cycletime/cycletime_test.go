@@ -0,0 +1,73 @@
+package cycletime
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func TestPercentilesNearestRank(t *testing.T) {
+	durations := make([]time.Duration, 0, 10)
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Minute)
+	}
+
+	got := Percentiles(durations)
+
+	want := model.DurationPercentiles{Count: 10, P50: 300, P90: 540, P99: 600}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if empty := Percentiles(nil); empty != (model.DurationPercentiles{}) {
+		t.Errorf("expected zero percentiles, got %+v", empty)
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	sunday := time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC)
+	if got := WeekStart(sunday); !got.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the Monday before, got %s", got)
+	}
+	monday := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	if got := WeekStart(monday); !got.Equal(monday) {
+		t.Errorf("expected Monday itself, got %s", got)
+	}
+}
+
+func TestSummarizeByTeamAndWeek(t *testing.T) {
+	at := func(day, hour int) *time.Time {
+		t := time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	prs := []*model.PullRequestTimes{
+		{PullRequestID: "pr-1", TeamName: "backend", AuthorID: "u1", CreatedAt: *at(4, 9), FirstReviewAt: at(4, 10), MergedAt: at(5, 9)},
+		{PullRequestID: "pr-2", TeamName: "backend", AuthorID: "u2", CreatedAt: *at(6, 9), FirstReviewAt: at(6, 12)},
+		{PullRequestID: "pr-3", TeamName: "backend", AuthorID: "u1", CreatedAt: *at(11, 9)},
+		{PullRequestID: "pr-4", TeamName: "", AuthorID: "u1", CreatedAt: *at(11, 9), MergedAt: at(11, 10)},
+	}
+
+	groups := Summarize(prs, model.CycleTimeByTeam)
+
+	if len(groups) != 2 || groups[0].Key != "" || groups[1].Key != "backend" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	backend := groups[1]
+	if len(backend.Weeks) != 2 || backend.Weeks[0].WeekStart != "2024-03-04" || backend.Weeks[1].WeekStart != "2024-03-11" {
+		t.Fatalf("unexpected weeks: %+v", backend.Weeks)
+	}
+	first := backend.Weeks[0]
+	if first.PullRequests != 2 ||
+		first.TimeToFirstReview != (model.DurationPercentiles{Count: 2, P50: 3600, P90: 10800, P99: 10800}) ||
+		first.TimeToMerge != (model.DurationPercentiles{Count: 1, P50: 86400, P90: 86400, P99: 86400}) {
+		t.Errorf("unexpected first week: %+v", first)
+	}
+	if second := backend.Weeks[1]; second.PullRequests != 1 || second.TimeToMerge.Count != 0 {
+		t.Errorf("unexpected second week: %+v", second)
+	}
+
+	byAuthor := Summarize(prs, model.CycleTimeByAuthor)
+	if len(byAuthor) != 2 || byAuthor[0].Key != "u1" || len(byAuthor[0].Weeks) != 2 {
+		t.Errorf("unexpected author groups: %+v", byAuthor)
+	}
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateSQLiteIsRepeatable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := NewSQLiteDB(path)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	if err := MigrateSQLite(context.Background(), db); err != nil {
+		t.Fatalf("second migration run failed: %v", err)
+	}
+
+	var statuses int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pull_request_statuses`).Scan(&statuses); err != nil {
+		t.Fatalf("failed to count statuses: %v", err)
+	}
+	if statuses != 2 {
+		t.Errorf("expected 2 statuses, got %d", statuses)
+	}
+}
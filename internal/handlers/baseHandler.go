@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode"
 
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
 )
@@ -12,14 +16,21 @@ var ErrorMap = map[error]struct {
 	Code    string
 	Message string
 }{
-	model.ErrTeamExists:   {"TEAM_EXISTS", "team_name already exists"},
-	model.ErrPrExists:     {"PR_EXISTS", "PR id already exists"},
-	model.ErrPrMerged:     {"PR_MERGED", "cannot reassign on merged PR"},
-	model.ErrNotAssigned:  {"NOT_ASSIGNED", "reviewer is not assigned to this PR"},
-	model.ErrNoCandidate:  {"NO_CANDIDATE", "no active replacement candidate in team"},
-	model.ErrNotFound:     {"NOT_FOUND", "resource not found"},
-	model.ErrInvalidInput: {"INVALID_REQUEST", "invalid request body"},
-	model.ErrMissingParam: {"INVALID_REQUEST", "missing required parameter"},
+	model.ErrTeamExists:      {"TEAM_EXISTS", "team_name already exists"},
+	model.ErrUserExists:      {"USER_EXISTS", "user_id or username already exists"},
+	model.ErrHasOpenPrs:      {"HAS_OPEN_PRS", "user authors open PRs; merge them before deleting the user"},
+	model.ErrPrExists:        {"PR_EXISTS", "PR id already exists"},
+	model.ErrPrMerged:        {"PR_MERGED", "cannot reassign on merged PR"},
+	model.ErrNotAssigned:     {"NOT_ASSIGNED", "reviewer is not assigned to this PR"},
+	model.ErrAlreadyAssigned: {"ALREADY_ASSIGNED", "reviewer is already assigned to this PR"},
+	model.ErrInvalidReviewer: {"INVALID_REVIEWER", "reviewer must be an active user other than the author"},
+	model.ErrNoCandidate:     {"NO_CANDIDATE", "no active replacement candidate in team"},
+	model.ErrNoSenior:        {"NO_SENIOR", "team rule requires a senior reviewer but none is available"},
+	model.ErrNotFound:        {"NOT_FOUND", "resource not found"},
+	model.ErrInvalidInput:    {"INVALID_REQUEST", "invalid request body"},
+	model.ErrMissingParam:    {"INVALID_REQUEST", "missing required parameter"},
+	model.ErrInvalidRules:    {"INVALID_RULES", "ownership rules are malformed"},
+	model.ErrInvalidRoster:   {"INVALID_ROSTER", "team roster is malformed"},
 }
 
 type BaseHandler struct {
@@ -56,3 +67,72 @@ func (h *BaseHandler) WriteJSON(w http.ResponseWriter, v interface{}, status int
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
+
+// maxReviewers is how many reviewers a pull request gets on creation.
+const maxReviewers = 2
+
+// validLevel accepts the known seniority levels and the empty level, which
+// leaves the stored one untouched.
+func validLevel(level string) bool {
+	switch level {
+	case "", model.LevelJunior, model.LevelMiddle, model.LevelSenior:
+		return true
+	}
+	return false
+}
+
+// validEmail accepts a bare email address and the empty one, which leaves
+// the stored address untouched.
+func validEmail(email string) bool {
+	if email == "" {
+		return true
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// validChatHandle accepts a handle without whitespace, and the empty one,
+// which leaves the stored handle untouched.
+func validChatHandle(handle string) bool {
+	return !strings.ContainsFunc(handle, unicode.IsSpace)
+}
+
+// normalizeSkills lowercases and dedupes skill tags, keeping nil as nil so
+// that omitted tags stay untouched. Tags with spaces or commas are rejected.
+func normalizeSkills(skills []string) ([]string, bool) {
+	if skills == nil {
+		return nil, true
+	}
+	seen := make(map[string]bool, len(skills))
+	normalized := make([]string, 0, len(skills))
+	for _, skill := range skills {
+		skill = strings.ToLower(strings.TrimSpace(skill))
+		if skill == "" || strings.ContainsAny(skill, ", \t") {
+			return nil, false
+		}
+		if !seen[skill] {
+			seen[skill] = true
+			normalized = append(normalized, skill)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, true
+}
+
+// normalizeIDs trims and dedupes user ids, keeping their order. Empty ids are
+// rejected.
+func normalizeIDs(ids []string) ([]string, bool) {
+	seen := make(map[string]bool, len(ids))
+	normalized := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			normalized = append(normalized, id)
+		}
+	}
+	return normalized, true
+}
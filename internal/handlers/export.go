@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+const (
+	exportCSV    = "csv"
+	exportNDJSON = "ndjson"
+)
+
+// exportFlushEvery is how many rows are written between flushes, so that the
+// client receives a large export progressively.
+const exportFlushEvery = 500
+
+// exportWriteTimeout is how long the client has to take in each batch of
+// rows. A large export may outlast the server's write timeout, but a client
+// that stops reading is still cut off.
+const exportWriteTimeout = 30 * time.Second
+
+var (
+	assignmentColumns = []string{
+		"pull_request_id", "pull_request_name", "team_name", "author_id", "status",
+		"reviewer_id", "assigned_at", "reviewed_at", "overdue_at",
+	}
+	pullRequestColumns = []string{
+		"pull_request_id", "pull_request_name", "team_name", "author_id", "status",
+		"created_at", "merged_at", "reviewers",
+	}
+)
+
+// exportFormat picks the format from the format parameter or, without one,
+// from the Accept header. CSV is the default; an unknown format parameter
+// yields an empty string.
+func exportFormat(r *http.Request) string {
+	switch format := r.URL.Query().Get("format"); format {
+	case exportCSV, exportNDJSON:
+		return format
+	case "":
+	default:
+		return ""
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return exportCSV
+		case "application/x-ndjson", "application/json":
+			return exportNDJSON
+		}
+	}
+	return exportCSV
+}
+
+// exporter writes the rows of an export as they are read. The response
+// headers are only sent with the first row, so that an error raised before
+// it can still be reported as JSON.
+type exporter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	format  string
+	name    string
+	columns []string
+	csv     *csv.Writer
+	json    *json.Encoder
+	rows    int
+}
+
+func newExporter(w http.ResponseWriter, format, name string, columns []string) *exporter {
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	return &exporter{w: w, rc: rc, format: format, name: name, columns: columns}
+}
+
+func (e *exporter) start() error {
+	if e.csv != nil || e.json != nil {
+		return nil
+	}
+	contentType := "application/x-ndjson"
+	if e.format == exportCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	e.w.Header().Set("Content-Type", contentType)
+	e.w.Header().Set("Content-Disposition", `attachment; filename="`+e.name+"."+e.format+`"`)
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format == exportNDJSON {
+		e.json = json.NewEncoder(e.w)
+		return nil
+	}
+	e.csv = csv.NewWriter(e.w)
+	return e.csv.Write(e.columns)
+}
+
+// write sends one row: record in CSV, v as a JSON line in NDJSON.
+func (e *exporter) write(record []string, v any) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	var err error
+	if e.json != nil {
+		err = e.json.Encode(v)
+	} else {
+		err = e.csv.Write(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushEvery == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// finish sends the headers of an empty export and flushes what is left.
+func (e *exporter) finish() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+func (e *exporter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := e.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	// the next batch gets a deadline of its own
+	if err := e.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+func (e *exporter) started() bool {
+	return e.csv != nil || e.json != nil
+}
+
+// exportTime formats an optional time for CSV, in UTC.
+func exportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ExportAssignments streams the reviewer assignments made in an optional
+// time range as CSV or NDJSON.
+func (h *StatsHandler) ExportAssignments(w http.ResponseWriter, r *http.Request) {
+	filter, format, ok := h.parseExport(w, r)
+	if !ok {
+		return
+	}
+
+	e := newExporter(w, format, "assignments", assignmentColumns)
+	err := h.StatsRepo.ExportAssignments(r.Context(), filter, func(a *model.AssignmentExport) error {
+		return e.write([]string{
+			a.PullRequestID, a.PullRequestName, a.TeamName, a.AuthorID, a.Status,
+			a.ReviewerID, exportTime(&a.AssignedAt), exportTime(a.ReviewedAt), exportTime(a.OverdueAt),
+		}, a)
+	})
+	h.finishExport(w, r, e, err)
+}
+
+// ExportPullRequests streams the pull requests created in an optional time
+// range as CSV or NDJSON. In CSV the reviewers are separated by semicolons.
+func (h *StatsHandler) ExportPullRequests(w http.ResponseWriter, r *http.Request) {
+	filter, format, ok := h.parseExport(w, r)
+	if !ok {
+		return
+	}
+
+	e := newExporter(w, format, "pull_requests", pullRequestColumns)
+	err := h.StatsRepo.ExportPullRequests(r.Context(), filter, func(p *model.PullRequestExport) error {
+		return e.write([]string{
+			p.PullRequestID, p.PullRequestName, p.TeamName, p.AuthorID, p.Status,
+			exportTime(&p.CreatedAt), exportTime(p.MergedAt), strings.Join(p.Reviewers, ";"),
+		}, p)
+	})
+	h.finishExport(w, r, e, err)
+}
+
+func (h *StatsHandler) parseExport(w http.ResponseWriter, r *http.Request) (model.StatsFilter, string, bool) {
+	filter, invalid := parseStatsFilter(r.URL.Query())
+	format := exportFormat(r)
+	if format == "" {
+		invalid = "format"
+	}
+	if invalid != "" {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", invalid), slog.String("query", r.URL.RawQuery))
+		return filter, "", false
+	}
+	return filter, format, true
+}
+
+// finishExport completes the response. Once rows were sent the status can
+// no longer change, so a later failure is only logged and the export ends
+// short.
+func (h *StatsHandler) finishExport(w http.ResponseWriter, r *http.Request, e *exporter, err error) {
+	if err == nil {
+		err = e.finish()
+		if err == nil {
+			h.Logger.Info("API export finished",
+				slog.String("path", r.URL.Path), slog.Int("rows", e.rows), slog.String("query", r.URL.RawQuery))
+			return
+		}
+	}
+
+	if e.started() {
+		h.Logger.Error("API export interrupted",
+			slog.String("path", r.URL.Path), slog.Int("rows", e.rows), slog.String("error", err.Error()))
+		return
+	}
+	status := http.StatusInternalServerError
+	if errors.Is(err, model.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	h.WriteErrorFromMap(w, err, status, slog.String("query", r.URL.RawQuery))
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/mocks"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"go.uber.org/mock/gomock"
+)
+
+func TestExportAssignmentsCSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	assignedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	reviewedAt := assignedAt.Add(time.Hour)
+	mockRepo.EXPECT().
+		ExportAssignments(gomock.Any(), model.StatsFilter{TeamName: "backend"}, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ model.StatsFilter, emit func(*model.AssignmentExport) error) error {
+			return emit(&model.AssignmentExport{
+				PullRequestID: "pr-1", PullRequestName: "Add search, v2", TeamName: "backend", AuthorID: "u1",
+				Status: "OPEN", ReviewerID: "u2", AssignedAt: assignedAt, ReviewedAt: &reviewedAt,
+			})
+		})
+
+	req := httptest.NewRequest("GET", "/export/assignments?team_name=backend", nil)
+	w := httptest.NewRecorder()
+
+	handler.ExportAssignments(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("expected CSV, got %q", ct)
+	}
+	want := "pull_request_id,pull_request_name,team_name,author_id,status,reviewer_id,assigned_at,reviewed_at,overdue_at\n" +
+		`pr-1,"Add search, v2",backend,u1,OPEN,u2,2024-03-01T10:00:00Z,2024-03-01T11:00:00Z,` + "\n"
+	if w.Body.String() != want {
+		t.Errorf("unexpected body:\n%s", w.Body.String())
+	}
+}
+
+func TestExportPullRequestsNDJSONByAccept(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	mockRepo.EXPECT().
+		ExportPullRequests(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ model.StatsFilter, emit func(*model.PullRequestExport) error) error {
+			for _, id := range []string{"pr-1", "pr-2"} {
+				if err := emit(&model.PullRequestExport{PullRequestID: id, Reviewers: []string{"u2", "u3"}}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	req := httptest.NewRequest("GET", "/export/pullRequests", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	handler.ExportPullRequests(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	var pr model.PullRequestExport
+	if err := json.Unmarshal([]byte(lines[1]), &pr); err != nil {
+		t.Fatalf("failed to decode line: %v", err)
+	}
+	if pr.PullRequestID != "pr-2" || len(pr.Reviewers) != 2 {
+		t.Errorf("unexpected pull request: %+v", pr)
+	}
+}
+
+func TestExportTeamNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	mockRepo.EXPECT().
+		ExportAssignments(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(model.ErrNotFound)
+
+	req := httptest.NewRequest("GET", "/export/assignments?team_name=none&format=ndjson", nil)
+	w := httptest.NewRecorder()
+
+	handler.ExportAssignments(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	req := httptest.NewRequest("GET", "/export/pullRequests?format=xml", nil)
+	w := httptest.NewRecorder()
+
+	handler.ExportPullRequests(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
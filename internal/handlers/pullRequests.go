@@ -1,27 +1,61 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/notify"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/pagination"
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
 )
 
 type PullRequestHandler struct {
 	BaseHandler
 	PRRepo repository.PullRequestRepository
+	// Notifier tells reviewers about their assignments; nil sends nothing.
+	Notifier notify.Notifier
 }
 
-func NewPullRequestHandler(logger slog.Logger, prRepo repository.PullRequestRepository) *PullRequestHandler {
+func NewPullRequestHandler(logger slog.Logger, prRepo repository.PullRequestRepository, notifier notify.Notifier) *PullRequestHandler {
 	return &PullRequestHandler{
 		BaseHandler: BaseHandler{Logger: logger},
 		PRRepo:      prRepo,
+		Notifier:    notifier,
 	}
 }
 
+// notify delivers the announcement, when there is one, and the notices in
+// the background, so a slow channel never holds up the response.
+func (h *PullRequestHandler) notify(ctx context.Context, announcement *model.Announcement, notices []*model.Notice) {
+	if h.Notifier == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if announcement != nil {
+			if err := h.Notifier.Announce(ctx, announcement); err != nil {
+				h.Logger.Error("failed to send announcement",
+					slog.String("kind", announcement.Kind),
+					slog.String("pull_request_id", announcement.PullRequest.PullRequestID),
+					slog.String("error", err.Error()))
+			}
+		}
+		for _, notice := range notices {
+			if err := h.Notifier.Notify(ctx, notice); err != nil {
+				h.Logger.Error("failed to send notice",
+					slog.String("kind", notice.Kind),
+					slog.String("user_id", notice.UserID),
+					slog.String("error", err.Error()))
+			}
+		}
+	}()
+}
+
 func (h *PullRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req model.PullRequestPayload
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -36,6 +70,14 @@ func (h *PullRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	skills, ok := normalizeSkills(req.RequiredSkills)
+	if !ok {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "required_skills"))
+		return
+	}
+	req.RequiredSkills = skills
+
 	pr, err := h.PRRepo.Create(r.Context(), req)
 	if err != nil {
 		status := http.StatusInternalServerError
@@ -43,12 +85,16 @@ func (h *PullRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
 			status = http.StatusNotFound
 		} else if errors.Is(err, model.ErrPrExists) {
 			status = http.StatusConflict
+		} else if errors.Is(err, model.ErrNoSenior) {
+			status = http.StatusConflict
 		}
 		h.WriteErrorFromMap(w, err, status,
 			slog.String("pull_request_id", req.PullRequestID))
 		return
 	}
 
+	h.notify(r.Context(), &model.Announcement{Kind: model.AnnounceCreated, PullRequest: pr},
+		notify.Assigned(pr, pr.AssignedReviewers...))
 	h.WriteJSON(w, map[string]any{"pr": pr}, http.StatusCreated,
 		slog.String("pull_request_id", req.PullRequestID))
 }
@@ -70,7 +116,7 @@ func (h *PullRequestHandler) Merge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pr, err := h.PRRepo.Merge(r.Context(), req.PullRequestID)
+	pr, merged, err := h.PRRepo.Merge(r.Context(), req.PullRequestID)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, model.ErrNotFound) {
@@ -81,6 +127,10 @@ func (h *PullRequestHandler) Merge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// merging again is a no-op and is not announced twice
+	if merged {
+		h.notify(r.Context(), &model.Announcement{Kind: model.AnnounceMerged, PullRequest: pr}, nil)
+	}
 	h.WriteJSON(w, map[string]any{"pr": pr}, http.StatusOK,
 		slog.String("pull_request_id", req.PullRequestID))
 }
@@ -89,6 +139,7 @@ func (h *PullRequestHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 	type reqBody struct {
 		PullRequestID string `json:"pull_request_id"`
 		OldUserID     string `json:"old_user_id"`
+		NewUserID     string `json:"new_user_id"`
 	}
 	var req reqBody
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -103,7 +154,13 @@ func (h *PullRequestHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pr, replacedBy, err := h.PRRepo.Reassign(r.Context(), req.PullRequestID, req.OldUserID)
+	if req.NewUserID == req.OldUserID {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "new_user_id"))
+		return
+	}
+
+	pr, replacedBy, err := h.PRRepo.Reassign(r.Context(), req.PullRequestID, req.OldUserID, req.NewUserID)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, model.ErrNotFound) {
@@ -114,6 +171,8 @@ func (h *PullRequestHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 			status = http.StatusConflict
 		} else if errors.Is(err, model.ErrNoCandidate) {
 			status = http.StatusConflict
+		} else if errors.Is(err, model.ErrNoSenior) {
+			status = http.StatusConflict
 		}
 		h.WriteErrorFromMap(w, err, status,
 			slog.String("pull_request_id", req.PullRequestID),
@@ -121,6 +180,12 @@ func (h *PullRequestHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.notify(r.Context(), &model.Announcement{
+		Kind:        model.AnnounceReassigned,
+		PullRequest: pr,
+		Replaced:    req.OldUserID,
+		ReplacedBy:  replacedBy,
+	}, notify.Reassigned(pr, req.OldUserID, replacedBy))
 	h.WriteJSON(w, map[string]any{
 		"pr":          pr,
 		"replaced_by": replacedBy,
@@ -128,3 +193,194 @@ func (h *PullRequestHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 		slog.String("pull_request_id", req.PullRequestID),
 		slog.String("replaced_by", replacedBy))
 }
+
+// AddReviewer assigns an extra reviewer picked by hand.
+func (h *PullRequestHandler) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	h.changeReviewer(w, r, func(ctx context.Context, prID, reviewerID string) (*model.PullRequest, error) {
+		pr, err := h.PRRepo.AddReviewer(ctx, prID, reviewerID)
+		if err == nil {
+			h.notify(ctx, nil, notify.Assigned(pr, reviewerID))
+		}
+		return pr, err
+	})
+}
+
+// RemoveReviewer drops a reviewer without assigning a replacement.
+func (h *PullRequestHandler) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	h.changeReviewer(w, r, h.PRRepo.RemoveReviewer)
+}
+
+// SubmitReview records that a reviewer has reviewed the pull request, which
+// stops their review SLA clock.
+func (h *PullRequestHandler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	h.changeReviewer(w, r, h.PRRepo.SubmitReview)
+}
+
+func (h *PullRequestHandler) changeReviewer(w http.ResponseWriter, r *http.Request,
+	change func(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error)) {
+	type reqBody struct {
+		PullRequestID string `json:"pull_request_id"`
+		UserID        string `json:"user_id"`
+	}
+	var req reqBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if req.PullRequestID == "" || req.UserID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("missing_fields", "pull_request_id or user_id"))
+		return
+	}
+
+	pr, err := change(r.Context(), req.PullRequestID, req.UserID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, model.ErrPrMerged) ||
+			errors.Is(err, model.ErrNotAssigned) ||
+			errors.Is(err, model.ErrAlreadyAssigned) ||
+			errors.Is(err, model.ErrInvalidReviewer) {
+			status = http.StatusConflict
+		}
+		h.WriteErrorFromMap(w, err, status,
+			slog.String("pull_request_id", req.PullRequestID),
+			slog.String("user_id", req.UserID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"pr": pr}, http.StatusOK,
+		slog.String("pull_request_id", req.PullRequestID),
+		slog.String("user_id", req.UserID))
+}
+
+func (h *PullRequestHandler) Get(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	pr, err := h.PRRepo.Get(r.Context(), prID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("pull_request_id", prID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"pr": pr}, http.StatusOK,
+		slog.String("pull_request_id", prID))
+}
+
+// List pages through pull requests. Newest pull requests come first unless
+// sort and order say otherwise; next_cursor fetches the following page.
+func (h *PullRequestHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := model.PullRequestFilter{
+		Status:     query.Get("status"),
+		AuthorID:   query.Get("author_id"),
+		ReviewerID: query.Get("reviewer_id"),
+		TeamName:   query.Get("team_name"),
+		Sort:       query.Get("sort"),
+	}
+
+	invalid := func(field string) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", field), slog.String("query", r.URL.RawQuery))
+	}
+
+	if filter.Status != "" && filter.Status != "OPEN" && filter.Status != "MERGED" {
+		invalid("status")
+		return
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = model.PullRequestSortCreated
+	case model.PullRequestSortCreated, model.PullRequestSortName:
+	default:
+		invalid("sort")
+		return
+	}
+
+	switch query.Get("order") {
+	case "":
+		filter.Desc = filter.Sort == model.PullRequestSortCreated
+	case "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		invalid("order")
+		return
+	}
+
+	bounds := []struct {
+		param string
+		dest  **time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+		{"merged_from", &filter.MergedFrom},
+		{"merged_to", &filter.MergedTo},
+	}
+	for _, b := range bounds {
+		value := query.Get(b.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			invalid(b.param)
+			return
+		}
+		*b.dest = &t
+	}
+
+	var err error
+	if filter.Limit, err = pagination.ParseLimit(query.Get("limit")); err != nil {
+		invalid("limit")
+		return
+	}
+	if filter.After, err = pagination.Decode(query.Get("cursor")); err != nil {
+		invalid("cursor")
+		return
+	}
+
+	page, err := h.PRRepo.List(r.Context(), filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"pull_requests": page.PullRequests,
+		"next_cursor":   pagination.Encode(page.Next),
+	}, http.StatusOK, slog.Int("count", len(page.PullRequests)))
+}
+
+// Overdue lists the reviews that missed their team's SLA, optionally of one
+// team only.
+func (h *PullRequestHandler) Overdue(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+
+	overdue, err := h.PRRepo.ListOverdue(r.Context(), teamName)
+	if err != nil {
+		h.WriteErrorFromMap(w, err, http.StatusInternalServerError,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"overdue": overdue}, http.StatusOK,
+		slog.Int("count", len(overdue)))
+}
@@ -1,3 +1,75 @@
 package handlers
 
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/mocks"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"go.uber.org/mock/gomock"
+)
+
 // TODO (task-4)
+
+type announcements chan *model.Announcement
+
+func (a announcements) Notify(ctx context.Context, notice *model.Notice) error {
+	return nil
+}
+
+func (a announcements) Announce(ctx context.Context, announcement *model.Announcement) error {
+	a <- announcement
+	return nil
+}
+
+func (a announcements) SendDigest(ctx context.Context, digest *model.ReviewDigest) error {
+	return nil
+}
+
+func TestMergeAnnouncesOnlyTheMerge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockPullRequestRepository(ctrl)
+	sent := make(announcements, 2)
+	handler := &PullRequestHandler{
+		BaseHandler: BaseHandler{Logger: *slog.New(slog.NewTextHandler(io.Discard, nil))},
+		PRRepo:      mockRepo,
+		Notifier:    sent,
+	}
+
+	pr := &model.PullRequest{PullRequestShort: model.PullRequestShort{PullRequestID: "pr-1", Status: "MERGED"}}
+	gomock.InOrder(
+		mockRepo.EXPECT().Merge(gomock.Any(), "pr-1").Return(pr, true, nil),
+		mockRepo.EXPECT().Merge(gomock.Any(), "pr-1").Return(pr, false, nil),
+	)
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/merge", bytes.NewBufferString(`{"pull_request_id":"pr-1"}`))
+		w := httptest.NewRecorder()
+		handler.Merge(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+	}
+
+	select {
+	case a := <-sent:
+		if a.Kind != model.AnnounceMerged {
+			t.Errorf("unexpected announcement: %+v", a)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the merge to be announced")
+	}
+	select {
+	case a := <-sent:
+		t.Errorf("expected the repeated merge not to be announced, got %+v", a)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/cycletime"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+)
+
+type StatsHandler struct {
+	BaseHandler
+	StatsRepo repository.StatsRepository
+}
+
+func NewStatsHandler(logger slog.Logger, statsRepo repository.StatsRepository) *StatsHandler {
+	return &StatsHandler{
+		BaseHandler: BaseHandler{Logger: logger},
+		StatsRepo:   statsRepo,
+	}
+}
+
+// parseStatsFilter reads team_name and the RFC 3339 from and to bounds. On
+// failure it returns the offending parameter.
+func parseStatsFilter(query url.Values) (model.StatsFilter, string) {
+	filter := model.StatsFilter{TeamName: query.Get("team_name")}
+
+	bounds := []struct {
+		param string
+		dest  **time.Time
+	}{
+		{"from", &filter.From},
+		{"to", &filter.To},
+	}
+	for _, b := range bounds {
+		value := query.Get(b.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, b.param
+		}
+		*b.dest = &t
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, "to"
+	}
+	return filter, ""
+}
+
+// Reviewers reports the review load of every reviewer, or of one team's
+// members, over an optional time range.
+func (h *StatsHandler) Reviewers(w http.ResponseWriter, r *http.Request) {
+	filter, invalid := parseStatsFilter(r.URL.Query())
+	if invalid != "" {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", invalid), slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	stats, err := h.StatsRepo.ReviewerStats(r.Context(), filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"reviewers": stats}, http.StatusOK,
+		slog.String("query", r.URL.RawQuery))
+}
+
+// PullRequests reports the time to first review and to merge of the pull
+// requests created in an optional time range, as weekly percentiles per team
+// or, with group_by=author, per author.
+func (h *StatsHandler) PullRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, invalid := parseStatsFilter(query)
+	groupBy := query.Get("group_by")
+	switch groupBy {
+	case "":
+		groupBy = model.CycleTimeByTeam
+	case model.CycleTimeByTeam, model.CycleTimeByAuthor:
+	default:
+		invalid = "group_by"
+	}
+	if invalid != "" {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", invalid), slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	times, err := h.StatsRepo.PullRequestTimes(r.Context(), filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"group_by": groupBy,
+		"groups":   cycletime.Summarize(times, groupBy),
+	}, http.StatusOK, slog.String("query", r.URL.RawQuery))
+}
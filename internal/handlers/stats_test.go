@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/mocks"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReviewerStatsSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mockRepo.EXPECT().
+		ReviewerStats(gomock.Any(), model.StatsFilter{TeamName: "backend", From: &from}).
+		Return([]*model.ReviewerStats{{UserID: "u1", Assigned: 2}}, nil)
+
+	req := httptest.NewRequest("GET", "/stats/reviewers?team_name=backend&from=2024-03-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	handler.Reviewers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestReviewerStatsEmptyRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	req := httptest.NewRequest("GET", "/stats/reviewers?from=2024-03-01T00:00:00Z&to=2024-03-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	handler.Reviewers(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestPullRequestStatsGroupsByAuthor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	createdAt := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	mergedAt := createdAt.Add(time.Hour)
+	mockRepo.EXPECT().
+		PullRequestTimes(gomock.Any(), model.StatsFilter{}).
+		Return([]*model.PullRequestTimes{{PullRequestID: "pr-1", AuthorID: "u1", CreatedAt: createdAt, MergedAt: &mergedAt}}, nil)
+
+	req := httptest.NewRequest("GET", "/stats/pullRequests?group_by=author", nil)
+	w := httptest.NewRecorder()
+
+	handler.PullRequests(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Groups []*model.CycleTimeGroup `json:"groups"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Groups) != 1 || resp.Groups[0].Key != "u1" || resp.Groups[0].Weeks[0].TimeToMerge.P50 != 3600 {
+		t.Errorf("unexpected groups: %+v", resp.Groups)
+	}
+}
+
+func TestPullRequestStatsUnknownGrouping(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockStatsRepository(ctrl)
+	handler := &StatsHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		StatsRepo: mockRepo,
+	}
+
+	req := httptest.NewRequest("GET", "/stats/pullRequests?group_by=reviewer", nil)
+	w := httptest.NewRecorder()
+
+	handler.PullRequests(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
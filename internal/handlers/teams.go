@@ -3,10 +3,18 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/calendar"
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/ownership"
 	repository "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/roster"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 )
 
 type TeamHandler struct {
@@ -35,6 +43,27 @@ func (h *TeamHandler) Add(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if team.MinSeniorReviewers < 0 || team.MinSeniorReviewers > maxReviewers {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "min_senior_reviewers"))
+		return
+	}
+
+	for _, m := range team.Members {
+		if m == nil || !validLevel(m.Level) {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("field", "members"))
+			return
+		}
+		skills, ok := normalizeSkills(m.Skills)
+		if !ok {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("user_id", m.UserID), slog.String("field", "skills"))
+			return
+		}
+		m.Skills = skills
+	}
+
 	err := h.TeamRepo.Add(r.Context(), &team)
 	if err != nil {
 		status := http.StatusInternalServerError
@@ -76,3 +105,520 @@ func (h *TeamHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	h.WriteJSON(w, team, http.StatusOK, slog.String("team_name", teamName))
 }
+
+func (h *TeamHandler) List(w http.ResponseWriter, r *http.Request) {
+	teams, err := h.TeamRepo.List(r.Context())
+	if err != nil {
+		h.WriteErrorFromMap(w, err, http.StatusInternalServerError,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"teams": teams}, http.StatusOK,
+		slog.Int("count", len(teams)))
+}
+
+func (h *TeamHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var update model.TeamUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if update.TeamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "team_name"))
+		return
+	}
+
+	if update.NewTeamName == "" && len(update.AddMembers) == 0 && len(update.RemoveMembers) == 0 &&
+		update.MinSeniorReviewers == nil {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("fields", "new_team_name, add_members, remove_members, or min_senior_reviewers"))
+		return
+	}
+
+	if rule := update.MinSeniorReviewers; rule != nil && (*rule < 0 || *rule > maxReviewers) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "min_senior_reviewers"))
+		return
+	}
+
+	for _, m := range update.AddMembers {
+		if m == nil || m.UserID == "" || m.Username == "" || !validLevel(m.Level) {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("field", "add_members"))
+			return
+		}
+		for _, removed := range update.RemoveMembers {
+			if removed == m.UserID {
+				h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+					slog.String("user_id", m.UserID))
+				return
+			}
+		}
+		skills, ok := normalizeSkills(m.Skills)
+		if !ok {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("user_id", m.UserID), slog.String("field", "skills"))
+			return
+		}
+		m.Skills = skills
+	}
+
+	released, err := h.TeamRepo.Update(r.Context(), &update)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, model.ErrTeamExists) {
+			status = http.StatusBadRequest
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", update.TeamName))
+		return
+	}
+
+	teamName := update.TeamName
+	if update.NewTeamName != "" {
+		teamName = update.NewTeamName
+	}
+
+	updatedTeam, err := h.TeamRepo.Get(r.Context(), teamName)
+	if err != nil {
+		h.WriteErrorFromMap(w, err, http.StatusInternalServerError,
+			slog.String("team_name", teamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"team":             updatedTeam,
+		"released_reviews": released,
+	}, http.StatusOK, slog.String("team_name", teamName))
+}
+
+func (h *TeamHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	type reqBody struct {
+		TeamName string `json:"team_name"`
+	}
+	var req reqBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if req.TeamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "team_name"))
+		return
+	}
+
+	released, err := h.TeamRepo.Delete(r.Context(), req.TeamName)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", req.TeamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"team_name":        req.TeamName,
+		"released_reviews": released,
+	}, http.StatusOK, slog.String("team_name", req.TeamName))
+}
+
+// SetOwners replaces the team's ownership rules with an uploaded CODEOWNERS
+// file.
+func (h *TeamHandler) SetOwners(w http.ResponseWriter, r *http.Request) {
+	type reqBody struct {
+		TeamName   string `json:"team_name"`
+		CodeOwners string `json:"codeowners"`
+	}
+	var req reqBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if req.TeamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "team_name"))
+		return
+	}
+
+	rules, err := ownership.Parse(req.CodeOwners)
+	if err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidRules, http.StatusBadRequest,
+			slog.String("team_name", req.TeamName), slog.String("details", err.Error()))
+		return
+	}
+
+	if err := h.TeamRepo.SetOwnershipRules(r.Context(), req.TeamName, rules); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", req.TeamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"team_name": req.TeamName,
+		"rules":     rules,
+	}, http.StatusOK, slog.String("team_name", req.TeamName))
+}
+
+func (h *TeamHandler) GetOwners(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	rules, err := h.TeamRepo.GetOwnershipRules(r.Context(), teamName)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", teamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"team_name":  teamName,
+		"rules":      rules,
+		"codeowners": ownership.Format(rules),
+	}, http.StatusOK, slog.String("team_name", teamName))
+}
+
+// SetFallbacks replaces the chain of teams that lend reviewers when the team
+// cannot fill the reviewer seats itself. An empty chain turns fallback off.
+func (h *TeamHandler) SetFallbacks(w http.ResponseWriter, r *http.Request) {
+	type reqBody struct {
+		TeamName      string   `json:"team_name"`
+		FallbackTeams []string `json:"fallback_teams"`
+	}
+	var req reqBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if req.TeamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "team_name"))
+		return
+	}
+
+	fallbacks, ok := normalizeIDs(req.FallbackTeams)
+	if !ok {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "fallback_teams"))
+		return
+	}
+	for _, fallback := range fallbacks {
+		if fallback == req.TeamName {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("field", "fallback_teams"), slog.String("team_name", req.TeamName))
+			return
+		}
+	}
+
+	if err := h.TeamRepo.SetFallbackTeams(r.Context(), req.TeamName, fallbacks); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", req.TeamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"team_name":      req.TeamName,
+		"fallback_teams": fallbacks,
+	}, http.StatusOK, slog.String("team_name", req.TeamName))
+}
+
+func (h *TeamHandler) GetFallbacks(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	fallbacks, err := h.TeamRepo.GetFallbackTeams(r.Context(), teamName)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", teamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"team_name":      teamName,
+		"fallback_teams": fallbacks,
+	}, http.StatusOK, slog.String("team_name", teamName))
+}
+
+// SetSla configures how soon the team's reviewers must review. sla_minutes of
+// zero turns tracking off.
+func (h *TeamHandler) SetSla(w http.ResponseWriter, r *http.Request) {
+	var sla model.ReviewSLA
+	if err := json.NewDecoder(r.Body).Decode(&sla); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if sla.TeamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "team_name"))
+		return
+	}
+
+	if sla.Minutes < 0 {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "sla_minutes"))
+		return
+	}
+
+	if err := h.TeamRepo.SetReviewSLA(r.Context(), &sla); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", sla.TeamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"sla": sla}, http.StatusOK, slog.String("team_name", sla.TeamName))
+}
+
+func (h *TeamHandler) GetSla(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	sla, err := h.TeamRepo.GetReviewSLA(r.Context(), teamName)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", teamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"sla": sla}, http.StatusOK, slog.String("team_name", teamName))
+}
+
+// SetHolidays replaces the team's holiday calendar. Dates are YYYY-MM-DD and
+// may not repeat.
+func (h *TeamHandler) SetHolidays(w http.ResponseWriter, r *http.Request) {
+	var holidays model.TeamHolidays
+	if err := json.NewDecoder(r.Body).Decode(&holidays); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if holidays.TeamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "team_name"))
+		return
+	}
+
+	seen := make(map[string]bool, len(holidays.Holidays))
+	for _, holiday := range holidays.Holidays {
+		if holiday == nil {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("field", "holidays"))
+			return
+		}
+		if _, err := time.Parse(calendar.DateLayout, holiday.Date); err != nil || seen[holiday.Date] {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("date", holiday.Date))
+			return
+		}
+		seen[holiday.Date] = true
+	}
+	if holidays.Holidays == nil {
+		holidays.Holidays = make([]*model.Holiday, 0)
+	}
+
+	if err := h.TeamRepo.SetHolidays(r.Context(), &holidays); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", holidays.TeamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"holidays": holidays}, http.StatusOK,
+		slog.String("team_name", holidays.TeamName))
+}
+
+func (h *TeamHandler) GetHolidays(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	holidays, err := h.TeamRepo.GetHolidays(r.Context(), teamName)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", teamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"holidays": holidays}, http.StatusOK,
+		slog.String("team_name", teamName))
+}
+
+// SetChat points the team's pull request messages at a Slack or Mattermost
+// incoming webhook. An empty webhook_url stops them.
+func (h *TeamHandler) SetChat(w http.ResponseWriter, r *http.Request) {
+	var chat model.TeamChat
+	if err := json.NewDecoder(r.Body).Decode(&chat); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if chat.TeamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "team_name"))
+		return
+	}
+
+	if chat.WebhookURL != "" {
+		u, err := url.Parse(chat.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("field", "webhook_url"))
+			return
+		}
+	}
+
+	if err := h.TeamRepo.SetChat(r.Context(), &chat); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", chat.TeamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"chat": chat}, http.StatusOK, slog.String("team_name", chat.TeamName))
+}
+
+func (h *TeamHandler) GetChat(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	chat, err := h.TeamRepo.GetChat(r.Context(), teamName)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("team_name", teamName))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"chat": chat}, http.StatusOK, slog.String("team_name", teamName))
+}
+
+// maxRosterBytes caps the size of an imported roster.
+const maxRosterBytes = 1 << 20
+
+// Import syncs the members and seniority rules of one or many teams with a
+// roster sent as the request body, in YAML or, with format=csv or a text/csv
+// body, in CSV. The response lists the changes and the released reviews;
+// with dry_run=true they are only previewed.
+func (h *TeamHandler) Import(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("field", "dry_run"))
+			return
+		}
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = roster.FormatYAML
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+			format = roster.FormatCSV
+		}
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRosterBytes))
+	if err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path), slog.String("details", err.Error()))
+		return
+	}
+
+	teams, err := roster.Parse(content, format)
+	if err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidRoster, http.StatusBadRequest,
+			slog.String("format", format), slog.String("details", err.Error()))
+		return
+	}
+	for _, team := range teams {
+		if rule := team.MinSeniorReviewers; rule != nil && *rule > maxReviewers {
+			h.WriteErrorFromMap(w, model.ErrInvalidRoster, http.StatusBadRequest,
+				slog.String("team_name", team.TeamName), slog.String("field", "min_senior_reviewers"))
+			return
+		}
+		for _, m := range team.Members {
+			skills, ok := normalizeSkills(m.Skills)
+			if !ok {
+				h.WriteErrorFromMap(w, model.ErrInvalidRoster, http.StatusBadRequest,
+					slog.String("user_id", m.UserID), slog.String("field", "skills"))
+				return
+			}
+			m.Skills = skills
+		}
+	}
+
+	diff, released, err := h.TeamRepo.Import(r.Context(), teams, dryRun)
+	if err != nil {
+		h.WriteErrorFromMap(w, err, http.StatusInternalServerError,
+			slog.Int("teams", len(teams)))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{
+		"dry_run":          dryRun,
+		"diff":             diff,
+		"released_reviews": released,
+	}, http.StatusOK, slog.Int("teams", len(teams)), slog.Bool("dry_run", dryRun))
+}
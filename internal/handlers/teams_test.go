@@ -146,3 +146,716 @@ func TestGetTeamNotFound(t *testing.T) {
 		return
 	}
 }
+
+func TestListTeamsSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	mockRepo.EXPECT().
+		List(gomock.Any()).
+		Return([]*model.Team{
+			{TeamName: "backend", Members: []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}}},
+			{TeamName: "frontend", Members: []*model.TeamMember{}},
+		}, nil)
+
+	req := httptest.NewRequest("GET", "/team/list", nil)
+	w := httptest.NewRecorder()
+
+	handler.List(w, req)
+
+	resp := w.Result()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+		return
+	}
+
+	var result map[string]any
+	json.Unmarshal(respBody, &result)
+
+	teams, ok := result["teams"].([]any)
+	if !ok || len(teams) != 2 {
+		t.Errorf("expected 2 teams in response, got %v", result["teams"])
+		return
+	}
+}
+
+func TestUpdateTeamSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	reqBody := map[string]any{
+		"team_name":      "payments",
+		"new_team_name":  "billing",
+		"add_members":    []map[string]any{{"user_id": "u3", "username": "Carol", "is_active": true}},
+		"remove_members": []string{"u2"},
+	}
+	body, _ := json.Marshal(reqBody)
+
+	mockRepo.EXPECT().
+		Update(gomock.Any(), &model.TeamUpdate{
+			TeamName:      "payments",
+			NewTeamName:   "billing",
+			AddMembers:    []*model.TeamMember{{UserID: "u3", Username: "Carol", IsActive: true}},
+			RemoveMembers: []string{"u2"},
+		}).
+		Return([]*model.ReleasedReview{{PullRequestID: "pr-1", UserID: "u2"}}, nil)
+
+	mockRepo.EXPECT().
+		Get(gomock.Any(), "billing").
+		Return(&model.Team{
+			TeamName: "billing",
+			Members: []*model.TeamMember{
+				{UserID: "u1", Username: "Alice", IsActive: true},
+				{UserID: "u3", Username: "Carol", IsActive: true},
+			},
+		}, nil)
+
+	req := httptest.NewRequest("POST", "/team/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	resp := w.Result()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+		return
+	}
+
+	var result map[string]any
+	json.Unmarshal(respBody, &result)
+
+	team, ok := result["team"].(map[string]any)
+	if !ok || team["team_name"] != "billing" {
+		t.Errorf("expected renamed team in response, got %v", result["team"])
+		return
+	}
+
+	released, ok := result["released_reviews"].([]any)
+	if !ok || len(released) != 1 {
+		t.Errorf("expected 1 released review, got %v", result["released_reviews"])
+		return
+	}
+}
+
+func TestUpdateTeamAllMembersRemoved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name":      "payments",
+		"remove_members": []string{"u1"},
+	})
+
+	mockRepo.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Return([]*model.ReleasedReview{}, nil)
+	mockRepo.EXPECT().
+		Get(gomock.Any(), "payments").
+		Return(&model.Team{TeamName: "payments", Members: []*model.TeamMember{}, MinSeniorReviewers: 1}, nil)
+
+	req := httptest.NewRequest("POST", "/team/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestUpdateTeamNothingToDo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"team_name": "payments"})
+
+	req := httptest.NewRequest("POST", "/team/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestUpdateTeamAddAndRemoveSameUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name":      "payments",
+		"add_members":    []map[string]any{{"user_id": "u1", "username": "Alice", "is_active": true}},
+		"remove_members": []string{"u1"},
+	})
+
+	req := httptest.NewRequest("POST", "/team/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestUpdateTeamRenameConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name":     "payments",
+		"new_team_name": "backend",
+	})
+
+	mockRepo.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Return(nil, model.ErrTeamExists)
+
+	req := httptest.NewRequest("POST", "/team/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestDeleteTeamSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"team_name": "payments"})
+
+	mockRepo.EXPECT().
+		Delete(gomock.Any(), "payments").
+		Return([]*model.ReleasedReview{{PullRequestID: "pr-1", UserID: "u2"}}, nil)
+
+	req := httptest.NewRequest("POST", "/team/delete", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Delete(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestDeleteTeamNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"team_name": "none"})
+
+	mockRepo.EXPECT().
+		Delete(gomock.Any(), "none").
+		Return(nil, model.ErrNotFound)
+
+	req := httptest.NewRequest("POST", "/team/delete", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Delete(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestSetOwnersSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name":  "backend",
+		"codeowners": "# owners\n*.go @u2 @acme/platform\n/docs/ @u3\n",
+	})
+
+	mockRepo.EXPECT().
+		SetOwnershipRules(gomock.Any(), "backend", gomock.Any()).
+		DoAndReturn(func(_ any, _ string, rules []*model.OwnershipRule) error {
+			if len(rules) != 2 || rules[0].Line != 2 || rules[1].Pattern != "/docs/" {
+				t.Errorf("unexpected rules: %+v", rules)
+			}
+			return nil
+		})
+
+	req := httptest.NewRequest("POST", "/team/setOwners", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetOwners(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestSetOwnersInvalidRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name":  "backend",
+		"codeowners": "*.go alice",
+	})
+
+	req := httptest.NewRequest("POST", "/team/setOwners", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetOwners(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+		return
+	}
+
+	var result model.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+	if result.Error.Code != "INVALID_RULES" {
+		t.Errorf("expected INVALID_RULES, got %s", result.Error.Code)
+	}
+}
+
+func TestGetOwnersTeamNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	mockRepo.EXPECT().
+		GetOwnershipRules(gomock.Any(), "none").
+		Return(nil, model.ErrNotFound)
+
+	req := httptest.NewRequest("GET", "/team/getOwners?team_name=none", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetOwners(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+		return
+	}
+}
+
+func TestAddInvalidSeniority(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	for _, team := range []map[string]any{
+		{"team_name": "payments", "min_senior_reviewers": 3, "members": []any{}},
+		{"team_name": "payments", "members": []map[string]any{
+			{"user_id": "u1", "username": "Alice", "is_active": true, "level": "lead"},
+		}},
+	} {
+		body, _ := json.Marshal(team)
+
+		req := httptest.NewRequest("POST", "/team/add", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.Add(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	}
+}
+
+func TestSetFallbacksSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name":      "backend",
+		"fallback_teams": []string{" platform", "frontend", "platform"},
+	})
+
+	mockRepo.EXPECT().
+		SetFallbackTeams(gomock.Any(), "backend", []string{"platform", "frontend"}).
+		Return(nil)
+
+	req := httptest.NewRequest("POST", "/team/setFallbacks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetFallbacks(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestSetFallbacksRejectsOwnTeam(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name":      "backend",
+		"fallback_teams": []string{"platform", "backend"},
+	})
+
+	req := httptest.NewRequest("POST", "/team/setFallbacks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetFallbacks(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestGetFallbacksTeamNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	mockRepo.EXPECT().
+		GetFallbackTeams(gomock.Any(), "none").
+		Return(nil, model.ErrNotFound)
+
+	req := httptest.NewRequest("GET", "/team/getFallbacks?team_name=none", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetFallbacks(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", resp.StatusCode)
+	}
+}
+
+func TestSetSlaNegativeMinutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"team_name": "payments", "sla_minutes": -5})
+	req := httptest.NewRequest("POST", "/team/setSla", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetSla(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestSetHolidaysInvalidDate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"team_name": "payments",
+		"holidays":  []map[string]any{{"date": "2024-12-25"}, {"date": "25.12.2024"}},
+	})
+	req := httptest.NewRequest("POST", "/team/setHolidays", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetHolidays(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestSetChatInvalidWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"team_name": "payments", "webhook_url": "chat.example.com/hooks/1"})
+	req := httptest.NewRequest("POST", "/team/setChat", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetChat(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	mockRepo.EXPECT().
+		Import(gomock.Any(), gomock.Any(), true).
+		Return(&model.RosterDiff{
+			CreatedTeams: []string{},
+			Changes: []*model.RosterChange{
+				{Action: model.RosterRemoved, UserID: "u2", TeamName: "backend", IsActive: true},
+				{Action: model.RosterAdded, UserID: "u3", TeamName: "backend", IsActive: true},
+			},
+			SeniorityChanges: []*model.SeniorityChange{},
+		}, []*model.ReleasedReview{{PullRequestID: "pr-1", UserID: "u2"}}, nil)
+
+	body := "team_name,user_id,username\nbackend,u1,Alice\nbackend,u3,Carol\n"
+	req := httptest.NewRequest("POST", "/team/import?dry_run=true", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+
+	handler.Import(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		DryRun   bool                    `json:"dry_run"`
+		Diff     model.RosterDiff        `json:"diff"`
+		Released []*model.ReleasedReview `json:"released_reviews"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.DryRun || len(resp.Diff.Changes) != 2 ||
+		resp.Diff.Changes[0].Action != model.RosterRemoved || resp.Diff.Changes[1].Action != model.RosterAdded {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(resp.Released) != 1 || resp.Released[0].PullRequestID != "pr-1" {
+		t.Errorf("expected the released reviews to be previewed, got %+v", resp.Released)
+	}
+}
+
+func TestImportApply(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	one := 1
+	mockRepo.EXPECT().
+		Import(gomock.Any(), []*model.RosterTeam{{
+			TeamName:           "backend",
+			Members:            []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true, Skills: []string{"go"}}},
+			MinSeniorReviewers: &one,
+		}}, false).
+		Return(&model.RosterDiff{}, []*model.ReleasedReview{}, nil)
+
+	body := "teams:\n  - team_name: backend\n    min_senior_reviewers: 1\n    members:\n      - {user_id: u1, username: Alice, skills: [Go]}\n"
+	req := httptest.NewRequest("POST", "/team/import", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	handler.Import(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestImportTooManySeniors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	body := "teams:\n  - team_name: backend\n    min_senior_reviewers: 9\n    members: []\n"
+	req := httptest.NewRequest("POST", "/team/import", bytes.NewReader([]byte(body)))
+	w := httptest.NewRecorder()
+
+	handler.Import(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestImportInvalidRoster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTeamRepository(ctrl)
+	handler := &TeamHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		TeamRepo: mockRepo,
+	}
+
+	req := httptest.NewRequest("POST", "/team/import?format=csv", bytes.NewReader([]byte("user_id,username\nu1,Alice\n")))
+	w := httptest.NewRecorder()
+
+	handler.Import(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
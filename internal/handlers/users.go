@@ -3,10 +3,13 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/calendar"
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/pagination"
 	repository "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
 	"log/slog"
 	"net/http"
+	"strings"
 )
 
 type UserHandler struct {
@@ -21,10 +24,191 @@ func NewUserHandler(logger slog.Logger, userRepo repository.UserRepository) *Use
 	}
 }
 
+// Create adds a user outside of any team import. The user is active unless
+// is_active is false, and joins team_name when it is given. A max_open_reviews
+// of 0, like an omitted one, leaves the user without a cap.
+func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
+	type reqBody struct {
+		UserID         string   `json:"user_id"`
+		Username       string   `json:"username"`
+		Email          string   `json:"email"`
+		ChatHandle     string   `json:"chat_handle"`
+		TeamName       string   `json:"team_name"`
+		IsActive       *bool    `json:"is_active"`
+		Skills         []string `json:"skills"`
+		Level          string   `json:"level"`
+		MaxOpenReviews *int     `json:"max_open_reviews"`
+	}
+	var req reqBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if req.UserID == "" || req.Username == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("fields", "user_id and username"))
+		return
+	}
+
+	if req.MaxOpenReviews != nil && *req.MaxOpenReviews < 0 {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "max_open_reviews"))
+		return
+	}
+	if req.MaxOpenReviews != nil && *req.MaxOpenReviews == 0 {
+		req.MaxOpenReviews = nil
+	}
+
+	if !validLevel(req.Level) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "level"))
+		return
+	}
+
+	if !validEmail(req.Email) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "email"))
+		return
+	}
+
+	req.ChatHandle = strings.TrimPrefix(req.ChatHandle, "@")
+	if !validChatHandle(req.ChatHandle) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "chat_handle"))
+		return
+	}
+
+	skills, ok := normalizeSkills(req.Skills)
+	if !ok {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "skills"))
+		return
+	}
+
+	user := &model.User{
+		TeamMember: model.TeamMember{
+			UserID:   req.UserID,
+			Username: req.Username,
+			IsActive: req.IsActive == nil || *req.IsActive,
+			Skills:   skills,
+			Level:    req.Level,
+		},
+		TeamName:       req.TeamName,
+		MaxOpenReviews: req.MaxOpenReviews,
+		Email:          req.Email,
+		ChatHandle:     req.ChatHandle,
+	}
+	created, err := h.UserRepo.Create(r.Context(), user)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, model.ErrUserExists):
+			status = http.StatusBadRequest
+		case errors.Is(err, model.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", req.UserID))
+		return
+	}
+	h.WriteJSON(w, map[string]any{"user": created}, http.StatusCreated, slog.String("user_id", req.UserID))
+}
+
+func (h *UserHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	user, err := h.UserRepo.Get(r.Context(), userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", userID))
+		return
+	}
+	h.WriteJSON(w, map[string]any{"user": user}, http.StatusOK, slog.String("user_id", userID))
+}
+
+// List returns users, optionally only the members of team_name and only
+// those whose is_active flag matches.
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := model.UserFilter{TeamName: query.Get("team_name")}
+
+	switch query.Get("is_active") {
+	case "":
+	case "true":
+		active := true
+		filter.IsActive = &active
+	case "false":
+		active := false
+		filter.IsActive = &active
+	default:
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "is_active"), slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	users, err := h.UserRepo.List(r.Context(), filter)
+	if err != nil {
+		h.WriteErrorFromMap(w, err, http.StatusInternalServerError,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"users": users}, http.StatusOK,
+		slog.Int("count", len(users)))
+}
+
+// Delete retires a user who authors no OPEN pull requests: they are
+// deactivated, leave all their teams and their open review seats are
+// released. Their pull requests and reviews stay on record, but the user is
+// no longer listed and a repeated delete answers NOT_FOUND. Creating the
+// same user_id again brings the record back.
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	type reqBody struct {
+		UserID string `json:"user_id"`
+	}
+	var req reqBody
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if req.UserID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "user_id"))
+		return
+	}
+
+	deletion, err := h.UserRepo.Delete(r.Context(), req.UserID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, model.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, model.ErrHasOpenPrs):
+			status = http.StatusConflict
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", req.UserID))
+		return
+	}
+	h.WriteJSON(w, deletion, http.StatusOK, slog.String("user_id", req.UserID))
+}
+
 func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 	type reqBody struct {
 		UserID   string `json:"user_id"`
 		IsActive bool   `json:"is_active"`
+		// TeamName limits the change to one team membership
+		TeamName string `json:"team_name"`
 	}
 	var req reqBody
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -33,7 +217,15 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.UserRepo.SetIsActive(r.Context(), req.UserID, req.IsActive)
+	var (
+		user *model.User
+		err  error
+	)
+	if req.TeamName != "" {
+		user, err = h.UserRepo.SetTeamIsActive(r.Context(), req.UserID, req.TeamName, req.IsActive)
+	} else {
+		user, err = h.UserRepo.SetIsActive(r.Context(), req.UserID, req.IsActive)
+	}
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, model.ErrNotFound) {
@@ -45,6 +237,81 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 	h.WriteJSON(w, map[string]any{"user": user}, http.StatusOK, slog.String("user_id", req.UserID))
 }
 
+// Update changes a user's name, email, chat_handle, level, skill tags,
+// max_open_reviews and primary team. Omitted fields are left alone; an empty
+// skills list clears the tags and a max_open_reviews of 0 lifts the cap.
+func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var update model.UserUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if update.UserID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "user_id"))
+		return
+	}
+
+	if update.Username == "" && update.Email == "" && update.ChatHandle == "" && update.TeamName == "" &&
+		update.Skills == nil && update.Level == "" && update.MaxOpenReviews == nil {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("fields", "username, email, chat_handle, team_name, skills, level, or max_open_reviews"))
+		return
+	}
+
+	if update.MaxOpenReviews != nil && *update.MaxOpenReviews < 0 {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "max_open_reviews"))
+		return
+	}
+
+	if !validLevel(update.Level) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "level"))
+		return
+	}
+
+	if !validEmail(update.Email) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "email"))
+		return
+	}
+
+	update.ChatHandle = strings.TrimPrefix(update.ChatHandle, "@")
+	if !validChatHandle(update.ChatHandle) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "chat_handle"))
+		return
+	}
+
+	skills, ok := normalizeSkills(update.Skills)
+	if !ok {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "skills"))
+		return
+	}
+	update.Skills = skills
+
+	user, released, err := h.UserRepo.Update(r.Context(), &update)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, model.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, model.ErrUserExists):
+			status = http.StatusBadRequest
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", update.UserID))
+		return
+	}
+	h.WriteJSON(w, map[string]any{
+		"user":             user,
+		"released_reviews": released,
+	}, http.StatusOK, slog.String("user_id", update.UserID))
+}
+
 func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
@@ -53,7 +320,36 @@ func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prs, err := h.UserRepo.GetReview(r.Context(), userID)
+	query := r.URL.Query()
+	filter := model.ReviewFilter{Status: query.Get("status")}
+
+	invalid := func(field string) {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", field), slog.String("query", r.URL.RawQuery))
+	}
+
+	switch filter.Status {
+	case "":
+		filter.Status = "OPEN"
+	case "OPEN", "MERGED":
+	case "ALL":
+		filter.Status = ""
+	default:
+		invalid("status")
+		return
+	}
+
+	var err error
+	if filter.Limit, err = pagination.ParseLimit(query.Get("limit")); err != nil {
+		invalid("limit")
+		return
+	}
+	if filter.After, err = pagination.Decode(query.Get("cursor")); err != nil {
+		invalid("cursor")
+		return
+	}
+
+	load, err := h.UserRepo.GetReviewLoad(r.Context(), userID)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, model.ErrNotFound) {
@@ -62,8 +358,232 @@ func (h *UserHandler) GetReview(w http.ResponseWriter, r *http.Request) {
 		h.WriteErrorFromMap(w, err, status, slog.String("user_id", userID))
 		return
 	}
+
+	page, err := h.UserRepo.GetReview(r.Context(), userID, filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, model.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, model.ErrInvalidInput):
+			status = http.StatusBadRequest
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", userID))
+		return
+	}
 	h.WriteJSON(w, map[string]any{
-		"user_id":       userID,
-		"pull_requests": prs,
+		"user_id":          userID,
+		"pull_requests":    page.Reviews,
+		"next_cursor":      pagination.Encode(page.Next),
+		"open_reviews":     load.OpenReviews,
+		"max_open_reviews": load.MaxOpenReviews,
 	}, http.StatusOK, slog.String("user_id", userID))
 }
+
+func (h *UserHandler) SetReviewerPreferences(w http.ResponseWriter, r *http.Request) {
+	var prefs model.ReviewerPreferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if prefs.UserID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "user_id"))
+		return
+	}
+
+	blocked, ok := normalizeIDs(prefs.Blocked)
+	if !ok {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "blocked"))
+		return
+	}
+	preferred, ok := normalizeIDs(prefs.Preferred)
+	if !ok {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("field", "preferred"))
+		return
+	}
+
+	// a reviewer is either blocked or preferred, and never the author
+	listed := map[string]bool{prefs.UserID: true}
+	for _, id := range append(blocked, preferred...) {
+		if listed[id] {
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("user_id", id))
+			return
+		}
+		listed[id] = true
+	}
+	prefs.Blocked, prefs.Preferred = blocked, preferred
+
+	if err := h.UserRepo.SetReviewerPreferences(r.Context(), &prefs); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", prefs.UserID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"preferences": prefs}, http.StatusOK,
+		slog.String("user_id", prefs.UserID))
+}
+
+func (h *UserHandler) GetReviewerPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	prefs, err := h.UserRepo.GetReviewerPreferences(r.Context(), userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", userID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"preferences": prefs}, http.StatusOK,
+		slog.String("user_id", userID))
+}
+
+// SetSchedule sets the user's timezone and working hours. Both work_start and
+// work_end or neither must be given; without them the user counts as always
+// at work.
+func (h *UserHandler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	var schedule model.WorkSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if schedule.UserID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "user_id"))
+		return
+	}
+
+	if err := calendar.Validate(schedule); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("user_id", schedule.UserID), slog.String("reason", err.Error()))
+		return
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+
+	if err := h.UserRepo.SetSchedule(r.Context(), &schedule); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", schedule.UserID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"schedule": schedule}, http.StatusOK,
+		slog.String("user_id", schedule.UserID))
+}
+
+func (h *UserHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	schedule, err := h.UserRepo.GetSchedule(r.Context(), userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", userID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"schedule": schedule}, http.StatusOK,
+		slog.String("user_id", userID))
+}
+
+// SetNotifications replaces the notification kinds the user opted out of.
+func (h *UserHandler) SetNotifications(w http.ResponseWriter, r *http.Request) {
+	var settings model.NotificationSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+			slog.String("path", r.URL.Path))
+		return
+	}
+
+	if settings.UserID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("field", "user_id"))
+		return
+	}
+
+	seen := make(map[string]bool, len(settings.OptOut))
+	optOut := make([]string, 0, len(settings.OptOut))
+	for _, kind := range settings.OptOut {
+		switch kind {
+		case model.NoticeAssigned, model.NoticeReplaced, model.NoticeDigest:
+		default:
+			h.WriteErrorFromMap(w, model.ErrInvalidInput, http.StatusBadRequest,
+				slog.String("field", "opt_out"), slog.String("kind", kind))
+			return
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			optOut = append(optOut, kind)
+		}
+	}
+	settings.OptOut = optOut
+
+	if err := h.UserRepo.SetNotificationSettings(r.Context(), &settings); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", settings.UserID))
+		return
+	}
+
+	stored, err := h.UserRepo.GetNotificationSettings(r.Context(), settings.UserID)
+	if err != nil {
+		h.WriteErrorFromMap(w, err, http.StatusInternalServerError, slog.String("user_id", settings.UserID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"notifications": stored}, http.StatusOK,
+		slog.String("user_id", settings.UserID))
+}
+
+func (h *UserHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.WriteErrorFromMap(w, model.ErrMissingParam, http.StatusBadRequest,
+			slog.String("query", r.URL.RawQuery))
+		return
+	}
+
+	settings, err := h.UserRepo.GetNotificationSettings(r.Context(), userID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, model.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		h.WriteErrorFromMap(w, err, status, slog.String("user_id", userID))
+		return
+	}
+
+	h.WriteJSON(w, map[string]any{"notifications": settings}, http.StatusOK,
+		slog.String("user_id", userID))
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/mocks"
 	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/pagination"
 	"go.uber.org/mock/gomock"
 )
 
@@ -74,6 +75,56 @@ func TestSetIsActiveSuccess(t *testing.T) {
 	}
 }
 
+func TestSetIsActiveForTeam(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	expectedUser := &model.User{
+		TeamMember: model.TeamMember{UserID: "u1", Username: "Alice", IsActive: true},
+		TeamName:   "backend",
+		Teams: []*model.Membership{
+			{TeamName: "backend", IsActive: true},
+			{TeamName: "platform", IsActive: false},
+		},
+	}
+
+	mockRepo.
+		EXPECT().
+		SetTeamIsActive(gomock.Any(), "u1", "platform", false).
+		Return(expectedUser, nil)
+
+	body, _ := json.Marshal(map[string]any{
+		"user_id":   "u1",
+		"team_name": "platform",
+		"is_active": false,
+	})
+
+	req := httptest.NewRequest("POST", "/users/setIsActive", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetIsActive(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	var result struct {
+		User model.User `json:"user"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if len(result.User.Teams) != 2 || result.User.Teams[1].IsActive {
+		t.Errorf("unexpected teams: %+v", result.User.Teams)
+	}
+}
+
 func TestSetIsActiveUserNotFound(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -121,25 +172,28 @@ func TestGetReviewSuccess(t *testing.T) {
 		UserRepo: mockRepo,
 	}
 
-	expectedPRs := []*model.PullRequestShort{
+	expectedPRs := []*model.ReviewAssignment{
 		{
-			PullRequestID:   "pr-1001",
-			PullRequestName: "Add search",
-			AuthorID:        "u1",
-			Status:          "OPEN",
-		},
-		{
-			PullRequestID:   "pr-1002",
-			PullRequestName: "Fix bug",
-			AuthorID:        "u2",
-			Status:          "MERGED",
+			PullRequestShort: model.PullRequestShort{
+				PullRequestID:   "pr-1001",
+				PullRequestName: "Add search",
+				AuthorID:        "u1",
+				Status:          "OPEN",
+			},
+			AgeSeconds: 3600,
 		},
 	}
 
+	capacity := 3
+	mockRepo.
+		EXPECT().
+		GetReviewLoad(gomock.Any(), "u1").
+		Return(&model.ReviewLoad{OpenReviews: 1, MaxOpenReviews: &capacity}, nil)
+
 	mockRepo.
 		EXPECT().
-		GetReview(gomock.Any(), "u1").
-		Return(expectedPRs, nil)
+		GetReview(gomock.Any(), "u1", model.ReviewFilter{Status: "OPEN", Limit: pagination.DefaultLimit}).
+		Return(&model.ReviewPage{Reviews: expectedPRs, Next: &model.Cursor{Key: "k", ID: "pr-1001"}}, nil)
 
 	req := httptest.NewRequest("GET", "/users/getReview?user_id=u1", nil)
 	w := httptest.NewRecorder()
@@ -161,6 +215,39 @@ func TestGetReviewSuccess(t *testing.T) {
 		t.Errorf("expected user_id u1, got %v", result["user_id"])
 		return
 	}
+	if result["open_reviews"] != 1.0 || result["max_open_reviews"] != 3.0 {
+		t.Errorf("unexpected load: %v of %v", result["open_reviews"], result["max_open_reviews"])
+	}
+	if result["next_cursor"] == "" {
+		t.Error("expected a next cursor")
+	}
+	prs, _ := result["pull_requests"].([]any)
+	if len(prs) != 1 || prs[0].(map[string]any)["age_seconds"] != 3600.0 {
+		t.Errorf("unexpected pull requests: %v", result["pull_requests"])
+	}
+}
+
+func TestGetReviewInvalidStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	req := httptest.NewRequest("GET", "/users/getReview?user_id=u1&status=CLOSED", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetReview(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+	}
 }
 
 func TestGetReviewMissingUserID(t *testing.T) {
@@ -201,7 +288,7 @@ func TestGetReviewUserNotFound(t *testing.T) {
 
 	mockRepo.
 		EXPECT().
-		GetReview(gomock.Any(), "none").
+		GetReviewLoad(gomock.Any(), "none").
 		Return(nil, model.ErrNotFound)
 
 	req := httptest.NewRequest("GET", "/users/getReview?user_id=none", nil)
@@ -215,3 +302,462 @@ func TestGetReviewUserNotFound(t *testing.T) {
 		return
 	}
 }
+
+func TestUpdateUserSkills(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	mockRepo.
+		EXPECT().
+		Update(gomock.Any(), &model.UserUpdate{UserID: "u1", Skills: []string{"go", "terraform"}}).
+		Return(&model.User{
+			TeamMember: model.TeamMember{UserID: "u1", Username: "Alice", IsActive: true, Skills: []string{"go", "terraform"}},
+		}, []*model.ReleasedReview{}, nil)
+
+	body, _ := json.Marshal(map[string]any{
+		"user_id": "u1",
+		"skills":  []string{"Terraform", " go", "go"},
+	})
+
+	req := httptest.NewRequest("POST", "/users/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestUpdateUserInvalidSkill(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"user_id": "u1",
+		"skills":  []string{"go,rust"},
+	})
+
+	req := httptest.NewRequest("POST", "/users/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestUpdateUserNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	mockRepo.
+		EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Return(nil, nil, model.ErrNotFound)
+
+	body, _ := json.Marshal(map[string]any{"user_id": "none", "username": "Ghost"})
+
+	req := httptest.NewRequest("POST", "/users/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestUpdateUserNegativeCapacity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"user_id":          "u1",
+		"max_open_reviews": -1,
+	})
+
+	req := httptest.NewRequest("POST", "/users/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestSetReviewerPreferencesSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	mockRepo.
+		EXPECT().
+		SetReviewerPreferences(gomock.Any(), &model.ReviewerPreferences{
+			UserID:    "u1",
+			Blocked:   []string{"u2"},
+			Preferred: []string{"u3"},
+		}).
+		Return(nil)
+
+	body, _ := json.Marshal(map[string]any{
+		"user_id":   "u1",
+		"blocked":   []string{"u2", " u2"},
+		"preferred": []string{"u3"},
+	})
+
+	req := httptest.NewRequest("POST", "/users/setReviewerPreferences", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetReviewerPreferences(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestSetReviewerPreferencesConflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	for _, prefs := range []map[string]any{
+		{"user_id": "u1", "blocked": []string{"u2"}, "preferred": []string{"u2"}},
+		{"user_id": "u1", "preferred": []string{"u1"}},
+	} {
+		body, _ := json.Marshal(prefs)
+
+		req := httptest.NewRequest("POST", "/users/setReviewerPreferences", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		handler.SetReviewerPreferences(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", w.Code)
+		}
+	}
+}
+
+func TestGetReviewerPreferencesNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	mockRepo.
+		EXPECT().
+		GetReviewerPreferences(gomock.Any(), "none").
+		Return(nil, model.ErrNotFound)
+
+	req := httptest.NewRequest("GET", "/users/getReviewerPreferences?user_id=none", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetReviewerPreferences(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestCreateUserDefaultsToActive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	expected := &model.User{
+		TeamMember: model.TeamMember{UserID: "u1", Username: "Alice", IsActive: true, Skills: []string{"go"}},
+		TeamName:   "backend",
+	}
+	mockRepo.
+		EXPECT().
+		Create(gomock.Any(), expected).
+		Return(expected, nil)
+
+	body, _ := json.Marshal(map[string]any{
+		"user_id":   "u1",
+		"username":  "Alice",
+		"team_name": "backend",
+		"skills":    []string{" Go"},
+		// no cap, as in /users/update
+		"max_open_reviews": 0,
+	})
+	req := httptest.NewRequest("POST", "/users/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Create(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateUserExists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	mockRepo.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(nil, model.ErrUserExists)
+
+	body, _ := json.Marshal(map[string]any{"user_id": "u1", "username": "Alice", "is_active": false})
+	req := httptest.NewRequest("POST", "/users/create", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Create(w, req)
+
+	resp := w.Result()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+	}
+	if !bytes.Contains(respBody, []byte("USER_EXISTS")) {
+		t.Errorf("expected USER_EXISTS, got %s", respBody)
+	}
+}
+
+func TestListUsersInvalidIsActive(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	req := httptest.NewRequest("GET", "/users/list?is_active=yes", nil)
+	w := httptest.NewRecorder()
+
+	handler.List(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestDeleteUserHasOpenPrs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	mockRepo.
+		EXPECT().
+		Delete(gomock.Any(), "u1").
+		Return(nil, model.ErrHasOpenPrs)
+
+	body, _ := json.Marshal(map[string]any{"user_id": "u1"})
+	req := httptest.NewRequest("POST", "/users/delete", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Delete(w, req)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", resp.StatusCode)
+	}
+}
+
+func TestSetScheduleDefaultsToUTC(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	mockRepo.
+		EXPECT().
+		SetSchedule(gomock.Any(), &model.WorkSchedule{UserID: "u1", Timezone: "UTC", WorkStart: "09:00", WorkEnd: "17:00"}).
+		Return(nil)
+
+	body, _ := json.Marshal(map[string]any{"user_id": "u1", "work_start": "09:00", "work_end": "17:00"})
+	req := httptest.NewRequest("POST", "/users/setSchedule", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetSchedule(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestSetScheduleInvalidTimezone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"user_id": "u1", "timezone": "Mars/Olympus"})
+	req := httptest.NewRequest("POST", "/users/setSchedule", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetSchedule(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestUpdateInvalidEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"user_id": "u1", "email": "Alice <alice@example.com>"})
+	req := httptest.NewRequest("POST", "/users/update", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Update(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestSetNotificationsDedupesOptOuts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	want := &model.NotificationSettings{UserID: "u1", OptOut: []string{"digest"}}
+	mockRepo.EXPECT().SetNotificationSettings(gomock.Any(), want).Return(nil)
+	mockRepo.EXPECT().GetNotificationSettings(gomock.Any(), "u1").Return(want, nil)
+
+	body, _ := json.Marshal(map[string]any{"user_id": "u1", "opt_out": []string{"digest", "digest"}})
+	req := httptest.NewRequest("POST", "/users/setNotifications", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetNotifications(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestSetNotificationsUnknownKind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	handler := &UserHandler{
+		BaseHandler: BaseHandler{
+			Logger: *slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		UserRepo: mockRepo,
+	}
+
+	body, _ := json.Marshal(map[string]any{"user_id": "u1", "opt_out": []string{"merged"}})
+	req := httptest.NewRequest("POST", "/users/setNotifications", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.SetNotifications(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+}
@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
 	gomock "go.uber.org/mock/gomock"
@@ -55,6 +56,21 @@ func (mr *MockTeamRepositoryMockRecorder) Add(ctx, team any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockTeamRepository)(nil).Add), ctx, team)
 }
 
+// Delete mocks base method.
+func (m *MockTeamRepository) Delete(ctx context.Context, teamName string) ([]*model.ReleasedReview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, teamName)
+	ret0, _ := ret[0].([]*model.ReleasedReview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockTeamRepositoryMockRecorder) Delete(ctx, teamName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTeamRepository)(nil).Delete), ctx, teamName)
+}
+
 // Get mocks base method.
 func (m *MockTeamRepository) Get(ctx context.Context, teamName string) (*model.Team, error) {
 	m.ctrl.T.Helper()
@@ -70,6 +86,197 @@ func (mr *MockTeamRepositoryMockRecorder) Get(ctx, teamName any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockTeamRepository)(nil).Get), ctx, teamName)
 }
 
+// GetChat mocks base method.
+func (m *MockTeamRepository) GetChat(ctx context.Context, teamName string) (*model.TeamChat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChat", ctx, teamName)
+	ret0, _ := ret[0].(*model.TeamChat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChat indicates an expected call of GetChat.
+func (mr *MockTeamRepositoryMockRecorder) GetChat(ctx, teamName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChat", reflect.TypeOf((*MockTeamRepository)(nil).GetChat), ctx, teamName)
+}
+
+// GetFallbackTeams mocks base method.
+func (m *MockTeamRepository) GetFallbackTeams(ctx context.Context, teamName string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFallbackTeams", ctx, teamName)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFallbackTeams indicates an expected call of GetFallbackTeams.
+func (mr *MockTeamRepositoryMockRecorder) GetFallbackTeams(ctx, teamName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFallbackTeams", reflect.TypeOf((*MockTeamRepository)(nil).GetFallbackTeams), ctx, teamName)
+}
+
+// GetHolidays mocks base method.
+func (m *MockTeamRepository) GetHolidays(ctx context.Context, teamName string) (*model.TeamHolidays, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHolidays", ctx, teamName)
+	ret0, _ := ret[0].(*model.TeamHolidays)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHolidays indicates an expected call of GetHolidays.
+func (mr *MockTeamRepositoryMockRecorder) GetHolidays(ctx, teamName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHolidays", reflect.TypeOf((*MockTeamRepository)(nil).GetHolidays), ctx, teamName)
+}
+
+// GetOwnershipRules mocks base method.
+func (m *MockTeamRepository) GetOwnershipRules(ctx context.Context, teamName string) ([]*model.OwnershipRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnershipRules", ctx, teamName)
+	ret0, _ := ret[0].([]*model.OwnershipRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwnershipRules indicates an expected call of GetOwnershipRules.
+func (mr *MockTeamRepositoryMockRecorder) GetOwnershipRules(ctx, teamName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnershipRules", reflect.TypeOf((*MockTeamRepository)(nil).GetOwnershipRules), ctx, teamName)
+}
+
+// GetReviewSLA mocks base method.
+func (m *MockTeamRepository) GetReviewSLA(ctx context.Context, teamName string) (*model.ReviewSLA, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReviewSLA", ctx, teamName)
+	ret0, _ := ret[0].(*model.ReviewSLA)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReviewSLA indicates an expected call of GetReviewSLA.
+func (mr *MockTeamRepositoryMockRecorder) GetReviewSLA(ctx, teamName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReviewSLA", reflect.TypeOf((*MockTeamRepository)(nil).GetReviewSLA), ctx, teamName)
+}
+
+// Import mocks base method.
+func (m *MockTeamRepository) Import(ctx context.Context, teams []*model.RosterTeam, dryRun bool) (*model.RosterDiff, []*model.ReleasedReview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", ctx, teams, dryRun)
+	ret0, _ := ret[0].(*model.RosterDiff)
+	ret1, _ := ret[1].([]*model.ReleasedReview)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockTeamRepositoryMockRecorder) Import(ctx, teams, dryRun any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockTeamRepository)(nil).Import), ctx, teams, dryRun)
+}
+
+// List mocks base method.
+func (m *MockTeamRepository) List(ctx context.Context) ([]*model.Team, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]*model.Team)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockTeamRepositoryMockRecorder) List(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTeamRepository)(nil).List), ctx)
+}
+
+// SetChat mocks base method.
+func (m *MockTeamRepository) SetChat(ctx context.Context, chat *model.TeamChat) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChat", ctx, chat)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetChat indicates an expected call of SetChat.
+func (mr *MockTeamRepositoryMockRecorder) SetChat(ctx, chat any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChat", reflect.TypeOf((*MockTeamRepository)(nil).SetChat), ctx, chat)
+}
+
+// SetFallbackTeams mocks base method.
+func (m *MockTeamRepository) SetFallbackTeams(ctx context.Context, teamName string, fallbacks []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetFallbackTeams", ctx, teamName, fallbacks)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetFallbackTeams indicates an expected call of SetFallbackTeams.
+func (mr *MockTeamRepositoryMockRecorder) SetFallbackTeams(ctx, teamName, fallbacks any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFallbackTeams", reflect.TypeOf((*MockTeamRepository)(nil).SetFallbackTeams), ctx, teamName, fallbacks)
+}
+
+// SetHolidays mocks base method.
+func (m *MockTeamRepository) SetHolidays(ctx context.Context, holidays *model.TeamHolidays) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetHolidays", ctx, holidays)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetHolidays indicates an expected call of SetHolidays.
+func (mr *MockTeamRepositoryMockRecorder) SetHolidays(ctx, holidays any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHolidays", reflect.TypeOf((*MockTeamRepository)(nil).SetHolidays), ctx, holidays)
+}
+
+// SetOwnershipRules mocks base method.
+func (m *MockTeamRepository) SetOwnershipRules(ctx context.Context, teamName string, rules []*model.OwnershipRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetOwnershipRules", ctx, teamName, rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetOwnershipRules indicates an expected call of SetOwnershipRules.
+func (mr *MockTeamRepositoryMockRecorder) SetOwnershipRules(ctx, teamName, rules any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOwnershipRules", reflect.TypeOf((*MockTeamRepository)(nil).SetOwnershipRules), ctx, teamName, rules)
+}
+
+// SetReviewSLA mocks base method.
+func (m *MockTeamRepository) SetReviewSLA(ctx context.Context, sla *model.ReviewSLA) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReviewSLA", ctx, sla)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetReviewSLA indicates an expected call of SetReviewSLA.
+func (mr *MockTeamRepositoryMockRecorder) SetReviewSLA(ctx, sla any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReviewSLA", reflect.TypeOf((*MockTeamRepository)(nil).SetReviewSLA), ctx, sla)
+}
+
+// Update mocks base method.
+func (m *MockTeamRepository) Update(ctx context.Context, update *model.TeamUpdate) ([]*model.ReleasedReview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, update)
+	ret0, _ := ret[0].([]*model.ReleasedReview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockTeamRepositoryMockRecorder) Update(ctx, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockTeamRepository)(nil).Update), ctx, update)
+}
+
 // MockUserRepository is a mock of UserRepository interface.
 type MockUserRepository struct {
 	ctrl     *gomock.Controller
@@ -94,19 +301,185 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Create mocks base method.
+func (m *MockUserRepository) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, user)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUserRepositoryMockRecorder) Create(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserRepository)(nil).Create), ctx, user)
+}
+
+// Delete mocks base method.
+func (m *MockUserRepository) Delete(ctx context.Context, userID string) (*model.UserDeletion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, userID)
+	ret0, _ := ret[0].(*model.UserDeletion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockUserRepositoryMockRecorder) Delete(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUserRepository)(nil).Delete), ctx, userID)
+}
+
+// Get mocks base method.
+func (m *MockUserRepository) Get(ctx context.Context, userID string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, userID)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockUserRepositoryMockRecorder) Get(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockUserRepository)(nil).Get), ctx, userID)
+}
+
+// GetChatHandles mocks base method.
+func (m *MockUserRepository) GetChatHandles(ctx context.Context, userIDs []string) (map[string]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChatHandles", ctx, userIDs)
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChatHandles indicates an expected call of GetChatHandles.
+func (mr *MockUserRepositoryMockRecorder) GetChatHandles(ctx, userIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChatHandles", reflect.TypeOf((*MockUserRepository)(nil).GetChatHandles), ctx, userIDs)
+}
+
+// GetNotificationSettings mocks base method.
+func (m *MockUserRepository) GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotificationSettings", ctx, userID)
+	ret0, _ := ret[0].(*model.NotificationSettings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotificationSettings indicates an expected call of GetNotificationSettings.
+func (mr *MockUserRepositoryMockRecorder) GetNotificationSettings(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotificationSettings", reflect.TypeOf((*MockUserRepository)(nil).GetNotificationSettings), ctx, userID)
+}
+
 // GetReview mocks base method.
-func (m *MockUserRepository) GetReview(ctx context.Context, userID string) ([]*model.PullRequestShort, error) {
+func (m *MockUserRepository) GetReview(ctx context.Context, userID string, filter model.ReviewFilter) (*model.ReviewPage, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetReview", ctx, userID)
-	ret0, _ := ret[0].([]*model.PullRequestShort)
+	ret := m.ctrl.Call(m, "GetReview", ctx, userID, filter)
+	ret0, _ := ret[0].(*model.ReviewPage)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetReview indicates an expected call of GetReview.
-func (mr *MockUserRepositoryMockRecorder) GetReview(ctx, userID any) *gomock.Call {
+func (mr *MockUserRepositoryMockRecorder) GetReview(ctx, userID, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReview", reflect.TypeOf((*MockUserRepository)(nil).GetReview), ctx, userID, filter)
+}
+
+// GetReviewLoad mocks base method.
+func (m *MockUserRepository) GetReviewLoad(ctx context.Context, userID string) (*model.ReviewLoad, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReviewLoad", ctx, userID)
+	ret0, _ := ret[0].(*model.ReviewLoad)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReviewLoad indicates an expected call of GetReviewLoad.
+func (mr *MockUserRepositoryMockRecorder) GetReviewLoad(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReviewLoad", reflect.TypeOf((*MockUserRepository)(nil).GetReviewLoad), ctx, userID)
+}
+
+// GetReviewerPreferences mocks base method.
+func (m *MockUserRepository) GetReviewerPreferences(ctx context.Context, userID string) (*model.ReviewerPreferences, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReviewerPreferences", ctx, userID)
+	ret0, _ := ret[0].(*model.ReviewerPreferences)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReviewerPreferences indicates an expected call of GetReviewerPreferences.
+func (mr *MockUserRepositoryMockRecorder) GetReviewerPreferences(ctx, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReview", reflect.TypeOf((*MockUserRepository)(nil).GetReview), ctx, userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReviewerPreferences", reflect.TypeOf((*MockUserRepository)(nil).GetReviewerPreferences), ctx, userID)
+}
+
+// GetSchedule mocks base method.
+func (m *MockUserRepository) GetSchedule(ctx context.Context, userID string) (*model.WorkSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedule", ctx, userID)
+	ret0, _ := ret[0].(*model.WorkSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSchedule indicates an expected call of GetSchedule.
+func (mr *MockUserRepositoryMockRecorder) GetSchedule(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedule", reflect.TypeOf((*MockUserRepository)(nil).GetSchedule), ctx, userID)
+}
+
+// List mocks base method.
+func (m *MockUserRepository) List(ctx context.Context, filter model.UserFilter) ([]*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, filter)
+	ret0, _ := ret[0].([]*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockUserRepositoryMockRecorder) List(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockUserRepository)(nil).List), ctx, filter)
+}
+
+// MarkReminded mocks base method.
+func (m *MockUserRepository) MarkReminded(ctx context.Context, userID string, at, notAfter time.Time) (bool, *time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkReminded", ctx, userID, at, notAfter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(*time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// MarkReminded indicates an expected call of MarkReminded.
+func (mr *MockUserRepositoryMockRecorder) MarkReminded(ctx, userID, at, notAfter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkReminded", reflect.TypeOf((*MockUserRepository)(nil).MarkReminded), ctx, userID, at, notAfter)
+}
+
+// ReviewQueues mocks base method.
+func (m *MockUserRepository) ReviewQueues(ctx context.Context, assignedBefore time.Time) ([]*model.ReviewerQueue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewQueues", ctx, assignedBefore)
+	ret0, _ := ret[0].([]*model.ReviewerQueue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewQueues indicates an expected call of ReviewQueues.
+func (mr *MockUserRepositoryMockRecorder) ReviewQueues(ctx, assignedBefore any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewQueues", reflect.TypeOf((*MockUserRepository)(nil).ReviewQueues), ctx, assignedBefore)
 }
 
 // SetIsActive mocks base method.
@@ -124,6 +497,93 @@ func (mr *MockUserRepositoryMockRecorder) SetIsActive(ctx, userID, isActive any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIsActive", reflect.TypeOf((*MockUserRepository)(nil).SetIsActive), ctx, userID, isActive)
 }
 
+// SetNotificationSettings mocks base method.
+func (m *MockUserRepository) SetNotificationSettings(ctx context.Context, settings *model.NotificationSettings) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNotificationSettings", ctx, settings)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetNotificationSettings indicates an expected call of SetNotificationSettings.
+func (mr *MockUserRepositoryMockRecorder) SetNotificationSettings(ctx, settings any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNotificationSettings", reflect.TypeOf((*MockUserRepository)(nil).SetNotificationSettings), ctx, settings)
+}
+
+// SetReviewerPreferences mocks base method.
+func (m *MockUserRepository) SetReviewerPreferences(ctx context.Context, prefs *model.ReviewerPreferences) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReviewerPreferences", ctx, prefs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetReviewerPreferences indicates an expected call of SetReviewerPreferences.
+func (mr *MockUserRepositoryMockRecorder) SetReviewerPreferences(ctx, prefs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReviewerPreferences", reflect.TypeOf((*MockUserRepository)(nil).SetReviewerPreferences), ctx, prefs)
+}
+
+// SetSchedule mocks base method.
+func (m *MockUserRepository) SetSchedule(ctx context.Context, schedule *model.WorkSchedule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSchedule", ctx, schedule)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSchedule indicates an expected call of SetSchedule.
+func (mr *MockUserRepositoryMockRecorder) SetSchedule(ctx, schedule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSchedule", reflect.TypeOf((*MockUserRepository)(nil).SetSchedule), ctx, schedule)
+}
+
+// SetTeamIsActive mocks base method.
+func (m *MockUserRepository) SetTeamIsActive(ctx context.Context, userID, teamName string, isActive bool) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTeamIsActive", ctx, userID, teamName, isActive)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetTeamIsActive indicates an expected call of SetTeamIsActive.
+func (mr *MockUserRepositoryMockRecorder) SetTeamIsActive(ctx, userID, teamName, isActive any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTeamIsActive", reflect.TypeOf((*MockUserRepository)(nil).SetTeamIsActive), ctx, userID, teamName, isActive)
+}
+
+// UnmarkReminded mocks base method.
+func (m *MockUserRepository) UnmarkReminded(ctx context.Context, userID string, at time.Time, previous *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmarkReminded", ctx, userID, at, previous)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnmarkReminded indicates an expected call of UnmarkReminded.
+func (mr *MockUserRepositoryMockRecorder) UnmarkReminded(ctx, userID, at, previous any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmarkReminded", reflect.TypeOf((*MockUserRepository)(nil).UnmarkReminded), ctx, userID, at, previous)
+}
+
+// Update mocks base method.
+func (m *MockUserRepository) Update(ctx context.Context, update *model.UserUpdate) (*model.User, []*model.ReleasedReview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, update)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].([]*model.ReleasedReview)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockUserRepositoryMockRecorder) Update(ctx, update any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserRepository)(nil).Update), ctx, update)
+}
+
 // MockPullRequestRepository is a mock of PullRequestRepository interface.
 type MockPullRequestRepository struct {
 	ctrl     *gomock.Controller
@@ -148,6 +608,21 @@ func (m *MockPullRequestRepository) EXPECT() *MockPullRequestRepositoryMockRecor
 	return m.recorder
 }
 
+// AddReviewer mocks base method.
+func (m *MockPullRequestRepository) AddReviewer(ctx context.Context, prID, reviewerID string) (*model.PullRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReviewer", ctx, prID, reviewerID)
+	ret0, _ := ret[0].(*model.PullRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddReviewer indicates an expected call of AddReviewer.
+func (mr *MockPullRequestRepositoryMockRecorder) AddReviewer(ctx, prID, reviewerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReviewer", reflect.TypeOf((*MockPullRequestRepository)(nil).AddReviewer), ctx, prID, reviewerID)
+}
+
 // Create mocks base method.
 func (m *MockPullRequestRepository) Create(ctx context.Context, req model.PullRequestPayload) (*model.PullRequest, error) {
 	m.ctrl.T.Helper()
@@ -163,32 +638,221 @@ func (mr *MockPullRequestRepositoryMockRecorder) Create(ctx, req any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPullRequestRepository)(nil).Create), ctx, req)
 }
 
-// Merge mocks base method.
-func (m *MockPullRequestRepository) Merge(ctx context.Context, prID string) (*model.PullRequest, error) {
+// Get mocks base method.
+func (m *MockPullRequestRepository) Get(ctx context.Context, prID string) (*model.PullRequest, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Merge", ctx, prID)
+	ret := m.ctrl.Call(m, "Get", ctx, prID)
 	ret0, _ := ret[0].(*model.PullRequest)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// Get indicates an expected call of Get.
+func (mr *MockPullRequestRepositoryMockRecorder) Get(ctx, prID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockPullRequestRepository)(nil).Get), ctx, prID)
+}
+
+// List mocks base method.
+func (m *MockPullRequestRepository) List(ctx context.Context, filter model.PullRequestFilter) (*model.PullRequestPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, filter)
+	ret0, _ := ret[0].(*model.PullRequestPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockPullRequestRepositoryMockRecorder) List(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockPullRequestRepository)(nil).List), ctx, filter)
+}
+
+// ListOverdue mocks base method.
+func (m *MockPullRequestRepository) ListOverdue(ctx context.Context, teamName string) ([]*model.OverdueReview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOverdue", ctx, teamName)
+	ret0, _ := ret[0].([]*model.OverdueReview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOverdue indicates an expected call of ListOverdue.
+func (mr *MockPullRequestRepositoryMockRecorder) ListOverdue(ctx, teamName any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOverdue", reflect.TypeOf((*MockPullRequestRepository)(nil).ListOverdue), ctx, teamName)
+}
+
+// MarkOverdue mocks base method.
+func (m *MockPullRequestRepository) MarkOverdue(ctx context.Context, prID, reviewerID string, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOverdue", ctx, prID, reviewerID, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkOverdue indicates an expected call of MarkOverdue.
+func (mr *MockPullRequestRepositoryMockRecorder) MarkOverdue(ctx, prID, reviewerID, at any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOverdue", reflect.TypeOf((*MockPullRequestRepository)(nil).MarkOverdue), ctx, prID, reviewerID, at)
+}
+
+// Merge mocks base method.
+func (m *MockPullRequestRepository) Merge(ctx context.Context, prID string) (*model.PullRequest, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, prID)
+	ret0, _ := ret[0].(*model.PullRequest)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // Merge indicates an expected call of Merge.
 func (mr *MockPullRequestRepositoryMockRecorder) Merge(ctx, prID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockPullRequestRepository)(nil).Merge), ctx, prID)
 }
 
+// PendingReviews mocks base method.
+func (m *MockPullRequestRepository) PendingReviews(ctx context.Context) ([]*model.PendingReview, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingReviews", ctx)
+	ret0, _ := ret[0].([]*model.PendingReview)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingReviews indicates an expected call of PendingReviews.
+func (mr *MockPullRequestRepositoryMockRecorder) PendingReviews(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingReviews", reflect.TypeOf((*MockPullRequestRepository)(nil).PendingReviews), ctx)
+}
+
 // Reassign mocks base method.
-func (m *MockPullRequestRepository) Reassign(ctx context.Context, prID, oldReviewerID string) (*model.PullRequest, error) {
+func (m *MockPullRequestRepository) Reassign(ctx context.Context, prID, oldReviewerID, newReviewerID string) (*model.PullRequest, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Reassign", ctx, prID, oldReviewerID)
+	ret := m.ctrl.Call(m, "Reassign", ctx, prID, oldReviewerID, newReviewerID)
+	ret0, _ := ret[0].(*model.PullRequest)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Reassign indicates an expected call of Reassign.
+func (mr *MockPullRequestRepositoryMockRecorder) Reassign(ctx, prID, oldReviewerID, newReviewerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reassign", reflect.TypeOf((*MockPullRequestRepository)(nil).Reassign), ctx, prID, oldReviewerID, newReviewerID)
+}
+
+// RemoveReviewer mocks base method.
+func (m *MockPullRequestRepository) RemoveReviewer(ctx context.Context, prID, reviewerID string) (*model.PullRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveReviewer", ctx, prID, reviewerID)
 	ret0, _ := ret[0].(*model.PullRequest)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Reassign indicates an expected call of Reassign.
-func (mr *MockPullRequestRepositoryMockRecorder) Reassign(ctx, prID, oldReviewerID any) *gomock.Call {
+// RemoveReviewer indicates an expected call of RemoveReviewer.
+func (mr *MockPullRequestRepositoryMockRecorder) RemoveReviewer(ctx, prID, reviewerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveReviewer", reflect.TypeOf((*MockPullRequestRepository)(nil).RemoveReviewer), ctx, prID, reviewerID)
+}
+
+// SubmitReview mocks base method.
+func (m *MockPullRequestRepository) SubmitReview(ctx context.Context, prID, reviewerID string) (*model.PullRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubmitReview", ctx, prID, reviewerID)
+	ret0, _ := ret[0].(*model.PullRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubmitReview indicates an expected call of SubmitReview.
+func (mr *MockPullRequestRepositoryMockRecorder) SubmitReview(ctx, prID, reviewerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubmitReview", reflect.TypeOf((*MockPullRequestRepository)(nil).SubmitReview), ctx, prID, reviewerID)
+}
+
+// MockStatsRepository is a mock of StatsRepository interface.
+type MockStatsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatsRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockStatsRepositoryMockRecorder is the mock recorder for MockStatsRepository.
+type MockStatsRepositoryMockRecorder struct {
+	mock *MockStatsRepository
+}
+
+// NewMockStatsRepository creates a new mock instance.
+func NewMockStatsRepository(ctrl *gomock.Controller) *MockStatsRepository {
+	mock := &MockStatsRepository{ctrl: ctrl}
+	mock.recorder = &MockStatsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStatsRepository) EXPECT() *MockStatsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ExportAssignments mocks base method.
+func (m *MockStatsRepository) ExportAssignments(ctx context.Context, filter model.StatsFilter, emit func(*model.AssignmentExport) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportAssignments", ctx, filter, emit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportAssignments indicates an expected call of ExportAssignments.
+func (mr *MockStatsRepositoryMockRecorder) ExportAssignments(ctx, filter, emit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportAssignments", reflect.TypeOf((*MockStatsRepository)(nil).ExportAssignments), ctx, filter, emit)
+}
+
+// ExportPullRequests mocks base method.
+func (m *MockStatsRepository) ExportPullRequests(ctx context.Context, filter model.StatsFilter, emit func(*model.PullRequestExport) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportPullRequests", ctx, filter, emit)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportPullRequests indicates an expected call of ExportPullRequests.
+func (mr *MockStatsRepositoryMockRecorder) ExportPullRequests(ctx, filter, emit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportPullRequests", reflect.TypeOf((*MockStatsRepository)(nil).ExportPullRequests), ctx, filter, emit)
+}
+
+// PullRequestTimes mocks base method.
+func (m *MockStatsRepository) PullRequestTimes(ctx context.Context, filter model.StatsFilter) ([]*model.PullRequestTimes, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PullRequestTimes", ctx, filter)
+	ret0, _ := ret[0].([]*model.PullRequestTimes)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PullRequestTimes indicates an expected call of PullRequestTimes.
+func (mr *MockStatsRepositoryMockRecorder) PullRequestTimes(ctx, filter any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullRequestTimes", reflect.TypeOf((*MockStatsRepository)(nil).PullRequestTimes), ctx, filter)
+}
+
+// ReviewerStats mocks base method.
+func (m *MockStatsRepository) ReviewerStats(ctx context.Context, filter model.StatsFilter) ([]*model.ReviewerStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewerStats", ctx, filter)
+	ret0, _ := ret[0].([]*model.ReviewerStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewerStats indicates an expected call of ReviewerStats.
+func (mr *MockStatsRepositoryMockRecorder) ReviewerStats(ctx, filter any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reassign", reflect.TypeOf((*MockPullRequestRepository)(nil).Reassign), ctx, prID, oldReviewerID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewerStats", reflect.TypeOf((*MockStatsRepository)(nil).ReviewerStats), ctx, filter)
 }
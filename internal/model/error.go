@@ -3,14 +3,21 @@ package model
 import "errors"
 
 var (
-	ErrTeamExists   = errors.New("team already exists")
-	ErrPrExists     = errors.New("PR already exists")
-	ErrPrMerged     = errors.New("PR already merged")
-	ErrNotAssigned  = errors.New("not assigned")
-	ErrNoCandidate  = errors.New("no candidate")
-	ErrNotFound     = errors.New("not found")
-	ErrInvalidInput = errors.New("invalid input")
-	ErrMissingParam = errors.New("missing required parameter")
+	ErrTeamExists      = errors.New("team already exists")
+	ErrUserExists      = errors.New("user already exists")
+	ErrHasOpenPrs      = errors.New("user authors open PRs")
+	ErrPrExists        = errors.New("PR already exists")
+	ErrPrMerged        = errors.New("PR already merged")
+	ErrNotAssigned     = errors.New("not assigned")
+	ErrAlreadyAssigned = errors.New("already assigned")
+	ErrInvalidReviewer = errors.New("invalid reviewer")
+	ErrNoCandidate     = errors.New("no candidate")
+	ErrNoSenior        = errors.New("no senior candidate")
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidInput    = errors.New("invalid input")
+	ErrMissingParam    = errors.New("missing required parameter")
+	ErrInvalidRules    = errors.New("invalid ownership rules")
+	ErrInvalidRoster   = errors.New("invalid team roster")
 )
 
 type ErrorDetails struct {
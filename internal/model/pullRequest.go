@@ -6,9 +6,15 @@ import (
 
 type PullRequest struct {
 	PullRequestShort
+	TeamName          string     `json:"team_name,omitempty"`
 	AssignedReviewers []string   `json:"assigned_reviewers" valid:"required"`
 	CreatedAt         *time.Time `json:"createdAt,omitempty"`
 	MergedAt          *time.Time `json:"mergedAt,omitempty"`
+	// ReviewerMatches explains the choice of each reviewer picked on create
+	// or reassign.
+	ReviewerMatches []*ReviewerMatch `json:"reviewer_matches,omitempty"`
+	// UncoveredSkills are required skills no picked reviewer has.
+	UncoveredSkills []string `json:"uncovered_skills,omitempty"`
 }
 
 type PullRequestShort struct {
@@ -22,4 +28,97 @@ type PullRequestPayload struct {
 	PullRequestID   string `json:"pull_request_id" valid:"required"`
 	PullRequestName string `json:"pull_request_name" valid:"required"`
 	AuthorID        string `json:"author_id" valid:"required"`
+	// TeamName selects the team whose members review the PR. Empty means the
+	// author's primary team.
+	TeamName string `json:"team_name,omitempty"`
+	// ChangedFiles are matched against the team's ownership rules.
+	ChangedFiles []string `json:"changed_files,omitempty"`
+	// RequiredSkills are tags the reviewers should cover between them.
+	RequiredSkills []string `json:"required_skills,omitempty"`
+}
+
+// ReviewerMatch tells why a reviewer was picked. MatchedRule is the ownership
+// rule that brought them in, nil for reviewers from the general team pool;
+// MatchedSkills are the required skills they cover. FallbackTeam is set for
+// reviewers lent by a fallback team.
+type ReviewerMatch struct {
+	UserID        string         `json:"user_id"`
+	MatchedRule   *OwnershipRule `json:"matched_rule,omitempty"`
+	MatchedSkills []string       `json:"matched_skills,omitempty"`
+	FallbackTeam  string         `json:"fallback_team,omitempty"`
+}
+
+const (
+	// PullRequestSortCreated orders pull requests by creation time.
+	PullRequestSortCreated = "created_at"
+	// PullRequestSortName orders pull requests by name.
+	PullRequestSortName = "name"
+)
+
+// PullRequestFilter narrows PullRequestRepository.List. Empty fields match
+// every pull request; From bounds are inclusive, To bounds exclusive.
+type PullRequestFilter struct {
+	Status      string
+	AuthorID    string
+	ReviewerID  string
+	TeamName    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MergedFrom  *time.Time
+	MergedTo    *time.Time
+	// Sort is one of the PullRequestSort values, ascending unless Desc is set.
+	Sort string
+	Desc bool
+	// Limit caps the page size; After continues from a previous page.
+	Limit int
+	After *Cursor
+}
+
+// PullRequestPage is one page of List. Next is nil on the last page.
+type PullRequestPage struct {
+	PullRequests []*PullRequest
+	Next         *Cursor
+}
+
+// Cursor points past the last item of a page: its sort key and the id that
+// breaks ties between equal keys.
+type Cursor struct {
+	Key string `json:"k"`
+	ID  string `json:"id"`
+}
+
+// PendingReview is an assignment on an OPEN pull request whose reviewer has
+// not submitted a review yet and that is not overdue yet, with the SLA of the
+// pull request's team. Schedule is the reviewer's and Holidays are the days
+// off of the pull request's team, so that the deadline counts business time.
+type PendingReview struct {
+	PullRequestID   string
+	PullRequestName string
+	ReviewerID      string
+	AssignedAt      time.Time
+	SLA             ReviewSLA
+	Schedule        WorkSchedule
+	Holidays        []string
+}
+
+// OverdueReview is an assignment that missed its team's review SLA.
+type OverdueReview struct {
+	PullRequestID   string    `json:"pull_request_id"`
+	PullRequestName string    `json:"pull_request_name"`
+	ReviewerID      string    `json:"reviewer_id"`
+	TeamName        string    `json:"team_name"`
+	AssignedAt      time.Time `json:"assigned_at"`
+	OverdueAt       time.Time `json:"overdue_at"`
+}
+
+// EventReviewOverdue is the kind of the event emitted when a review misses
+// its SLA.
+const EventReviewOverdue = "review_overdue"
+
+// ReviewEvent reports a change in a review's SLA state. ReassignedTo is set
+// when the overdue seat was handed over to another reviewer.
+type ReviewEvent struct {
+	Kind string `json:"kind"`
+	OverdueReview
+	ReassignedTo string `json:"reassigned_to,omitempty"`
 }
@@ -0,0 +1,95 @@
+package model
+
+import "time"
+
+// StatsFilter narrows statistics down to one team's pull requests and to the
+// half-open time range [From, To). Nil bounds leave the range open.
+type StatsFilter struct {
+	TeamName string
+	From     *time.Time
+	To       *time.Time
+}
+
+// ReviewerStats is the review load of one user. Assigned counts the seats
+// the user was given in the range and still holds, plus the seats handed
+// over from them or taken from them in the range. Of the seats still held,
+// Open are on OPEN pull requests and not reviewed yet, and Completed were
+// reviewed. ReassignedAway counts the seats handed over from the user in the
+// range.
+type ReviewerStats struct {
+	UserID         string `json:"user_id"`
+	Username       string `json:"username"`
+	Assigned       int    `json:"assigned"`
+	Open           int    `json:"open"`
+	Completed      int    `json:"completed"`
+	ReassignedAway int    `json:"reassigned_away"`
+}
+
+// PullRequestTimes are the milestones of one pull request. FirstReviewAt is
+// the earliest review submitted by a reviewer who still holds their seat.
+type PullRequestTimes struct {
+	PullRequestID string
+	TeamName      string
+	AuthorID      string
+	CreatedAt     time.Time
+	FirstReviewAt *time.Time
+	MergedAt      *time.Time
+}
+
+// Ways of grouping cycle times.
+const (
+	CycleTimeByTeam   = "team"
+	CycleTimeByAuthor = "author"
+)
+
+// DurationPercentiles summarize Count durations, in seconds.
+type DurationPercentiles struct {
+	Count int   `json:"count"`
+	P50   int64 `json:"p50"`
+	P90   int64 `json:"p90"`
+	P99   int64 `json:"p99"`
+}
+
+// CycleTimeWeek summarizes the pull requests created in the week starting
+// on Monday WeekStart, in UTC. Pull requests not reviewed or merged yet only
+// count towards PullRequests.
+type CycleTimeWeek struct {
+	WeekStart         string              `json:"week_start"`
+	PullRequests      int                 `json:"pull_requests"`
+	TimeToFirstReview DurationPercentiles `json:"time_to_first_review"`
+	TimeToMerge       DurationPercentiles `json:"time_to_merge"`
+}
+
+// CycleTimeGroup are the weekly cycle times of one team or author; Key is
+// the team name or the author id. Pull requests without a team are grouped
+// under an empty Key.
+type CycleTimeGroup struct {
+	Key   string           `json:"key"`
+	Weeks []*CycleTimeWeek `json:"weeks"`
+}
+
+// AssignmentExport is one reviewer seat as exported for analysis.
+type AssignmentExport struct {
+	PullRequestID   string     `json:"pull_request_id"`
+	PullRequestName string     `json:"pull_request_name"`
+	TeamName        string     `json:"team_name"`
+	AuthorID        string     `json:"author_id"`
+	Status          string     `json:"status"`
+	ReviewerID      string     `json:"reviewer_id"`
+	AssignedAt      time.Time  `json:"assigned_at"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	OverdueAt       *time.Time `json:"overdue_at"`
+}
+
+// PullRequestExport is one pull request as exported for analysis. Reviewers
+// come in the order they were assigned.
+type PullRequestExport struct {
+	PullRequestID   string     `json:"pull_request_id"`
+	PullRequestName string     `json:"pull_request_name"`
+	TeamName        string     `json:"team_name"`
+	AuthorID        string     `json:"author_id"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	MergedAt        *time.Time `json:"merged_at"`
+	Reviewers       []string   `json:"reviewers"`
+}
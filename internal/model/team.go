@@ -3,4 +3,107 @@ package model
 type Team struct {
 	TeamName string        `json:"team_name" valid:"required"`
 	Members  []*TeamMember `json:"members" valid:"required"`
+	// MinSeniorReviewers is how many senior reviewers every pull request of
+	// the team needs.
+	MinSeniorReviewers int `json:"min_senior_reviewers"`
+}
+
+// TeamUpdate describes changes to an existing team. Empty fields are left
+// untouched.
+type TeamUpdate struct {
+	TeamName      string        `json:"team_name" valid:"required"`
+	NewTeamName   string        `json:"new_team_name,omitempty"`
+	AddMembers    []*TeamMember `json:"add_members,omitempty"`
+	RemoveMembers []string      `json:"remove_members,omitempty"`
+	// MinSeniorReviewers changes the team's seniority rule when set.
+	MinSeniorReviewers *int `json:"min_senior_reviewers,omitempty"`
+}
+
+// ReleasedReview is an assignment on an OPEN pull request that was dropped
+// because the reviewer left their team.
+type ReleasedReview struct {
+	PullRequestID string `json:"pull_request_id"`
+	UserID        string `json:"user_id"`
+}
+
+// OwnershipRule is one CODEOWNERS line of a team. Files matching Pattern are
+// owned by Owners, written as @user_id or @org/team_name.
+type OwnershipRule struct {
+	Line    int      `json:"line"`
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"`
+}
+
+// ReviewSLA is how soon a team promises that an assigned reviewer submits
+// their review. Minutes of zero turns tracking off. With AutoReassign, a
+// review that misses the SLA is handed over to another candidate.
+type ReviewSLA struct {
+	TeamName     string `json:"team_name"`
+	Minutes      int    `json:"sla_minutes"`
+	AutoReassign bool   `json:"auto_reassign"`
+}
+
+// Holiday is a day off of a team, written as YYYY-MM-DD.
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name,omitempty"`
+}
+
+// TeamHolidays is a team's holiday calendar. Review SLAs do not run on these
+// days, in each reviewer's own timezone.
+type TeamHolidays struct {
+	TeamName string     `json:"team_name"`
+	Holidays []*Holiday `json:"holidays"`
+}
+
+// TeamChat is where a team's pull request messages are posted: an incoming
+// webhook of Slack or Mattermost. With DirectMessages the reviewers are also
+// messaged in person by overriding the webhook's channel with @handle, which
+// Mattermost honours and Slack does not.
+type TeamChat struct {
+	TeamName       string `json:"team_name"`
+	WebhookURL     string `json:"webhook_url"`
+	DirectMessages bool   `json:"direct_messages"`
+}
+
+const (
+	RosterAdded           = "added"
+	RosterRemoved         = "removed"
+	RosterMoved           = "moved"
+	RosterActivityChanged = "activity_changed"
+)
+
+// RosterTeam is one team of an imported roster. MinSeniorReviewers changes
+// the team's seniority rule when set; a created team without it needs no
+// seniors.
+type RosterTeam struct {
+	TeamName           string
+	Members            []*TeamMember
+	MinSeniorReviewers *int
+}
+
+// RosterChange is one difference between an imported roster and the stored
+// teams. A moved user left FromTeam for TeamName. IsActive is the user's
+// activity in TeamName after the import, or before it for a removed user.
+type RosterChange struct {
+	Action   string `json:"action"`
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
+	FromTeam string `json:"from_team,omitempty"`
+	IsActive bool   `json:"is_active"`
+}
+
+// SeniorityChange is a team's seniority rule changed by an import.
+type SeniorityChange struct {
+	TeamName string `json:"team_name"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+}
+
+// RosterDiff is what importing a roster changes. Teams missing from the
+// roster are left untouched.
+type RosterDiff struct {
+	CreatedTeams     []string           `json:"created_teams"`
+	Changes          []*RosterChange    `json:"changes"`
+	SeniorityChanges []*SeniorityChange `json:"seniority_changes"`
 }
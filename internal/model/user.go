@@ -1,12 +1,183 @@
 package model
 
+import "time"
+
+const (
+	LevelJunior = "junior"
+	LevelMiddle = "middle"
+	LevelSenior = "senior"
+)
+
 type TeamMember struct {
 	UserID   string `json:"user_id" valid:"required"`
 	Username string `json:"username" valid:"required"`
 	IsActive bool   `json:"is_active" valid:"required"`
+	// Skills are expertise tags such as "go" or "terraform". A nil list on
+	// input leaves the stored tags untouched.
+	Skills []string `json:"skills,omitempty"`
+	// Level is junior, middle or senior. Empty on input leaves it untouched.
+	Level string `json:"level,omitempty"`
+}
+
+// Membership is a user's participation in one team. IsActive is the per-team
+// flag; a user is only picked as a reviewer when both it and the user-level
+// flag are set.
+type Membership struct {
+	TeamName string `json:"team_name"`
+	IsActive bool   `json:"is_active"`
 }
 
 type User struct {
 	TeamMember
-	TeamName string `json:"team_name" valid:"required"`
+	// TeamName is the user's primary team, the one they joined first.
+	TeamName string        `json:"team_name"`
+	Teams    []*Membership `json:"teams"`
+	// MaxOpenReviews caps the user's concurrent open reviews; nil means no
+	// limit.
+	MaxOpenReviews *int `json:"max_open_reviews,omitempty"`
+	// Email is where email notifications go; users without one get none.
+	Email string `json:"email,omitempty"`
+	// ChatHandle is how the user is mentioned in chat messages.
+	ChatHandle string `json:"chat_handle,omitempty"`
+}
+
+// ReviewLoad is how many open pull requests a user reviews against their
+// capacity. MaxOpenReviews is nil for users without a limit.
+type ReviewLoad struct {
+	OpenReviews    int  `json:"open_reviews"`
+	MaxOpenReviews *int `json:"max_open_reviews"`
+}
+
+// UserUpdate describes changes to a user. Empty fields are left untouched; an
+// empty, non-nil Skills list clears the tags and a zero MaxOpenReviews lifts
+// the limit. TeamName moves the user out of their primary team into the named
+// one, which then takes its place as the primary team.
+type UserUpdate struct {
+	UserID         string   `json:"user_id" valid:"required"`
+	Username       string   `json:"username,omitempty"`
+	Email          string   `json:"email,omitempty"`
+	ChatHandle     string   `json:"chat_handle,omitempty"`
+	TeamName       string   `json:"team_name,omitempty"`
+	Skills         []string `json:"skills,omitempty"`
+	Level          string   `json:"level,omitempty"`
+	MaxOpenReviews *int     `json:"max_open_reviews,omitempty"`
+}
+
+// UserFilter narrows a user listing. Empty fields match every user.
+type UserFilter struct {
+	TeamName string
+	IsActive *bool
+}
+
+// UserDeletion is what deleting a user released: their assignments on OPEN
+// pull requests. The user's pull requests and review history are kept.
+type UserDeletion struct {
+	UserID          string            `json:"user_id"`
+	ReleasedReviews []*ReleasedReview `json:"released_reviews"`
+}
+
+// ReviewerPreferences are an author's reviewer pairings. Blocked users never
+// review the author's pull requests; preferred ones win ties when reviewers
+// are picked.
+type ReviewerPreferences struct {
+	UserID    string   `json:"user_id" valid:"required"`
+	Blocked   []string `json:"blocked"`
+	Preferred []string `json:"preferred"`
+}
+
+// ReviewAssignment is a pull request in a reviewer's queue. AgeSeconds is how
+// long ago it was assigned to them; TeamName is only filled in digests.
+type ReviewAssignment struct {
+	PullRequestShort
+	TeamName   string    `json:"team_name,omitempty"`
+	AssignedAt time.Time `json:"assigned_at"`
+	AgeSeconds int64     `json:"age_seconds"`
+}
+
+// ReviewFilter pages through a reviewer's queue, the oldest assignment
+// first. An empty Status matches pull requests in any status.
+type ReviewFilter struct {
+	Status string
+	Limit  int
+	After  *Cursor
+}
+
+// ReviewPage is one page of a reviewer's queue. Next is nil on the last page.
+type ReviewPage struct {
+	Reviews []*ReviewAssignment
+	Next    *Cursor
+}
+
+// WorkSchedule is when a user is at work. Timezone is an IANA name and UTC
+// when empty. WorkStart and WorkEnd are HH:MM clock times in that timezone;
+// with them the user works those hours Monday to Friday, without them they
+// count as always at work.
+type WorkSchedule struct {
+	UserID    string `json:"user_id" valid:"required"`
+	Timezone  string `json:"timezone"`
+	WorkStart string `json:"work_start,omitempty"`
+	WorkEnd   string `json:"work_end,omitempty"`
+}
+
+// ReviewerQueue is a reviewer's OPEN assignments with what tells when the
+// reviewer is at work: their schedule and the holidays of their teams.
+type ReviewerQueue struct {
+	UserID   string
+	Username string
+	Schedule WorkSchedule
+	Holidays []string
+	Reviews  []*ReviewAssignment
+}
+
+// ReviewDigest reminds a reviewer of the OPEN reviews that have been waiting
+// on them for too long, the oldest first.
+type ReviewDigest struct {
+	UserID   string              `json:"user_id"`
+	Username string              `json:"username"`
+	Reviews  []*ReviewAssignment `json:"reviews"`
+	SentAt   time.Time           `json:"sent_at"`
+}
+
+// Notification kinds a user can opt out of.
+const (
+	NoticeAssigned = "assigned"
+	NoticeReplaced = "replaced"
+	NoticeDigest   = "digest"
+)
+
+// NotificationSettings are where a user is notified and which notification
+// kinds they opted out of. Email and Username are read only; the email is
+// set on the user.
+type NotificationSettings struct {
+	UserID   string   `json:"user_id" valid:"required"`
+	Username string   `json:"username,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	OptOut   []string `json:"opt_out"`
+}
+
+// Notice tells a reviewer about their seat on a pull request: Kind is
+// NoticeAssigned when they got it and NoticeReplaced when ReplacedBy took it
+// over.
+type Notice struct {
+	Kind        string           `json:"kind"`
+	UserID      string           `json:"user_id"`
+	PullRequest PullRequestShort `json:"pull_request"`
+	TeamName    string           `json:"team_name,omitempty"`
+	ReplacedBy  string           `json:"replaced_by,omitempty"`
+}
+
+// Kinds of the pull request announcements posted to team channels.
+const (
+	AnnounceCreated    = "created"
+	AnnounceReassigned = "reassigned"
+	AnnounceMerged     = "merged"
+)
+
+// Announcement tells a team about a pull request it reviews. Replaced and
+// ReplacedBy are set for AnnounceReassigned.
+type Announcement struct {
+	Kind        string       `json:"kind"`
+	PullRequest *PullRequest `json:"pull_request"`
+	Replaced    string       `json:"replaced,omitempty"`
+	ReplacedBy  string       `json:"replaced_by,omitempty"`
 }
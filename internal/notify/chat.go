@@ -0,0 +1,269 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+// ChatTeams looks up the incoming webhook of a team.
+type ChatTeams interface {
+	GetChat(ctx context.Context, teamName string) (*model.TeamChat, error)
+}
+
+// ChatHandles looks up how users are mentioned in chat.
+type ChatHandles interface {
+	GetChatHandles(ctx context.Context, userIDs []string) (map[string]string, error)
+}
+
+// ChatNotifier posts to Slack or Mattermost incoming webhooks. Announcements
+// go to the team's channel; notices go to the reviewer in person when the
+// team turned direct messages on. Digests go to the reviewer the same way,
+// or to the channel with a mention. Teams without a webhook get nothing.
+type ChatNotifier struct {
+	Teams  ChatTeams
+	Users  ChatHandles
+	Client *http.Client
+}
+
+func NewChatNotifier(teams ChatTeams, users ChatHandles, timeout time.Duration) *ChatNotifier {
+	return &ChatNotifier{
+		Teams:  teams,
+		Users:  users,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+// chatMessage is an incoming webhook payload. Slack renders the blocks and
+// Mattermost the text; Channel redirects a Mattermost post to @handle.
+type chatMessage struct {
+	Channel string      `json:"channel,omitempty"`
+	Text    string      `json:"text"`
+	Blocks  []chatBlock `json:"blocks"`
+}
+
+type chatBlock struct {
+	Type     string     `json:"type"`
+	Text     *chatText  `json:"text,omitempty"`
+	Elements []chatText `json:"elements,omitempty"`
+}
+
+type chatText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func newChatMessage(text string, pr model.PullRequestShort, teamName string) *chatMessage {
+	return &chatMessage{
+		Text: text,
+		Blocks: []chatBlock{
+			{Type: "section", Text: &chatText{Type: "mrkdwn", Text: text}},
+			{Type: "context", Elements: []chatText{
+				{Type: "mrkdwn", Text: fmt.Sprintf("`%s` · team %s", pr.PullRequestID, teamName)},
+			}},
+		},
+	}
+}
+
+func (n *ChatNotifier) Notify(ctx context.Context, notice *model.Notice) error {
+	chat, err := n.chat(ctx, notice.TeamName)
+	if err != nil || chat == nil || !chat.DirectMessages {
+		return err
+	}
+
+	handles, err := n.Users.GetChatHandles(ctx, []string{notice.UserID, notice.PullRequest.AuthorID, notice.ReplacedBy})
+	if err != nil {
+		return fmt.Errorf("failed to get chat handles: %v", err)
+	}
+	handle, ok := handles[notice.UserID]
+	if !ok || strings.HasPrefix(handle, "<") {
+		return nil
+	}
+
+	pr := notice.PullRequest
+	var text string
+	switch notice.Kind {
+	case model.NoticeAssigned:
+		text = fmt.Sprintf("%s, you have been assigned to review *%s* by %s.",
+			mention(handles, notice.UserID), pr.PullRequestName, mention(handles, pr.AuthorID))
+	case model.NoticeReplaced:
+		text = fmt.Sprintf("%s, your review of *%s* was handed over to %s.",
+			mention(handles, notice.UserID), pr.PullRequestName, mention(handles, notice.ReplacedBy))
+	default:
+		return nil
+	}
+
+	msg := newChatMessage(text, pr, notice.TeamName)
+	msg.Channel = "@" + handle
+	return n.post(ctx, chat.WebhookURL, msg)
+}
+
+func (n *ChatNotifier) Announce(ctx context.Context, announcement *model.Announcement) error {
+	pr := announcement.PullRequest
+	chat, err := n.chat(ctx, pr.TeamName)
+	if err != nil || chat == nil {
+		return err
+	}
+
+	userIDs := append([]string{pr.AuthorID, announcement.Replaced, announcement.ReplacedBy}, pr.AssignedReviewers...)
+	handles, err := n.Users.GetChatHandles(ctx, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get chat handles: %v", err)
+	}
+	reviewers := "nobody"
+	if len(pr.AssignedReviewers) > 0 {
+		mentions := make([]string, 0, len(pr.AssignedReviewers))
+		for _, id := range pr.AssignedReviewers {
+			mentions = append(mentions, mention(handles, id))
+		}
+		reviewers = strings.Join(mentions, ", ")
+	}
+
+	var text string
+	switch announcement.Kind {
+	case model.AnnounceCreated:
+		text = fmt.Sprintf("New pull request *%s* by %s, reviewed by %s.",
+			pr.PullRequestName, mention(handles, pr.AuthorID), reviewers)
+	case model.AnnounceReassigned:
+		text = fmt.Sprintf("%s handed the review of *%s* over to %s.",
+			mention(handles, announcement.Replaced), pr.PullRequestName, mention(handles, announcement.ReplacedBy))
+	case model.AnnounceMerged:
+		text = fmt.Sprintf("*%s* by %s was merged, reviewed by %s.",
+			pr.PullRequestName, mention(handles, pr.AuthorID), reviewers)
+	default:
+		return nil
+	}
+
+	return n.post(ctx, chat.WebhookURL, newChatMessage(text, pr.PullRequestShort, pr.TeamName))
+}
+
+// SendDigest posts the reviews of each team to that team's webhook: to the
+// reviewer in person when the team turned direct messages on, and otherwise
+// to the channel with the reviewer mentioned. It reports a PartialError when
+// only some of the teams got their message.
+func (n *ChatNotifier) SendDigest(ctx context.Context, digest *model.ReviewDigest) error {
+	teams := make([]string, 0)
+	byTeam := make(map[string][]*model.ReviewAssignment)
+	userIDs := []string{digest.UserID}
+	for _, review := range digest.Reviews {
+		if _, ok := byTeam[review.TeamName]; !ok {
+			teams = append(teams, review.TeamName)
+		}
+		byTeam[review.TeamName] = append(byTeam[review.TeamName], review)
+		userIDs = append(userIDs, review.AuthorID)
+	}
+
+	var handles map[string]string
+	errs := make([]error, 0)
+	posted := 0
+	for _, teamName := range teams {
+		chat, err := n.chat(ctx, teamName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if chat == nil {
+			continue
+		}
+		if handles == nil {
+			handles, err = n.Users.GetChatHandles(ctx, userIDs)
+			if err != nil {
+				return fmt.Errorf("failed to get chat handles: %v", err)
+			}
+		}
+
+		reviews := byTeam[teamName]
+		lines := make([]string, 0, len(reviews)+1)
+		waiting := fmt.Sprintf("%d reviews are", len(reviews))
+		if len(reviews) == 1 {
+			waiting = "a review is"
+		}
+		lines = append(lines, fmt.Sprintf("%s, %s waiting on you:", mention(handles, digest.UserID), waiting))
+		for _, review := range reviews {
+			lines = append(lines, fmt.Sprintf("• *%s* by %s, waiting %s",
+				review.PullRequestName, mention(handles, review.AuthorID), age(review.AgeSeconds)))
+		}
+		text := strings.Join(lines, "\n")
+
+		msg := &chatMessage{
+			Text: text,
+			Blocks: []chatBlock{
+				{Type: "section", Text: &chatText{Type: "mrkdwn", Text: text}},
+				{Type: "context", Elements: []chatText{{Type: "mrkdwn", Text: "team " + teamName}}},
+			},
+		}
+		if handle, ok := handles[digest.UserID]; chat.DirectMessages && ok && !strings.HasPrefix(handle, "<") {
+			msg.Channel = "@" + handle
+		}
+		if err := n.post(ctx, chat.WebhookURL, msg); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		posted++
+	}
+	if len(errs) > 0 && posted > 0 {
+		return &PartialError{Err: errors.Join(errs...)}
+	}
+	return errors.Join(errs...)
+}
+
+// chat returns the team's chat, or nil when the team has no webhook.
+func (n *ChatNotifier) chat(ctx context.Context, teamName string) (*model.TeamChat, error) {
+	if teamName == "" {
+		return nil, nil
+	}
+	chat, err := n.Teams.GetChat(ctx, teamName)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat of team %s: %v", teamName, err)
+	}
+	if chat.WebhookURL == "" {
+		return nil, nil
+	}
+	return chat, nil
+}
+
+func (n *ChatNotifier) post(ctx context.Context, url string, msg *chatMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid chat webhook: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post chat message: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("chat webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// mention writes how the user is mentioned: @handle, a Slack member mention
+// such as <@U024BE7LH> as it is, and the bare user id without a handle.
+func mention(handles map[string]string, userID string) string {
+	handle, ok := handles[userID]
+	switch {
+	case !ok:
+		return userID
+	case strings.HasPrefix(handle, "<"):
+		return handle
+	default:
+		return "@" + handle
+	}
+}
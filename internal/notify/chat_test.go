@@ -0,0 +1,152 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+type chatDirectory struct {
+	chats   map[string]*model.TeamChat
+	handles map[string]string
+}
+
+func (d *chatDirectory) GetChat(ctx context.Context, teamName string) (*model.TeamChat, error) {
+	chat, ok := d.chats[teamName]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return chat, nil
+}
+
+func (d *chatDirectory) GetChatHandles(ctx context.Context, userIDs []string) (map[string]string, error) {
+	return d.handles, nil
+}
+
+func newTestChat(t *testing.T, directMessages bool) (*ChatNotifier, *[]chatMessage) {
+	t.Helper()
+	var posted []chatMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg chatMessage
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		posted = append(posted, msg)
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := &chatDirectory{
+		chats: map[string]*model.TeamChat{
+			"backend": {TeamName: "backend", WebhookURL: srv.URL, DirectMessages: directMessages},
+			"mobile":  {TeamName: "mobile"},
+		},
+		handles: map[string]string{"u1": "alice", "u2": "bob", "u3": "<@U024BE7LH>"},
+	}
+	return NewChatNotifier(dir, dir, time.Second), &posted
+}
+
+func TestChatAnnouncesToTeamChannel(t *testing.T) {
+	n, posted := newTestChat(t, false)
+	pr := &model.PullRequest{
+		PullRequestShort:  model.PullRequestShort{PullRequestID: "pr-1", PullRequestName: "Add search", AuthorID: "u1"},
+		TeamName:          "backend",
+		AssignedReviewers: []string{"u2", "u3", "u4"},
+	}
+
+	if err := n.Announce(context.Background(), &model.Announcement{Kind: model.AnnounceCreated, PullRequest: pr}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if err := n.Notify(context.Background(), Assigned(pr, "u2")[0]); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(*posted) != 1 {
+		t.Fatalf("expected only the channel post, got %d", len(*posted))
+	}
+	msg := (*posted)[0]
+	want := "New pull request *Add search* by @alice, reviewed by @bob, <@U024BE7LH>, u4."
+	if msg.Text != want || msg.Channel != "" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if len(msg.Blocks) != 2 || msg.Blocks[0].Text.Text != want {
+		t.Errorf("unexpected blocks: %+v", msg.Blocks)
+	}
+}
+
+func TestChatMessagesReviewersInPerson(t *testing.T) {
+	n, posted := newTestChat(t, true)
+	pr := &model.PullRequest{
+		PullRequestShort:  model.PullRequestShort{PullRequestID: "pr-1", PullRequestName: "Add search", AuthorID: "u1"},
+		TeamName:          "backend",
+		AssignedReviewers: []string{"u3"},
+	}
+
+	for _, notice := range Reassigned(pr, "u2", "u3") {
+		if err := n.Notify(context.Background(), notice); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+
+	// u3 is a Slack member id, which incoming webhooks cannot message
+	if len(*posted) != 1 {
+		t.Fatalf("expected one direct message, got %d", len(*posted))
+	}
+	msg := (*posted)[0]
+	if msg.Channel != "@bob" || !strings.Contains(msg.Text, "handed over to <@U024BE7LH>") {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestChatSkipsTeamsWithoutWebhook(t *testing.T) {
+	n, posted := newTestChat(t, true)
+
+	for _, teamName := range []string{"mobile", "none", ""} {
+		pr := &model.PullRequest{TeamName: teamName}
+		if err := n.Announce(context.Background(), &model.Announcement{Kind: model.AnnounceMerged, PullRequest: pr}); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+	}
+
+	if len(*posted) != 0 {
+		t.Errorf("expected no posts, got %d", len(*posted))
+	}
+}
+
+func TestChatSendsDigestPerTeam(t *testing.T) {
+	for _, directMessages := range []bool{false, true} {
+		n, posted := newTestChat(t, directMessages)
+		digest := &model.ReviewDigest{
+			UserID: "u2",
+			Reviews: []*model.ReviewAssignment{
+				{PullRequestShort: model.PullRequestShort{PullRequestID: "pr-1", PullRequestName: "Add search", AuthorID: "u1"}, TeamName: "backend", AgeSeconds: 7290},
+				{PullRequestShort: model.PullRequestShort{PullRequestID: "pr-2", PullRequestName: "Dark mode", AuthorID: "u1"}, TeamName: "mobile", AgeSeconds: 60},
+				{PullRequestShort: model.PullRequestShort{PullRequestID: "pr-3", PullRequestName: "Fix login", AuthorID: "u3"}, TeamName: "backend", AgeSeconds: 3600},
+			},
+		}
+
+		if err := n.SendDigest(context.Background(), digest); err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+
+		// mobile has no webhook
+		if len(*posted) != 1 {
+			t.Fatalf("expected one post, got %d", len(*posted))
+		}
+		msg := (*posted)[0]
+		want := "@bob, 2 reviews are waiting on you:\n" +
+			"• *Add search* by @alice, waiting 2h1m0s\n" +
+			"• *Fix login* by <@U024BE7LH>, waiting 1h0m0s"
+		if msg.Text != want {
+			t.Errorf("unexpected text: %q", msg.Text)
+		}
+		if wantChannel := map[bool]string{false: "", true: "@bob"}[directMessages]; msg.Channel != wantChannel {
+			t.Errorf("expected channel %q, got %q", wantChannel, msg.Channel)
+		}
+	}
+}
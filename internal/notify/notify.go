@@ -0,0 +1,143 @@
+// Package notify delivers messages to people through the configured
+// channels. Every channel is a Notifier; Multi fans a message out to all of
+// them.
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+// Notifier is a delivery channel.
+type Notifier interface {
+	// Notify tells a reviewer they were assigned to or replaced on a pull
+	// request.
+	Notify(ctx context.Context, notice *model.Notice) error
+	// Announce tells a team about a pull request it reviews.
+	Announce(ctx context.Context, announcement *model.Announcement) error
+	SendDigest(ctx context.Context, digest *model.ReviewDigest) error
+}
+
+// Assigned builds the notices of the reviewers who got a seat on pr.
+func Assigned(pr *model.PullRequest, reviewerIDs ...string) []*model.Notice {
+	notices := make([]*model.Notice, 0, len(reviewerIDs))
+	for _, id := range reviewerIDs {
+		notices = append(notices, &model.Notice{
+			Kind:        model.NoticeAssigned,
+			UserID:      id,
+			PullRequest: pr.PullRequestShort,
+			TeamName:    pr.TeamName,
+		})
+	}
+	return notices
+}
+
+// Reassigned builds the notices of a seat on pr handed over from oldID to
+// newID.
+func Reassigned(pr *model.PullRequest, oldID, newID string) []*model.Notice {
+	return append(Assigned(pr, newID), &model.Notice{
+		Kind:        model.NoticeReplaced,
+		UserID:      oldID,
+		PullRequest: pr.PullRequestShort,
+		TeamName:    pr.TeamName,
+		ReplacedBy:  newID,
+	})
+}
+
+// LogNotifier writes the messages to the log. It stands in for the real
+// channels when none is configured.
+type LogNotifier struct {
+	Logger slog.Logger
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notice *model.Notice) error {
+	n.Logger.Info("review notice",
+		slog.String("kind", notice.Kind),
+		slog.String("user_id", notice.UserID),
+		slog.String("pull_request_id", notice.PullRequest.PullRequestID),
+	)
+	return nil
+}
+
+func (n *LogNotifier) Announce(ctx context.Context, announcement *model.Announcement) error {
+	n.Logger.Info("pull request announcement",
+		slog.String("kind", announcement.Kind),
+		slog.String("pull_request_id", announcement.PullRequest.PullRequestID),
+		slog.String("team_name", announcement.PullRequest.TeamName),
+	)
+	return nil
+}
+
+func (n *LogNotifier) SendDigest(ctx context.Context, digest *model.ReviewDigest) error {
+	ids := make([]string, 0, len(digest.Reviews))
+	for _, review := range digest.Reviews {
+		ids = append(ids, review.PullRequestID)
+	}
+	n.Logger.Info("review digest",
+		slog.String("user_id", digest.UserID),
+		slog.Any("pull_requests", ids),
+	)
+	return nil
+}
+
+// Multi delivers through every channel in turn. A failing channel does not
+// stop the others; their errors are joined.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, notice *model.Notice) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, notice); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) Announce(ctx context.Context, announcement *model.Announcement) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Announce(ctx, announcement); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendDigest reports a PartialError when some channel other than the log
+// delivered the digest while others failed.
+func (m Multi) SendDigest(ctx context.Context, digest *model.ReviewDigest) error {
+	var errs []error
+	delivered := false
+	for _, n := range m {
+		if err := n.SendDigest(ctx, digest); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// the log reaches no one
+		if _, ok := n.(*LogNotifier); !ok {
+			delivered = true
+		}
+	}
+	if len(errs) > 0 && delivered {
+		return &PartialError{Err: errors.Join(errs...)}
+	}
+	return errors.Join(errs...)
+}
+
+// PartialError is a delivery that reached the recipient through some of the
+// channels but failed on others.
+type PartialError struct {
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	return "partially delivered: " + e.Err.Error()
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+type channel struct {
+	err  error
+	sent int
+}
+
+func (c *channel) Notify(ctx context.Context, notice *model.Notice) error {
+	c.sent++
+	return c.err
+}
+
+func (c *channel) Announce(ctx context.Context, announcement *model.Announcement) error {
+	c.sent++
+	return c.err
+}
+
+func (c *channel) SendDigest(ctx context.Context, digest *model.ReviewDigest) error {
+	c.sent++
+	return c.err
+}
+
+func TestMultiDeliversThroughEveryChannel(t *testing.T) {
+	errDown := errors.New("channel down")
+	broken, working := &channel{err: errDown}, &channel{}
+
+	err := Multi{broken, working}.SendDigest(context.Background(), &model.ReviewDigest{UserID: "u1"})
+
+	if !errors.Is(err, errDown) {
+		t.Errorf("expected the channel error, got: %v", err)
+	}
+	if broken.sent != 1 || working.sent != 1 {
+		t.Errorf("expected both channels to be tried, got %d and %d", broken.sent, working.sent)
+	}
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Errorf("expected a partial delivery, got: %v", err)
+	}
+
+	// the log alone delivers nothing
+	logged := &LogNotifier{Logger: *slog.New(slog.NewTextHandler(io.Discard, nil))}
+	err = Multi{logged, broken}.SendDigest(context.Background(), &model.ReviewDigest{UserID: "u1"})
+	if !errors.Is(err, errDown) || errors.As(err, &partial) {
+		t.Errorf("expected an undelivered digest, got: %v", err)
+	}
+}
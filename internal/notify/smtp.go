@@ -0,0 +1,271 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// SMTPConfig is the mail server the notifications go out through. Username
+// is optional; without it the server is used unauthenticated. Timeout bounds
+// a whole delivery, from dialing to QUIT.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// defaultSMTPTimeout applies when the config leaves the timeout out.
+const defaultSMTPTimeout = 10 * time.Second
+
+// Recipients looks up where a user is emailed and which notifications they
+// opted out of.
+type Recipients interface {
+	GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error)
+}
+
+// SMTPNotifier emails the notifications. Every message has a plain text and
+// an HTML part, rendered from the templates of its kind. Users without an
+// email address, or who opted out of the kind, are skipped.
+type SMTPNotifier struct {
+	Config     SMTPConfig
+	Recipients Recipients
+
+	templates map[string]*mailTemplate
+	send      func(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+type mailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// mailData is what the templates render.
+type mailData struct {
+	Username    string
+	PullRequest model.PullRequestShort
+	ReplacedBy  string
+	Reviews     []*model.ReviewAssignment
+}
+
+var templateFuncs = map[string]any{
+	"age": age,
+}
+
+// age writes how long a review has waited, to the minute.
+func age(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).Truncate(time.Minute).String()
+}
+
+// NewSMTPNotifier parses the message templates.
+func NewSMTPNotifier(cfg SMTPConfig, recipients Recipients) (*SMTPNotifier, error) {
+	if cfg.Host == "" || cfg.From == "" {
+		return nil, fmt.Errorf("smtp host and from address are required")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSMTPTimeout
+	}
+
+	n := &SMTPNotifier{
+		Config:     cfg,
+		Recipients: recipients,
+		templates:  make(map[string]*mailTemplate),
+	}
+	n.send = n.sendMail
+	for _, kind := range []string{model.NoticeAssigned, model.NoticeReplaced, model.NoticeDigest} {
+		text, err := texttemplate.New(kind+".txt").Funcs(templateFuncs).ParseFS(templateFS, "templates/"+kind+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s text template: %v", kind, err)
+		}
+		html, err := htmltemplate.New(kind+".html").Funcs(templateFuncs).ParseFS(templateFS, "templates/"+kind+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s html template: %v", kind, err)
+		}
+		n.templates[kind] = &mailTemplate{text: text, html: html}
+	}
+	return n, nil
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, notice *model.Notice) error {
+	return n.mail(ctx, notice.Kind, notice.UserID, mailData{
+		PullRequest: notice.PullRequest,
+		ReplacedBy:  notice.ReplacedBy,
+	})
+}
+
+// Announce sends nothing: email goes to people, not to teams.
+func (n *SMTPNotifier) Announce(ctx context.Context, announcement *model.Announcement) error {
+	return nil
+}
+
+func (n *SMTPNotifier) SendDigest(ctx context.Context, digest *model.ReviewDigest) error {
+	return n.mail(ctx, model.NoticeDigest, digest.UserID, mailData{Reviews: digest.Reviews})
+}
+
+func (n *SMTPNotifier) mail(ctx context.Context, kind, userID string, data mailData) error {
+	tmpl, ok := n.templates[kind]
+	if !ok {
+		return fmt.Errorf("no email template for %q", kind)
+	}
+
+	settings, err := n.Recipients.GetNotificationSettings(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get notification settings of %s: %v", userID, err)
+	}
+	if settings.Email == "" || slices.Contains(settings.OptOut, kind) {
+		return nil
+	}
+	data.Username = settings.Username
+
+	msg, err := tmpl.render(n.Config.From, settings.Email, data)
+	if err != nil {
+		return fmt.Errorf("failed to render %s email: %v", kind, err)
+	}
+
+	var auth smtp.Auth
+	if n.Config.Username != "" {
+		auth = smtp.PlainAuth("", n.Config.Username, n.Config.Password, n.Config.Host)
+	}
+	addr := net.JoinHostPort(n.Config.Host, strconv.Itoa(n.Config.Port))
+	if err := n.send(ctx, addr, auth, n.Config.From, []string{settings.Email}, msg); err != nil {
+		return fmt.Errorf("failed to send %s email to %s: %v", kind, userID, err)
+	}
+	return nil
+}
+
+// sendMail delivers msg the way smtp.SendMail does, but gives up once
+// Config.Timeout passes or ctx is done, whichever comes first.
+func (n *SMTPNotifier) sendMail(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.Config.Timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	// a cancelled ctx interrupts the exchange in progress
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, n.Config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.Config.Host}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// render writes a multipart/alternative message. The subject is the
+// "subject" template defined next to the plain text body.
+func (t *mailTemplate) render(from, to string, data mailData) ([]byte, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,204 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+type recipients map[string]*model.NotificationSettings
+
+func (r recipients) GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error) {
+	settings, ok := r[userID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return settings, nil
+}
+
+type sent struct {
+	addr string
+	to   []string
+	msg  []byte
+}
+
+func newTestSMTPNotifier(t *testing.T, r recipients) (*SMTPNotifier, *[]sent) {
+	t.Helper()
+	n, err := NewSMTPNotifier(SMTPConfig{Host: "localhost", Port: 1025, From: "reviews@example.com"}, r)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	var outbox []sent
+	n.send = func(ctx context.Context, addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		outbox = append(outbox, sent{addr: addr, to: to, msg: msg})
+		return nil
+	}
+	return n, &outbox
+}
+
+func TestSMTPNotifyRendersTextAndHTML(t *testing.T) {
+	n, outbox := newTestSMTPNotifier(t, recipients{
+		"u2": {UserID: "u2", Username: "Bob", Email: "bob@example.com", OptOut: []string{}},
+	})
+
+	err := n.Notify(context.Background(), &model.Notice{
+		Kind:        model.NoticeAssigned,
+		UserID:      "u2",
+		PullRequest: model.PullRequestShort{PullRequestID: "pr-1", PullRequestName: "Add <search>", AuthorID: "u1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(*outbox) != 1 {
+		t.Fatalf("expected one email, got %d", len(*outbox))
+	}
+	email := (*outbox)[0]
+	if email.addr != "localhost:1025" || email.to[0] != "bob@example.com" {
+		t.Errorf("unexpected envelope: %s %v", email.addr, email.to)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(email.msg))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "Review requested: Add <search>" {
+		t.Errorf("unexpected subject: %q", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type: %q", msg.Header.Get("Content-Type"))
+	}
+
+	bodies := make(map[string]string)
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = string(body)
+	}
+	if !strings.Contains(bodies["text/plain"], `pr-1 "Add <search>" by u1`) {
+		t.Errorf("unexpected text body: %q", bodies["text/plain"])
+	}
+	if !strings.Contains(bodies["text/html"], "Add &lt;search&gt;") {
+		t.Errorf("expected the html body to be escaped, got: %q", bodies["text/html"])
+	}
+}
+
+func TestSMTPSkipsOptedOutAndUnaddressedUsers(t *testing.T) {
+	n, outbox := newTestSMTPNotifier(t, recipients{
+		"u2": {UserID: "u2", Email: "bob@example.com", OptOut: []string{model.NoticeDigest}},
+		"u3": {UserID: "u3", OptOut: []string{}},
+	})
+
+	digest := &model.ReviewDigest{UserID: "u2", Reviews: []*model.ReviewAssignment{{}}}
+	if err := n.SendDigest(context.Background(), digest); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	notice := &model.Notice{Kind: model.NoticeReplaced, UserID: "u3"}
+	if err := n.Notify(context.Background(), notice); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(*outbox) != 0 {
+		t.Errorf("expected no emails, got %d", len(*outbox))
+	}
+}
+
+// smtpServer answers one SMTP session on a local port and hands over the
+// message it received.
+func smtpServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ready")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				body, _ := tp.ReadDotBytes()
+				received <- string(body)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("502 %s not implemented", verb)
+			}
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestSMTPSendMail(t *testing.T) {
+	addr, received := smtpServer(t)
+	n, err := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", From: "reviews@example.com"}, recipients{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if err := n.sendMail(context.Background(), addr, nil, "reviews@example.com", []string{"bob@example.com"}, []byte("Subject: hi\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if msg := <-received; !strings.Contains(msg, "hello") {
+		t.Errorf("unexpected message: %q", msg)
+	}
+}
+
+func TestSMTPSendMailTimesOut(t *testing.T) {
+	// the server accepts the connection but never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	n, err := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", From: "reviews@example.com", Timeout: 50 * time.Millisecond}, recipients{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	start := time.Now()
+	err = n.sendMail(context.Background(), ln.Addr().String(), nil, "reviews@example.com", []string{"bob@example.com"}, []byte("hello"))
+	if err == nil {
+		t.Fatal("expected the silent server to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the timeout to cut the delivery short, took %s", elapsed)
+	}
+}
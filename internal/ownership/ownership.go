@@ -0,0 +1,172 @@
+// Package ownership parses CODEOWNERS-style rules and finds the rules owning
+// the files touched by a pull request.
+package ownership
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+// Parse reads rules in CODEOWNERS format: a pattern followed by owners
+// written as @user_id or @org/team_name. Blank lines and comments are
+// skipped; a rule without owners marks the files as unowned.
+func Parse(content string) ([]*model.OwnershipRule, error) {
+	rules := make([]*model.OwnershipRule, 0)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		for i, f := range fields {
+			if strings.HasPrefix(f, "#") {
+				fields = fields[:i]
+				break
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		rule := &model.OwnershipRule{
+			Line:    line,
+			Pattern: fields[0],
+			Owners:  fields[1:],
+		}
+		if _, err := compile(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", model.ErrInvalidRules, line, err)
+		}
+		for _, owner := range rule.Owners {
+			if !validOwner(owner) {
+				return nil, fmt.Errorf("%w: line %d: bad owner %q", model.ErrInvalidRules, line, owner)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidRules, err)
+	}
+
+	return rules, nil
+}
+
+// Format renders rules back into CODEOWNERS text.
+func Format(rules []*model.OwnershipRule) string {
+	var b strings.Builder
+	for _, rule := range rules {
+		b.WriteString(rule.Pattern)
+		for _, owner := range rule.Owners {
+			b.WriteString(" ")
+			b.WriteString(owner)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// MatchFiles returns the rules owning the given files, in the order the files
+// first hit them. As in CODEOWNERS the last matching rule wins for a file.
+// Rules without owners are left out.
+func MatchFiles(rules []*model.OwnershipRule, files []string) []*model.OwnershipRule {
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		// rules are validated on upload, a broken one simply never matches
+		patterns[i], _ = compile(rule.Pattern)
+	}
+
+	matched := make([]*model.OwnershipRule, 0)
+	seen := make(map[int]bool)
+	for _, file := range files {
+		file = strings.TrimPrefix(file, "/")
+		for i := len(rules) - 1; i >= 0; i-- {
+			if patterns[i] == nil || !patterns[i].MatchString(file) {
+				continue
+			}
+			if len(rules[i].Owners) > 0 && !seen[i] {
+				seen[i] = true
+				matched = append(matched, rules[i])
+			}
+			break
+		}
+	}
+	return matched
+}
+
+// SplitOwners separates user ids from team names in a rule's owners.
+func SplitOwners(owners []string) (userIDs, teamNames []string) {
+	userIDs = make([]string, 0)
+	teamNames = make([]string, 0)
+	for _, owner := range owners {
+		owner = strings.TrimPrefix(owner, "@")
+		if i := strings.LastIndex(owner, "/"); i >= 0 {
+			teamNames = append(teamNames, owner[i+1:])
+		} else {
+			userIDs = append(userIDs, owner)
+		}
+	}
+	return userIDs, teamNames
+}
+
+func validOwner(owner string) bool {
+	name := strings.TrimPrefix(owner, "@")
+	if name == owner || name == "" {
+		return false
+	}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return i > 0 && i < len(name)-1
+	}
+	return true
+}
+
+// compile turns a gitignore-style pattern into a regexp over slash separated
+// paths relative to the repository root.
+func compile(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") {
+		return nil, fmt.Errorf("negated patterns are not supported")
+	}
+	if strings.ContainsAny(pattern, "[]") {
+		return nil, fmt.Errorf("character ranges are not supported")
+	}
+
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,91 @@
+package ownership
+
+import (
+	"errors"
+	"testing"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+const codeowners = `
+# default owners
+*               @u1
+
+/docs/          @acme/writers
+*.go            @u2 @u3   # backend code
+internal/db/**  @u4
+vendor/
+`
+
+func TestParse(t *testing.T) {
+	rules, err := Parse(codeowners)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 5 {
+		t.Fatalf("expected 5 rules, got %d", len(rules))
+	}
+
+	goRule := rules[2]
+	if goRule.Line != 6 || goRule.Pattern != "*.go" || len(goRule.Owners) != 2 {
+		t.Errorf("unexpected rule: %+v", goRule)
+	}
+	if len(rules[4].Owners) != 0 {
+		t.Errorf("expected unowned rule, got %+v", rules[4])
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, content := range []string{
+		"*.go u1",
+		"*.go @",
+		"*.go @acme/",
+		"!*.go @u1",
+		"[ab].go @u1",
+	} {
+		if _, err := Parse(content); !errors.Is(err, model.ErrInvalidRules) {
+			t.Errorf("%q: expected ErrInvalidRules, got: %v", content, err)
+		}
+	}
+}
+
+func TestMatchFiles(t *testing.T) {
+	rules, err := Parse(codeowners)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]string{
+		"README.md":               "*",
+		"docs/guide/intro.md":     "/docs/",
+		"cmd/main.go":             "*.go",
+		"internal/db/pg/conn.go":  "internal/db/**",
+		"internal/api/handler.go": "*.go",
+		"sub/docs/readme.md":      "*",
+	}
+	for file, pattern := range cases {
+		matched := MatchFiles(rules, []string{file})
+		if len(matched) != 1 || matched[0].Pattern != pattern {
+			t.Errorf("%s: expected %q, got %+v", file, pattern, matched)
+		}
+	}
+
+	if matched := MatchFiles(rules, []string{"vendor/lib/x.go"}); len(matched) != 0 {
+		t.Errorf("expected unowned vendor file, got %+v", matched)
+	}
+
+	matched := MatchFiles(rules, []string{"a.go", "b.go", "README.md"})
+	if len(matched) != 2 || matched[0].Pattern != "*.go" || matched[1].Pattern != "*" {
+		t.Errorf("unexpected rules: %+v", matched)
+	}
+}
+
+func TestSplitOwners(t *testing.T) {
+	users, teams := SplitOwners([]string{"@u1", "@acme/backend", "@u2"})
+	if len(users) != 2 || users[0] != "u1" || users[1] != "u2" {
+		t.Errorf("unexpected users: %v", users)
+	}
+	if len(teams) != 1 || teams[0] != "backend" {
+		t.Errorf("unexpected teams: %v", teams)
+	}
+}
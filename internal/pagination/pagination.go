@@ -0,0 +1,57 @@
+// Package pagination turns page cursors into the opaque strings list
+// endpoints hand out and parses the page size.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+const (
+	// DefaultLimit is the page size when the client asks for none.
+	DefaultLimit = 50
+	// MaxLimit is the largest page size a client may ask for.
+	MaxLimit = 100
+)
+
+// Encode returns the opaque form of the cursor, the empty string for nil.
+func Encode(c *model.Cursor) string {
+	if c == nil {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor made by Encode. The empty string means the first
+// page and yields nil.
+func Decode(s string) (*model.Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, model.ErrInvalidInput
+	}
+	var c model.Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, model.ErrInvalidInput
+	}
+	return &c, nil
+}
+
+// ParseLimit reads the page size, DefaultLimit when s is empty. Sizes outside
+// 1..MaxLimit are rejected.
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > MaxLimit {
+		return 0, model.ErrInvalidInput
+	}
+	return limit, nil
+}
@@ -0,0 +1,42 @@
+package pagination
+
+import (
+	"testing"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := &model.Cursor{Key: "2025-01-02T03:04:05Z", ID: "pr-1"}
+
+	got, err := Decode(Encode(c))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *got != *c {
+		t.Errorf("expected %+v, got %+v", c, got)
+	}
+
+	if got, err := Decode(""); got != nil || err != nil {
+		t.Errorf("expected the first page, got %+v %v", got, err)
+	}
+	for _, bad := range []string{"!!", Encode(&model.Cursor{Key: "x"})} {
+		if _, err := Decode(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	if limit, err := ParseLimit(""); err != nil || limit != DefaultLimit {
+		t.Errorf("expected the default limit, got %d %v", limit, err)
+	}
+	if limit, err := ParseLimit("10"); err != nil || limit != 10 {
+		t.Errorf("expected 10, got %d %v", limit, err)
+	}
+	for _, bad := range []string{"0", "-1", "101", "ten"} {
+		if _, err := ParseLimit(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
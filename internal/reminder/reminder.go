@@ -0,0 +1,142 @@
+// Package reminder sends reviewers digests of the OPEN reviews that have been
+// waiting on them for too long. A Scheduler runs on a cron expression; every
+// reviewer gets at most one digest per MinInterval, and only while they are
+// at work.
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/calendar"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/notify"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler sends the stale review digests.
+type Scheduler struct {
+	Logger   slog.Logger
+	Repo     repository.UserRepository
+	Notifier notify.Notifier
+	// Schedule tells when digests go out, in UTC.
+	Schedule cron.Schedule
+	// StaleAfter is how much of the reviewer's working time a review waits
+	// before it is reminded of.
+	StaleAfter time.Duration
+	// MinInterval is the least time between two digests to one reviewer.
+	MinInterval time.Duration
+	// Now is the clock the reviews are aged against.
+	Now func() time.Time
+}
+
+// NewScheduler parses spec, a standard five field cron expression or a
+// descriptor such as @hourly.
+func NewScheduler(logger slog.Logger, repo repository.UserRepository, notifier notify.Notifier, spec string, staleAfter, minInterval time.Duration) (*Scheduler, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reminder schedule %q: %v", spec, err)
+	}
+	if staleAfter <= 0 {
+		return nil, fmt.Errorf("reminder stale_after must be positive, got %s", staleAfter)
+	}
+
+	return &Scheduler{
+		Logger:      logger,
+		Repo:        repo,
+		Notifier:    notifier,
+		Schedule:    schedule,
+		StaleAfter:  staleAfter,
+		MinInterval: minInterval,
+		Now:         time.Now,
+	}, nil
+}
+
+// Run sends the digests on every tick of the schedule until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		now := s.Now()
+		timer := time.NewTimer(s.Schedule.Next(now.UTC()).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.Send(ctx); err != nil {
+			s.Logger.Error("review reminders failed", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// Send makes one pass over the reviewers. Everyone at work with reviews older
+// than StaleAfter in their working time, and without a digest in the last
+// MinInterval, gets one. A digest that no channel delivered does not count
+// against MinInterval.
+func (s *Scheduler) Send(ctx context.Context) error {
+	now := s.Now()
+	queues, err := s.Repo.ReviewQueues(ctx, now.Add(-s.StaleAfter))
+	if err != nil {
+		return err
+	}
+
+	for _, q := range queues {
+		cal, err := calendar.New(q.Schedule, q.Holidays)
+		if err != nil {
+			s.Logger.Warn("reviewer schedule ignored",
+				slog.String("user_id", q.UserID),
+				slog.String("error", err.Error()))
+		}
+		if !cal.Working(now) {
+			continue
+		}
+
+		stale := make([]*model.ReviewAssignment, 0, len(q.Reviews))
+		for _, review := range q.Reviews {
+			if !cal.Add(review.AssignedAt, s.StaleAfter).After(now) {
+				review.AgeSeconds = int64(now.Sub(review.AssignedAt).Seconds())
+				stale = append(stale, review)
+			}
+		}
+		if len(stale) == 0 {
+			continue
+		}
+
+		marked, previous, err := s.Repo.MarkReminded(ctx, q.UserID, now, now.Add(-s.MinInterval))
+		if err != nil {
+			return err
+		}
+		if !marked {
+			continue
+		}
+
+		digest := &model.ReviewDigest{
+			UserID:   q.UserID,
+			Username: q.Username,
+			Reviews:  stale,
+			SentAt:   now,
+		}
+		if err := s.Notifier.SendDigest(ctx, digest); err != nil {
+			s.Logger.Error("failed to send review digest",
+				slog.String("user_id", q.UserID),
+				slog.String("error", err.Error()))
+			var partial *notify.PartialError
+			if errors.As(err, &partial) {
+				// the reviewer was reminded through another channel
+				continue
+			}
+			// the reviewer was not reminded, so the next tick may try again
+			if err := s.Repo.UnmarkReminded(ctx, q.UserID, now, previous); err != nil {
+				s.Logger.Error("failed to unmark reminded",
+					slog.String("user_id", q.UserID),
+					slog.String("error", err.Error()))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/mocks"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/notify"
+	"go.uber.org/mock/gomock"
+)
+
+type outbox struct {
+	digests []*model.ReviewDigest
+	err     error
+}
+
+func (o *outbox) Notify(ctx context.Context, notice *model.Notice) error {
+	return nil
+}
+
+func (o *outbox) Announce(ctx context.Context, announcement *model.Announcement) error {
+	return nil
+}
+
+func (o *outbox) SendDigest(ctx context.Context, digest *model.ReviewDigest) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.digests = append(o.digests, digest)
+	return nil
+}
+
+func review(id string, assignedAt time.Time) *model.ReviewAssignment {
+	return &model.ReviewAssignment{
+		PullRequestShort: model.PullRequestShort{PullRequestID: id, Status: "OPEN"},
+		AssignedAt:       assignedAt,
+	}
+}
+
+func TestSendDigestsStaleReviews(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockUserRepository(ctrl)
+	// Monday 10:00 UTC
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	office := model.WorkSchedule{Timezone: "UTC", WorkStart: "09:00", WorkEnd: "17:00"}
+	night := model.WorkSchedule{Timezone: "Asia/Tokyo", WorkStart: "09:00", WorkEnd: "17:00"}
+
+	repo.EXPECT().ReviewQueues(gomock.Any(), now.Add(-4*time.Hour)).Return([]*model.ReviewerQueue{
+		{UserID: "u1", Schedule: office, Reviews: []*model.ReviewAssignment{
+			// Friday 13:00: four working hours on Friday
+			review("pr-1", time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC)),
+			// Friday 15:00: two hours on Friday and one on Monday
+			review("pr-2", time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)),
+		}},
+		// 19:00 in Tokyo, off work
+		{UserID: "u2", Schedule: night, Reviews: []*model.ReviewAssignment{
+			review("pr-3", time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)),
+		}},
+		{UserID: "u3", Reviews: []*model.ReviewAssignment{
+			review("pr-4", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)),
+		}},
+	}, nil)
+	repo.EXPECT().MarkReminded(gomock.Any(), "u1", now, now.Add(-24*time.Hour)).Return(true, nil, nil)
+	// u3 got a digest less than a day ago
+	repo.EXPECT().MarkReminded(gomock.Any(), "u3", now, now.Add(-24*time.Hour)).Return(false, nil, nil)
+
+	sent := &outbox{}
+	s, err := NewScheduler(*slog.New(slog.NewTextHandler(io.Discard, nil)), repo, sent, "@hourly", 4*time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	s.Now = func() time.Time { return now }
+
+	if err := s.Send(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(sent.digests) != 1 {
+		t.Fatalf("expected one digest, got %d", len(sent.digests))
+	}
+	digest := sent.digests[0]
+	if digest.UserID != "u1" || len(digest.Reviews) != 1 || digest.Reviews[0].PullRequestID != "pr-1" {
+		t.Errorf("unexpected digest: %+v", digest)
+	}
+	if age := digest.Reviews[0].AgeSeconds; age != int64((69 * time.Hour).Seconds()) {
+		t.Errorf("expected the wall clock age, got %d", age)
+	}
+}
+
+func TestSendDigestFailureUnmarks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockUserRepository(ctrl)
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	repo.EXPECT().ReviewQueues(gomock.Any(), now.Add(-4*time.Hour)).Return([]*model.ReviewerQueue{
+		{UserID: "u1", Reviews: []*model.ReviewAssignment{
+			review("pr-1", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)),
+		}},
+	}, nil)
+	previous := now.Add(-48 * time.Hour)
+	gomock.InOrder(
+		repo.EXPECT().MarkReminded(gomock.Any(), "u1", now, now.Add(-24*time.Hour)).Return(true, &previous, nil),
+		repo.EXPECT().UnmarkReminded(gomock.Any(), "u1", now, &previous).Return(nil),
+	)
+
+	sent := &outbox{err: errors.New("smtp: connection refused")}
+	s, err := NewScheduler(*slog.New(slog.NewTextHandler(io.Discard, nil)), repo, sent, "@hourly", 4*time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	s.Now = func() time.Time { return now }
+
+	if err := s.Send(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
+
+func TestSendDigestPartialDeliveryKeepsMark(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockUserRepository(ctrl)
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+	repo.EXPECT().ReviewQueues(gomock.Any(), now.Add(-4*time.Hour)).Return([]*model.ReviewerQueue{
+		{UserID: "u1", Reviews: []*model.ReviewAssignment{
+			review("pr-1", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)),
+		}},
+	}, nil)
+	// no UnmarkReminded: the chat got the digest out
+	repo.EXPECT().MarkReminded(gomock.Any(), "u1", now, now.Add(-24*time.Hour)).Return(true, nil, nil)
+
+	chat := &outbox{}
+	notifier := notify.Multi{&outbox{err: errors.New("smtp: connection refused")}, chat}
+	s, err := NewScheduler(*slog.New(slog.NewTextHandler(io.Discard, nil)), repo, notifier, "@hourly", 4*time.Hour, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	s.Now = func() time.Time { return now }
+
+	if err := s.Send(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(chat.digests) != 1 {
+		t.Errorf("expected the digest through the working channel, got %d", len(chat.digests))
+	}
+}
+
+func TestNewSchedulerInvalidSpec(t *testing.T) {
+	logger := *slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := NewScheduler(logger, nil, nil, "every morning", time.Hour, time.Hour); err == nil {
+		t.Error("expected an invalid cron expression to be rejected")
+	}
+	if _, err := NewScheduler(logger, nil, nil, "0 9 * * 1-5", 0, time.Hour); err == nil {
+		t.Error("expected a zero stale_after to be rejected")
+	}
+}
@@ -5,83 +5,126 @@ import (
 	"database/sql"
 	"fmt"
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/ownership"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/pagination"
 	def "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/selection"
+	"github.com/lib/pq"
+	"strings"
 	"time"
 )
 
 var _ def.PullRequestRepository = (*repository)(nil)
 
 type repository struct {
-	db *sql.DB
+	db        *sql.DB
+	selection selection.Config
 }
 
-func NewRepository(db *sql.DB) *repository {
-	return &repository{db: db}
+func NewRepository(db *sql.DB, cfg selection.Config) *repository {
+	return &repository{db: db, selection: cfg}
 }
 
 func (r *repository) Create(ctx context.Context, req model.PullRequestPayload) (*model.PullRequest, error) {
-	getCommandQuery := `
-		SELECT team_name 
-		FROM users 
-		WHERE user_id = $1
-	`
-
-	var teamName string
-	err := r.db.
-		QueryRowContext(ctx, getCommandQuery, req.AuthorID).
-		Scan(&teamName)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
 
+	teamID, teamName, err := primaryTeam(ctx, tx, req.AuthorID)
 	if err != nil {
-		return nil, model.ErrNotFound
+		return nil, err
 	}
 
-	getReviewiersQuery := `
-		SELECT user_id FROM users
-		WHERE team_name = $1 AND is_active = TRUE AND user_id <> $2
-		ORDER BY random() 
-		LIMIT 2
-	`
+	if req.TeamName != "" {
+		teamID, teamName, err = teamByName(ctx, tx, req.TeamName)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	rows, err := r.db.
-		QueryContext(ctx, getReviewiersQuery, teamName, req.AuthorID)
+	matches := make([]*model.ReviewerMatch, 0)
+	uncovered := req.RequiredSkills
+	if teamID.Valid {
+		owners, err := ownerCandidates(ctx, tx, teamID.Int64, req.AuthorID, req.ChangedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owners: %v", err)
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to get reviewers: %v", err)
-	}
-	defer rows.Close()
+		pool, err := selectReviewers(ctx, tx, teamID.Int64, req.AuthorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reviewers: %v", err)
+		}
 
-	var reviewers []string
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
+		minSeniors, err := seniorRule(ctx, tx, teamID.Int64)
+		if err != nil {
+			return nil, err
+		}
+
+		var history map[string]int
+		if window := r.selection.Window(); window > 0 {
+			history, err = recentReviewers(ctx, tx, req.AuthorID, window)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		in := selection.Input{
+			Limit:          2,
+			Owners:         owners,
+			Pool:           pool,
+			RequiredSkills: req.RequiredSkills,
+			MinSeniors:     minSeniors,
+			History:        history,
+			Now:            time.Now(),
+		}
+		matches, uncovered, err = selection.Pick(in)
+
+		// the fallback chain is only loaded when the team cannot fill the seats
+		if err == model.ErrNoSenior || (err == nil && len(matches) < in.Limit) {
+			in.Fallbacks, err = fallbackCandidates(ctx, tx, teamID.Int64, req.AuthorID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get fallback reviewers: %v", err)
+			}
+			matches, uncovered, err = selection.Pick(in)
+		}
+		if err != nil {
 			return nil, err
 		}
-		reviewers = append(reviewers, id)
 	}
+	reviewers := selection.ReviewerIDs(matches)
 
 	addNewPRQuery := `
-        INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, created_at)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id, createdAt)
+        VALUES ($1, $2, $3, $4, $5, $6)
 	`
 	addReviewiers := `
-		INSERT INTO pr_reviewers (pull_request_id, reviewer_id) 
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id)
 		VALUES ($1, $2)
 	`
 	now := time.Now().UTC()
 
-	_, err = r.db.
-		ExecContext(ctx, addNewPRQuery, req.PullRequestID, req.PullRequestName, req.AuthorID, statusToID("OPEN"), now)
+	_, err = tx.
+		ExecContext(ctx, addNewPRQuery, req.PullRequestID, req.PullRequestName, req.AuthorID, statusToID("OPEN"), teamID, now)
 
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return nil, model.ErrPrExists
+	}
 	if err != nil {
 		return nil, fmt.Errorf("insert pr failed: %v", err)
 	}
 	for _, rid := range reviewers {
-		_, err := r.db.ExecContext(ctx, addReviewiers, req.PullRequestID, rid)
+		_, err := tx.ExecContext(ctx, addReviewiers, req.PullRequestID, rid)
 		if err != nil {
 			return nil, fmt.Errorf("insert reviewer failed: %v", err)
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit error: %v", err)
+	}
+
 	pr := &model.PullRequest{
 		PullRequestShort: model.PullRequestShort{
 			PullRequestID:   req.PullRequestID,
@@ -89,46 +132,53 @@ func (r *repository) Create(ctx context.Context, req model.PullRequestPayload) (
 			AuthorID:        req.AuthorID,
 			Status:          "OPEN",
 		},
+		TeamName:          teamName.String,
 		AssignedReviewers: reviewers,
 		CreatedAt:         &now,
+		ReviewerMatches:   matches,
+		UncoveredSkills:   uncovered,
 	}
 	return pr, nil
 }
 
-func (r *repository) Merge(ctx context.Context, prID string) (*model.PullRequest, error) {
+// Merge marks the pull request MERGED. Merging it again changes nothing;
+// merged reports whether this call was the one that merged it.
+func (r *repository) Merge(ctx context.Context, prID string) (*model.PullRequest, bool, error) {
 	var (
 		name, author        string
 		statusID            int
+		teamName            sql.NullString
 		createdAt, mergedAt *time.Time
 	)
 
-	getPrQuery := ` 
-		SELECT pull_request_name, author_id, status_id, createdAt, mergedAt 
-		FROM pull_requests 
-		WHERE pull_request_id = $1
+	getPrQuery := `
+		SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name
+		FROM pull_requests pr
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		WHERE pr.pull_request_id = $1
 	`
 	err := r.db.
 		QueryRowContext(ctx, getPrQuery, prID).
-		Scan(&name, &author, &statusID, &createdAt, &mergedAt)
+		Scan(&name, &author, &statusID, &createdAt, &mergedAt, &teamName)
 
 	if err == sql.ErrNoRows {
-		return nil, model.ErrNotFound
+		return nil, false, model.ErrNotFound
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("select pr error: %v", err)
+		return nil, false, fmt.Errorf("select pr error: %v", err)
 	}
 
 	getReviewerIdQuery := `
-		SELECT reviewer_id 
-		FROM pr_reviewers 
+		SELECT reviewer_user_id
+		FROM pull_request_reviewers
 		WHERE pull_request_id = $1
 	`
 	rows, err := r.db.
 		QueryContext(ctx, getReviewerIdQuery, prID)
 
 	if err != nil {
-		return nil, fmt.Errorf("get reviewers error: %v", err)
+		return nil, false, fmt.Errorf("get reviewers error: %v", err)
 	}
 	defer rows.Close()
 
@@ -136,7 +186,7 @@ func (r *repository) Merge(ctx context.Context, prID string) (*model.PullRequest
 	for rows.Next() {
 		var rid string
 		if err := rows.Scan(&rid); err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		reviewers = append(reviewers, rid)
 	}
@@ -149,21 +199,37 @@ func (r *repository) Merge(ctx context.Context, prID string) (*model.PullRequest
 				AuthorID:        author,
 				Status:          "MERGED",
 			},
+			TeamName:          teamName.String,
 			AssignedReviewers: reviewers,
 			CreatedAt:         createdAt,
 			MergedAt:          mergedAt,
 		}
-		return pr, nil
+		return pr, false, nil
 	}
 
+	// only the merge that finds the pull request still OPEN records itself
 	updatePrQuery := `
-        UPDATE pull_requests SET status_id=$1, mergedAt=$2 
-		WHERE pull_request_id=$3
+        UPDATE pull_requests SET status_id=$1, mergedAt=$2
+		WHERE pull_request_id=$3 AND status_id <> $1
     `
 	mergedNow := time.Now().UTC()
-	_, err = r.db.ExecContext(ctx, updatePrQuery, statusToID("MERGED"), mergedNow, prID)
+	result, err := r.db.ExecContext(ctx, updatePrQuery, statusToID("MERGED"), mergedNow, prID)
+	if err != nil {
+		return nil, false, fmt.Errorf("merge error: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return nil, fmt.Errorf("merge error: %v", err)
+		return nil, false, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	merged := rowsAffected > 0
+	if !merged {
+		// a concurrent merge got there first
+		if err := r.db.QueryRowContext(ctx, "SELECT mergedAt FROM pull_requests WHERE pull_request_id = $1", prID).Scan(&mergedAt); err != nil {
+			return nil, false, fmt.Errorf("select pr error: %v", err)
+		}
+		if mergedAt != nil {
+			mergedNow = *mergedAt
+		}
 	}
 
 	pr := &model.PullRequest{
@@ -173,28 +239,44 @@ func (r *repository) Merge(ctx context.Context, prID string) (*model.PullRequest
 			AuthorID:        author,
 			Status:          "MERGED",
 		},
+		TeamName:          teamName.String,
 		AssignedReviewers: reviewers,
 		CreatedAt:         createdAt,
 		MergedAt:          &mergedNow,
 	}
-	return pr, nil
+	return pr, merged, nil
 }
 
-func (r *repository) Reassign(ctx context.Context, prID string, oldReviewerID string) (*model.PullRequest, string, error) {
+// Reassign hands oldReviewerID's seat over to newReviewerID, or to a
+// reviewer picked from the team and its fallback chain when newReviewerID is
+// empty. Users already reviewing the pull request are never picked.
+func (r *repository) Reassign(ctx context.Context, prID string, oldReviewerID string, newReviewerID string) (*model.PullRequest, string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
 	var (
 		name, author        string
 		statusID            int
+		teamID              sql.NullInt64
+		teamName            sql.NullString
 		createdAt, mergedAt *time.Time
 	)
+	// the row lock keeps concurrent reviewer changes and the SLA worker out
+	// until the seat is handed over
 	getPrQuery := `
-		SELECT pull_request_name, author_id, status_id, createdAt, mergedAt
-        FROM pull_requests
-		WHERE pull_request_id = $1
+		SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, pr.team_id, t.team_name
+        FROM pull_requests pr
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		WHERE pr.pull_request_id = $1
+		FOR UPDATE OF pr
 	`
 
-	err := r.db.
+	err = tx.
 		QueryRowContext(ctx, getPrQuery, prID).
-		Scan(&name, &author, &statusID, &createdAt, &mergedAt)
+		Scan(&name, &author, &statusID, &createdAt, &mergedAt, &teamID, &teamName)
 	if err == sql.ErrNoRows {
 		return nil, "", model.ErrNotFound
 	}
@@ -207,22 +289,22 @@ func (r *repository) Reassign(ctx context.Context, prID string, oldReviewerID st
 	}
 
 	getReviewerIdQuery := `
-		SELECT reviewer_id FROM pr_reviewers WHERE pull_request_id = $1
+		SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id = $1
 	`
 
-	rows, err := r.db.
+	rows, err := tx.
 		QueryContext(ctx, getReviewerIdQuery, prID)
 
 	if err != nil {
 		return nil, "", fmt.Errorf("get reviewers error: %v", err)
 	}
-	defer rows.Close()
 
 	var reviewers []string
 	found := false
 	for rows.Next() {
 		var rid string
 		if err := rows.Scan(&rid); err != nil {
+			rows.Close()
 			return nil, "", err
 		}
 		reviewers = append(reviewers, rid)
@@ -230,74 +312,986 @@ func (r *repository) Reassign(ctx context.Context, prID string, oldReviewerID st
 			found = true
 		}
 	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, "", fmt.Errorf("get reviewers error: %v", err)
+	}
 	if !found {
-		return nil, "", model.ErrNotFound
+		return nil, "", model.ErrNotAssigned
+	}
+
+	// pull requests whose team was deleted fall back to the author's team
+	if !teamID.Valid {
+		teamID, teamName, err = primaryTeam(ctx, tx, author)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	needSenior := false
+	if teamID.Valid {
+		needSenior, err = replacementMustBeSenior(ctx, tx, teamID.Int64, reviewers, oldReviewerID)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var fallbackTeam string
+	newReviewer, err := r.replacement(ctx, tx, teamID, author, reviewers, newReviewerID, needSenior)
+	if err == sql.ErrNoRows && teamID.Valid {
+		fallbacks, ferr := fallbackTeams(ctx, tx, teamID.Int64)
+		if ferr != nil {
+			return nil, "", fmt.Errorf("get fallback teams error: %v", ferr)
+		}
+		for _, fb := range fallbacks {
+			newReviewer, err = r.replacement(ctx, tx, fb.id, author, reviewers, newReviewerID, needSenior)
+			if err != sql.ErrNoRows {
+				fallbackTeam = fb.name
+				break
+			}
+		}
+	}
+
+	if err == sql.ErrNoRows && needSenior {
+		return nil, "", model.ErrNoSenior
+	} else if err == sql.ErrNoRows {
+		return nil, "", model.ErrNoCandidate
+	} else if err != nil {
+		return nil, "", fmt.Errorf("get new reviewer error: %v", err)
 	}
 
-	getTeamNameQuery := `
-		SELECT team_name FROM users WHERE user_id = $1
+	updateReviewerQuery := `
+		UPDATE pull_request_reviewers
+		SET reviewer_user_id = $1, assigned_at = CURRENT_TIMESTAMP, reviewed_at = NULL, overdue_at = NULL
+        WHERE pull_request_id = $2 AND reviewer_user_id = $3
 	`
-	var teamName string
-	err = r.db.
-		QueryRowContext(ctx, getTeamNameQuery, author).
-		Scan(&teamName)
+	_, err = tx.
+		ExecContext(ctx, updateReviewerQuery, newReviewer, prID, oldReviewerID)
 
 	if err != nil {
-		return nil, "", model.ErrNotFound
+		return nil, "", fmt.Errorf("update reviewer error: %v", err)
 	}
 
-	getNewReviewerQuery := `
-        SELECT user_id
-        FROM users
-        WHERE team_name = $1 AND is_active = TRUE AND user_id <> $2 AND user_id <> $3
-        ORDER BY random() 
-		LIMIT 1
+	if err := recordHistory(ctx, tx, prID, newReviewer, "reassigned", oldReviewerID); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("commit error: %v", err)
+	}
+
+	for i := range reviewers {
+		if reviewers[i] == oldReviewerID {
+			reviewers[i] = newReviewer
+			break
+		}
+	}
+
+	pr := &model.PullRequest{
+		PullRequestShort: model.PullRequestShort{
+			PullRequestID:   prID,
+			PullRequestName: name,
+			AuthorID:        author,
+			Status:          idToStatus(statusID),
+		},
+		TeamName:          teamName.String,
+		AssignedReviewers: reviewers,
+		CreatedAt:         createdAt,
+		MergedAt:          mergedAt,
+		ReviewerMatches:   []*model.ReviewerMatch{{UserID: newReviewer, FallbackTeam: fallbackTeam}},
+	}
+
+	return pr, newReviewer, nil
+}
+
+// Get loads a pull request with its reviewers.
+func (r *repository) Get(ctx context.Context, prID string) (*model.PullRequest, error) {
+	return getPullRequest(ctx, r.db, prID)
+}
+
+// List returns one page of the pull requests matching the filter, ordered by
+// the sort key and then by id.
+func (r *repository) List(ctx context.Context, filter model.PullRequestFilter) (*model.PullRequestPage, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Status != "" {
+		conds = append(conds, "pr.status_id = "+arg(statusToID(filter.Status)))
+	}
+	if filter.AuthorID != "" {
+		conds = append(conds, "pr.author_id = "+arg(filter.AuthorID))
+	}
+	if filter.ReviewerID != "" {
+		conds = append(conds, `EXISTS (
+			SELECT 1 FROM pull_request_reviewers prr
+			WHERE prr.pull_request_id = pr.pull_request_id AND prr.reviewer_user_id = `+arg(filter.ReviewerID)+`
+		)`)
+	}
+	if filter.TeamName != "" {
+		conds = append(conds, "t.team_name = "+arg(filter.TeamName))
+	}
+	if filter.CreatedFrom != nil {
+		conds = append(conds, "pr.createdAt >= "+arg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conds = append(conds, "pr.createdAt < "+arg(*filter.CreatedTo))
+	}
+	if filter.MergedFrom != nil {
+		conds = append(conds, "pr.mergedAt >= "+arg(*filter.MergedFrom))
+	}
+	if filter.MergedTo != nil {
+		conds = append(conds, "pr.mergedAt < "+arg(*filter.MergedTo))
+	}
+
+	sortColumn := "pr.createdAt"
+	if filter.Sort == model.PullRequestSortName {
+		sortColumn = "pr.pull_request_name"
+	}
+	direction, cmp := "ASC", ">"
+	if filter.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		var key any = filter.After.Key
+		if sortColumn == "pr.createdAt" {
+			createdAt, err := time.Parse(time.RFC3339Nano, filter.After.Key)
+			if err != nil {
+				return nil, model.ErrInvalidInput
+			}
+			key = createdAt
+		}
+		conds = append(conds, fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND pr.pull_request_id %[2]s %[4]s))",
+			sortColumn, cmp, arg(key), arg(filter.After.ID)))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = pagination.DefaultLimit
+	}
+
+	listQuery := fmt.Sprintf(`
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name
+		FROM pull_requests pr
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		%s
+		ORDER BY %s %s, pr.pull_request_id %s
+		LIMIT %s
+	`, where, sortColumn, direction, direction, arg(limit+1))
+
+	rows, err := r.db.QueryContext(ctx, listQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list pull requests error: %v", err)
+	}
+	defer rows.Close()
+
+	prs := make([]*model.PullRequest, 0)
+	byID := make(map[string]*model.PullRequest)
+	for rows.Next() {
+		var (
+			pr       model.PullRequest
+			statusID int
+			teamName sql.NullString
+		)
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &statusID, &pr.CreatedAt, &pr.MergedAt, &teamName); err != nil {
+			return nil, fmt.Errorf("scan pull request error: %v", err)
+		}
+		pr.Status = idToStatus(statusID)
+		pr.TeamName = teamName.String
+		pr.AssignedReviewers = make([]string, 0)
+		prs = append(prs, &pr)
+		byID[pr.PullRequestID] = &pr
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list pull requests error: %v", err)
+	}
+
+	page := &model.PullRequestPage{PullRequests: prs}
+	if len(prs) > limit {
+		page.PullRequests = prs[:limit]
+		last := prs[limit-1]
+		page.Next = &model.Cursor{Key: last.PullRequestName, ID: last.PullRequestID}
+		if sortColumn == "pr.createdAt" {
+			page.Next.Key = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	if len(page.PullRequests) == 0 {
+		return page, nil
+	}
+
+	ids := make([]string, 0, len(page.PullRequests))
+	for _, pr := range page.PullRequests {
+		ids = append(ids, pr.PullRequestID)
+	}
+	getReviewersQuery := `
+		SELECT pull_request_id, reviewer_user_id
+		FROM pull_request_reviewers
+		WHERE pull_request_id = ANY($1)
+		ORDER BY assigned_at, reviewer_user_id
 	`
-	var newReviewer string
-	err = r.db.
-		QueryRowContext(ctx, getNewReviewerQuery, teamName, author, oldReviewerID).
-		Scan(&newReviewer)
+	reviewerRows, err := r.db.QueryContext(ctx, getReviewersQuery, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("get reviewers error: %v", err)
+	}
+	defer reviewerRows.Close()
 
+	for reviewerRows.Next() {
+		var prID, reviewerID string
+		if err := reviewerRows.Scan(&prID, &reviewerID); err != nil {
+			return nil, fmt.Errorf("get reviewers error: %v", err)
+		}
+		if pr, ok := byID[prID]; ok {
+			pr.AssignedReviewers = append(pr.AssignedReviewers, reviewerID)
+		}
+	}
+	if err := reviewerRows.Err(); err != nil {
+		return nil, fmt.Errorf("get reviewers error: %v", err)
+	}
+	return page, nil
+}
+
+// AddReviewer assigns an extra reviewer to an open pull request. The reviewer
+// must be an active user other than the author.
+func (r *repository) AddReviewer(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	author, err := lockOpenPullRequest(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	getUserQuery := `
+		SELECT is_active
+		FROM users
+		WHERE user_id = $1
+	`
+	var isActive bool
+	err = tx.QueryRowContext(ctx, getUserQuery, reviewerID).Scan(&isActive)
 	if err == sql.ErrNoRows {
-		newReviewer = oldReviewerID
-	} else if err != nil {
-		return nil, "", fmt.Errorf("get new reviewer error: %v", err)
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reviewer error: %v", err)
+	}
+	if !isActive || reviewerID == author {
+		return nil, model.ErrInvalidReviewer
 	}
 
-	updateReviewerQuery := `
-		UPDATE pr_reviewers
-		SET reviewer_id = $1
-        WHERE pull_request_id = $2 AND reviewer_id = $3
+	addReviewerQuery := `
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, addReviewerQuery, prID, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("insert reviewer failed: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return nil, model.ErrAlreadyAssigned
+	}
+
+	if err := recordHistory(ctx, tx, prID, reviewerID, "added", ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit error: %v", err)
+	}
+	return getPullRequest(ctx, r.db, prID)
+}
+
+// RemoveReviewer drops a reviewer from an open pull request without a
+// replacement.
+func (r *repository) RemoveReviewer(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockOpenPullRequest(ctx, tx, prID); err != nil {
+		return nil, err
+	}
+
+	removeReviewerQuery := `
+		DELETE FROM pull_request_reviewers
+		WHERE pull_request_id = $1 AND reviewer_user_id = $2
+	`
+	result, err := tx.ExecContext(ctx, removeReviewerQuery, prID, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("remove reviewer failed: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return nil, model.ErrNotAssigned
+	}
+
+	if err := recordHistory(ctx, tx, prID, reviewerID, "removed", ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit error: %v", err)
+	}
+	return getPullRequest(ctx, r.db, prID)
+}
+
+// SubmitReview records that the reviewer has reviewed the open pull request,
+// which stops the SLA clock of their seat.
+func (r *repository) SubmitReview(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockOpenPullRequest(ctx, tx, prID); err != nil {
+		return nil, err
+	}
+
+	submitReviewQuery := `
+		UPDATE pull_request_reviewers
+		SET reviewed_at = COALESCE(reviewed_at, CURRENT_TIMESTAMP)
+		WHERE pull_request_id = $1 AND reviewer_user_id = $2
+	`
+	result, err := tx.ExecContext(ctx, submitReviewQuery, prID, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("submit review error: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected error: %v", err)
+	}
+	if rowsAffected == 0 {
+		return nil, model.ErrNotAssigned
+	}
+
+	pr, err := getPullRequest(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return pr, nil
+}
+
+// PendingReviews lists the seats the SLA worker watches: those on OPEN pull
+// requests of teams with an SLA that are neither reviewed nor overdue yet.
+func (r *repository) PendingReviews(ctx context.Context) ([]*model.PendingReview, error) {
+	query := `
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			prr.reviewer_user_id,
+			prr.assigned_at,
+			t.team_name,
+			t.review_sla_minutes,
+			t.sla_auto_reassign,
+			u.timezone,
+			COALESCE(u.work_start, ''),
+			COALESCE(u.work_end, ''),
+			ARRAY(SELECT to_char(h.holiday, 'YYYY-MM-DD') FROM team_holidays h WHERE h.team_id = t.team_id)
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr
+			ON pr.pull_request_id = prr.pull_request_id
+		JOIN teams t
+			ON t.team_id = pr.team_id
+		JOIN users u
+			ON u.user_id = prr.reviewer_user_id
+		WHERE pr.status_id = $1
+			AND t.review_sla_minutes IS NOT NULL
+			AND prr.reviewed_at IS NULL
+			AND prr.overdue_at IS NULL
+		ORDER BY prr.assigned_at, pr.pull_request_id, prr.reviewer_user_id
 	`
-	if newReviewer != oldReviewerID {
-		_, err = r.db.
-			ExecContext(ctx, updateReviewerQuery, newReviewer, prID, oldReviewerID)
+	rows, err := r.db.QueryContext(ctx, query, statusToID("OPEN"))
+	if err != nil {
+		return nil, fmt.Errorf("select pending reviews error: %v", err)
+	}
+	defer rows.Close()
 
+	pending := make([]*model.PendingReview, 0)
+	for rows.Next() {
+		p := &model.PendingReview{}
+		err := rows.Scan(
+			&p.PullRequestID,
+			&p.PullRequestName,
+			&p.ReviewerID,
+			&p.AssignedAt,
+			&p.SLA.TeamName,
+			&p.SLA.Minutes,
+			&p.SLA.AutoReassign,
+			&p.Schedule.Timezone,
+			&p.Schedule.WorkStart,
+			&p.Schedule.WorkEnd,
+			pq.Array(&p.Holidays),
+		)
 		if err != nil {
-			return nil, "", fmt.Errorf("update reviewer error: %v", err)
+			return nil, fmt.Errorf("scan pending review error: %v", err)
 		}
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate pending reviews error: %v", err)
+	}
+	return pending, nil
+}
 
-		for i := range reviewers {
-			if reviewers[i] == oldReviewerID {
-				reviewers[i] = newReviewer
-				break
-			}
+// MarkOverdue flags the seat as overdue. It reports false when the seat is
+// gone, reviewed or already flagged, so that each miss is handled once even
+// with several workers running.
+func (r *repository) MarkOverdue(ctx context.Context, prID string, reviewerID string, at time.Time) (bool, error) {
+	markOverdueQuery := `
+		UPDATE pull_request_reviewers
+		SET overdue_at = $1
+		WHERE pull_request_id = $2
+			AND reviewer_user_id = $3
+			AND reviewed_at IS NULL
+			AND overdue_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, markOverdueQuery, at, prID, reviewerID)
+	if err != nil {
+		return false, fmt.Errorf("mark overdue error: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected error: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListOverdue lists the overdue seats on OPEN pull requests, of one team when
+// teamName is set, the longest overdue first.
+func (r *repository) ListOverdue(ctx context.Context, teamName string) ([]*model.OverdueReview, error) {
+	query := `
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			prr.reviewer_user_id,
+			COALESCE(t.team_name, ''),
+			prr.assigned_at,
+			prr.overdue_at
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr
+			ON pr.pull_request_id = prr.pull_request_id
+		LEFT JOIN teams t
+			ON t.team_id = pr.team_id
+		WHERE pr.status_id = $1
+			AND prr.overdue_at IS NOT NULL
+			AND prr.reviewed_at IS NULL
+			AND ($2::text = '' OR t.team_name = $2)
+		ORDER BY prr.overdue_at, pr.pull_request_id, prr.reviewer_user_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, statusToID("OPEN"), teamName)
+	if err != nil {
+		return nil, fmt.Errorf("select overdue reviews error: %v", err)
+	}
+	defer rows.Close()
+
+	overdue := make([]*model.OverdueReview, 0)
+	for rows.Next() {
+		o := &model.OverdueReview{}
+		err := rows.Scan(
+			&o.PullRequestID,
+			&o.PullRequestName,
+			&o.ReviewerID,
+			&o.TeamName,
+			&o.AssignedAt,
+			&o.OverdueAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan overdue review error: %v", err)
 		}
+		overdue = append(overdue, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate overdue reviews error: %v", err)
 	}
+	return overdue, nil
+}
 
-	pr := &model.PullRequest{
+// lockOpenPullRequest locks the pull request row for the rest of the
+// transaction and returns its author. Merged pull requests are rejected.
+func lockOpenPullRequest(ctx context.Context, tx *sql.Tx, prID string) (string, error) {
+	lockPrQuery := `
+		SELECT author_id, status_id
+		FROM pull_requests
+		WHERE pull_request_id = $1
+		FOR UPDATE
+	`
+	var (
+		author   string
+		statusID int
+	)
+	err := tx.QueryRowContext(ctx, lockPrQuery, prID).Scan(&author, &statusID)
+	if err == sql.ErrNoRows {
+		return "", model.ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("select pr error: %v", err)
+	}
+	if idToStatus(statusID) == "MERGED" {
+		return "", model.ErrPrMerged
+	}
+	return author, nil
+}
+
+// recordHistory stores a manual change of the pull request's reviewers.
+// replacedID is the reviewer a reassigned one took over from.
+func recordHistory(ctx context.Context, tx *sql.Tx, prID, reviewerID, action, replacedID string) error {
+	addHistoryQuery := `
+		INSERT INTO reviewer_history (pull_request_id, reviewer_user_id, action, replaced_user_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+	`
+	if _, err := tx.ExecContext(ctx, addHistoryQuery, prID, reviewerID, action, replacedID); err != nil {
+		return fmt.Errorf("record history error: %v", err)
+	}
+	return nil
+}
+
+// getPullRequest loads a pull request with its reviewers, the earliest
+// assigned first.
+func getPullRequest(ctx context.Context, q querier, prID string) (*model.PullRequest, error) {
+	var (
+		name, author        string
+		statusID            int
+		teamName            sql.NullString
+		createdAt, mergedAt *time.Time
+	)
+	getPrQuery := `
+		SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name
+		FROM pull_requests pr
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		WHERE pr.pull_request_id = $1
+	`
+	err := q.
+		QueryRowContext(ctx, getPrQuery, prID).
+		Scan(&name, &author, &statusID, &createdAt, &mergedAt, &teamName)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("select pr error: %v", err)
+	}
+
+	getReviewerIdQuery := `
+		SELECT reviewer_user_id
+		FROM pull_request_reviewers
+		WHERE pull_request_id = $1
+		ORDER BY assigned_at, reviewer_user_id
+	`
+	rows, err := q.QueryContext(ctx, getReviewerIdQuery, prID)
+	if err != nil {
+		return nil, fmt.Errorf("get reviewers error: %v", err)
+	}
+	defer rows.Close()
+
+	reviewers := make([]string, 0)
+	for rows.Next() {
+		var rid string
+		if err := rows.Scan(&rid); err != nil {
+			return nil, err
+		}
+		reviewers = append(reviewers, rid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get reviewers error: %v", err)
+	}
+
+	return &model.PullRequest{
 		PullRequestShort: model.PullRequestShort{
 			PullRequestID:   prID,
 			PullRequestName: name,
 			AuthorID:        author,
 			Status:          idToStatus(statusID),
 		},
+		TeamName:          teamName.String,
 		AssignedReviewers: reviewers,
 		CreatedAt:         createdAt,
 		MergedAt:          mergedAt,
+	}, nil
+}
+
+// replacement picks the reviewer taking over a seat among the members of
+// teamID, skipping the current reviewers. Candidates are ranked like on
+// creation, so members at work right now win over those off work. A
+// non-empty nominee is the only candidate considered. sql.ErrNoRows is
+// reported when nobody fits.
+func (r *repository) replacement(ctx context.Context, q querier, teamID any, author string, reviewers []string, nominee string, needSenior bool) (string, error) {
+	getNewReviewerQuery := `
+        SELECT u.user_id, rp.kind IS NOT NULL, u.timezone, COALESCE(u.work_start, ''), COALESCE(u.work_end, ''),
+            ARRAY(SELECT to_char(h.holiday, 'YYYY-MM-DD') FROM team_holidays h WHERE h.team_id = $1)
+        FROM team_members tm
+        JOIN users u ON u.user_id = tm.user_id
+        JOIN reviewer_load rl ON rl.user_id = u.user_id
+        LEFT JOIN reviewer_preferences rp ON rp.author_id = $2 AND rp.reviewer_id = u.user_id
+        WHERE tm.team_id = $1 AND tm.is_active = TRUE AND u.is_active = TRUE
+            AND u.user_id <> $2 AND u.user_id <> ALL($3)
+            AND ($6::text = '' OR u.user_id = $6)
+            AND (rl.max_open_reviews IS NULL OR rl.open_reviews < rl.max_open_reviews)
+            AND (NOT $4 OR u.level = 'senior')
+            AND rp.kind IS DISTINCT FROM 'blocked'
+        ORDER BY
+            rp.kind IS NOT NULL DESC,
+            (
+                SELECT COUNT(*)
+                FROM pull_request_reviewers h
+                WHERE h.reviewer_user_id = u.user_id AND h.pull_request_id IN (
+                    SELECT pull_request_id
+                    FROM pull_requests
+                    WHERE author_id = $2
+                    ORDER BY createdAt DESC
+                    LIMIT $5
+                )
+            ),
+            random()
+	`
+	rows, err := q.
+		QueryContext(ctx, getNewReviewerQuery, teamID, author, pq.Array(reviewers), needSenior, r.selection.Window(), nominee)
+	if err != nil {
+		return "", err
 	}
+	defer rows.Close()
 
-	return pr, newReviewer, nil
+	candidates := make([]selection.Candidate, 0)
+	for rows.Next() {
+		var c selection.Candidate
+		err := rows.Scan(&c.UserID, &c.Preferred,
+			&c.Schedule.Timezone, &c.Schedule.WorkStart, &c.Schedule.WorkEnd, pq.Array(&c.Holidays))
+		if err != nil {
+			return "", err
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	ranked := selection.Rank(candidates, nil, time.Now())
+	if len(ranked) == 0 {
+		return "", sql.ErrNoRows
+	}
+	return ranked[0].UserID, nil
+}
+
+type fallbackTeam struct {
+	id   int64
+	name string
+}
+
+// fallbackTeams lists the team's fallback chain in order.
+func fallbackTeams(ctx context.Context, q querier, teamID int64) ([]fallbackTeam, error) {
+	getFallbacksQuery := `
+		SELECT f.team_id, f.team_name
+		FROM team_fallbacks tf
+		JOIN teams f ON f.team_id = tf.fallback_team_id
+		WHERE tf.team_id = $1
+		ORDER BY tf.position
+	`
+	rows, err := q.QueryContext(ctx, getFallbacksQuery, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := make([]fallbackTeam, 0)
+	for rows.Next() {
+		var t fallbackTeam
+		if err := rows.Scan(&t.id, &t.name); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// fallbackCandidates lists the reviewer pools of the team's fallback teams in
+// chain order, with the same filters as selectReviewers.
+func fallbackCandidates(ctx context.Context, tx *sql.Tx, teamID int64, authorID string) ([]selection.Fallback, error) {
+	teams, err := fallbackTeams(ctx, tx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	fallbacks := make([]selection.Fallback, 0, len(teams))
+	for _, t := range teams {
+		candidates, err := selectReviewers(ctx, tx, t.id, authorID)
+		if err != nil {
+			return nil, err
+		}
+		fallbacks = append(fallbacks, selection.Fallback{Team: t.name, Candidates: candidates})
+	}
+	return fallbacks, nil
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// primaryTeam returns the team the author joined first. The team is NULL for
+// authors without memberships; unknown and deleted authors are reported as
+// not found.
+func primaryTeam(ctx context.Context, q querier, authorID string) (sql.NullInt64, sql.NullString, error) {
+	getCommandQuery := `
+		SELECT t.team_id, t.team_name
+		FROM users u
+		LEFT JOIN team_members tm ON tm.user_id = u.user_id
+		LEFT JOIN teams t ON t.team_id = tm.team_id
+		WHERE u.user_id = $1 AND u.deleted_at IS NULL
+		ORDER BY tm.joined_at, t.team_name
+		LIMIT 1
+	`
+
+	var (
+		teamID   sql.NullInt64
+		teamName sql.NullString
+	)
+	err := q.
+		QueryRowContext(ctx, getCommandQuery, authorID).
+		Scan(&teamID, &teamName)
+
+	if err == sql.ErrNoRows {
+		return teamID, teamName, model.ErrNotFound
+	}
+	if err != nil {
+		return teamID, teamName, fmt.Errorf("get author team error: %v", err)
+	}
+	return teamID, teamName, nil
+}
+
+// recentReviewers counts how many of the author's latest pull requests, up to
+// window, each user reviewed.
+func recentReviewers(ctx context.Context, tx *sql.Tx, authorID string, window int) (map[string]int, error) {
+	getHistoryQuery := `
+		SELECT prr.reviewer_user_id, COUNT(*)
+		FROM pull_request_reviewers prr
+		WHERE prr.pull_request_id IN (
+			SELECT pull_request_id
+			FROM pull_requests
+			WHERE author_id = $1
+			ORDER BY createdAt DESC
+			LIMIT $2
+		)
+		GROUP BY prr.reviewer_user_id
+	`
+	rows, err := tx.QueryContext(ctx, getHistoryQuery, authorID, window)
+	if err != nil {
+		return nil, fmt.Errorf("get review history error: %v", err)
+	}
+	defer rows.Close()
+
+	history := make(map[string]int)
+	for rows.Next() {
+		var (
+			reviewerID string
+			count      int
+		)
+		if err := rows.Scan(&reviewerID, &count); err != nil {
+			return nil, fmt.Errorf("get review history error: %v", err)
+		}
+		history[reviewerID] = count
+	}
+	return history, rows.Err()
+}
+
+// seniorRule returns how many senior reviewers the team requires.
+func seniorRule(ctx context.Context, q querier, teamID int64) (int, error) {
+	getRuleQuery := `
+		SELECT min_senior_reviewers
+		FROM teams
+		WHERE team_id = $1
+	`
+	var minSeniors int
+	if err := q.QueryRowContext(ctx, getRuleQuery, teamID).Scan(&minSeniors); err != nil {
+		return 0, fmt.Errorf("get seniority rule error: %v", err)
+	}
+	return minSeniors, nil
+}
+
+// replacementMustBeSenior tells whether dropping oldReviewerID would break
+// the team's seniority rule, capped at the number of assigned reviewers.
+func replacementMustBeSenior(ctx context.Context, q querier, teamID int64, reviewers []string, oldReviewerID string) (bool, error) {
+	minSeniors, err := seniorRule(ctx, q, teamID)
+	if err != nil || minSeniors == 0 {
+		return false, err
+	}
+
+	remaining := make([]string, 0, len(reviewers))
+	for _, id := range reviewers {
+		if id != oldReviewerID {
+			remaining = append(remaining, id)
+		}
+	}
+
+	countSeniorsQuery := `
+		SELECT COUNT(*)
+		FROM users
+		WHERE user_id = ANY($1) AND level = 'senior'
+	`
+	var seniors int
+	if err := q.QueryRowContext(ctx, countSeniorsQuery, pq.Array(remaining)).Scan(&seniors); err != nil {
+		return false, fmt.Errorf("count seniors error: %v", err)
+	}
+	return seniors < min(minSeniors, len(reviewers)), nil
+}
+
+// teamByName resolves the explicit target team of a pull request.
+func teamByName(ctx context.Context, q querier, teamName string) (sql.NullInt64, sql.NullString, error) {
+	getTeamQuery := `
+		SELECT team_id, team_name
+		FROM teams
+		WHERE team_name = $1
+	`
+
+	var (
+		teamID sql.NullInt64
+		name   sql.NullString
+	)
+	err := q.
+		QueryRowContext(ctx, getTeamQuery, teamName).
+		Scan(&teamID, &name)
+
+	if err == sql.ErrNoRows {
+		return teamID, name, model.ErrNotFound
+	}
+	if err != nil {
+		return teamID, name, fmt.Errorf("get team error: %v", err)
+	}
+	return teamID, name, nil
+}
+
+// ownerCandidates matches the changed files against the team's ownership
+// rules and lists the active owners of every matched rule, author, users at
+// capacity and blocked reviewers excluded.
+func ownerCandidates(ctx context.Context, tx *sql.Tx, teamID int64, authorID string, files []string) ([]selection.Owners, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	getRulesQuery := `
+		SELECT position, pattern, owners
+		FROM team_ownership_rules
+		WHERE team_id = $1
+		ORDER BY position
+	`
+	rows, err := tx.QueryContext(ctx, getRulesQuery, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]*model.OwnershipRule, 0)
+	for rows.Next() {
+		var (
+			rule   model.OwnershipRule
+			owners string
+		)
+		if err := rows.Scan(&rule.Line, &rule.Pattern, &owners); err != nil {
+			return nil, err
+		}
+		rule.Owners = strings.Fields(owners)
+		rules = append(rules, &rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	getOwnersQuery := `
+		SELECT u.user_id, ARRAY(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id), COALESCE(u.level = 'senior', FALSE),
+			rp.kind IS NOT NULL, u.timezone, COALESCE(u.work_start, ''), COALESCE(u.work_end, ''),
+			ARRAY(SELECT to_char(h.holiday, 'YYYY-MM-DD') FROM team_holidays h WHERE h.team_id = $4)
+		FROM users u
+		JOIN reviewer_load rl ON rl.user_id = u.user_id
+		LEFT JOIN reviewer_preferences rp ON rp.author_id = $1 AND rp.reviewer_id = u.user_id
+		LEFT JOIN team_members tm ON tm.user_id = u.user_id AND tm.is_active = TRUE
+		LEFT JOIN teams t ON t.team_id = tm.team_id
+		WHERE u.is_active = TRUE AND u.user_id <> $1
+			AND (u.user_id = ANY($2) OR t.team_name = ANY($3))
+			AND (rl.max_open_reviews IS NULL OR rl.open_reviews < rl.max_open_reviews)
+			AND rp.kind IS DISTINCT FROM 'blocked'
+		GROUP BY u.user_id, rp.kind
+		ORDER BY random()
+	`
+	owners := make([]selection.Owners, 0)
+	for _, rule := range ownership.MatchFiles(rules, files) {
+		userIDs, teamNames := ownership.SplitOwners(rule.Owners)
+		candidates, err := queryCandidates(ctx, tx, getOwnersQuery, authorID, pq.Array(userIDs), pq.Array(teamNames), teamID)
+		if err != nil {
+			return nil, err
+		}
+		owners = append(owners, selection.Owners{Rule: rule, Candidates: candidates})
+	}
+	return owners, nil
+}
+
+// queryCandidates runs a query returning user ids with their skill arrays,
+// seniority, whether the author prefers them, their working hours and the
+// holidays of the team they are picked for.
+func queryCandidates(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]selection.Candidate, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]selection.Candidate, 0)
+	for rows.Next() {
+		var c selection.Candidate
+		err := rows.Scan(&c.UserID, pq.Array(&c.Skills), &c.Senior, &c.Preferred,
+			&c.Schedule.Timezone, &c.Schedule.WorkStart, &c.Schedule.WorkEnd, pq.Array(&c.Holidays))
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// selectReviewers lists, in random order, the members that are active both
+// in the team and globally and have spare review capacity, excluding the author
+// and the reviewers they blocked.
+func selectReviewers(ctx context.Context, tx *sql.Tx, teamID int64, authorID string) ([]selection.Candidate, error) {
+	getReviewiersQuery := `
+		SELECT u.user_id, ARRAY(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id), COALESCE(u.level = 'senior', FALSE),
+			rp.kind IS NOT NULL, u.timezone, COALESCE(u.work_start, ''), COALESCE(u.work_end, ''),
+			ARRAY(SELECT to_char(h.holiday, 'YYYY-MM-DD') FROM team_holidays h WHERE h.team_id = $1)
+		FROM team_members tm
+		JOIN users u ON u.user_id = tm.user_id
+		JOIN reviewer_load rl ON rl.user_id = u.user_id
+		LEFT JOIN reviewer_preferences rp ON rp.author_id = $2 AND rp.reviewer_id = u.user_id
+		WHERE tm.team_id = $1 AND tm.is_active = TRUE AND u.is_active = TRUE AND u.user_id <> $2
+			AND (rl.max_open_reviews IS NULL OR rl.open_reviews < rl.max_open_reviews)
+			AND rp.kind IS DISTINCT FROM 'blocked'
+		ORDER BY random()
+	`
+
+	return queryCandidates(ctx, tx, getReviewiersQuery, teamID, authorID)
 }
 
 func statusToID(status string) int {
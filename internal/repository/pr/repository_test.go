@@ -25,31 +25,40 @@ func TestCreateSuccess(t *testing.T) {
 		AuthorID:        "u1",
 	}
 
+	mock.ExpectBegin()
+
 	mock.
-		ExpectQuery("SELECT team_name FROM users WHERE user_id").
+		ExpectQuery("SELECT t.team_id, t.team_name FROM users u LEFT JOIN team_members tm").
 		WithArgs("u1").
-		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}).AddRow(1, "backend"))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, ARRAY\\(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id\\), COALESCE\\(u.level = 'senior', FALSE\\), rp.kind IS NOT NULL, u.timezone, .+ FROM team_members tm JOIN users u").
+		WithArgs(1, "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "skills", "senior", "preferred", "timezone", "work_start", "work_end", "holidays"}).AddRow("u2", "{}", false, false, "UTC", "", "", "{}").AddRow("u3", "{}", false, false, "UTC", "", "", "{}"))
 
 	mock.
-		ExpectQuery("SELECT user_id FROM users WHERE team_name = \\$1 AND is_active = TRUE AND user_id <>").
-		WithArgs("backend", "u1").
-		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u2").AddRow("u3"))
+		ExpectQuery("SELECT min_senior_reviewers FROM teams").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"min_senior_reviewers"}).AddRow(0))
 
 	mock.
 		ExpectExec("INSERT INTO pull_requests").
-		WithArgs("pr-1001", "Add search", "u1", statusToID("OPEN"), sqlmock.AnyArg()).
+		WithArgs("pr-1001", "Add search", "u1", statusToID("OPEN"), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.
-		ExpectExec("INSERT INTO pr_reviewers").
+		ExpectExec("INSERT INTO pull_request_reviewers").
 		WithArgs("pr-1001", "u2").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.
-		ExpectExec("INSERT INTO pr_reviewers").
+		ExpectExec("INSERT INTO pull_request_reviewers").
 		WithArgs("pr-1001", "u3").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
+	mock.ExpectCommit()
+
 	pr, err := repo.Create(context.Background(), req)
 
 	if err != nil {
@@ -62,6 +71,9 @@ func TestCreateSuccess(t *testing.T) {
 	if len(pr.AssignedReviewers) != 2 || pr.AssignedReviewers[0] != "u2" || pr.AssignedReviewers[1] != "u3" {
 		t.Errorf("wrong reviewers: %v", pr.AssignedReviewers)
 	}
+	if pr.TeamName != "backend" {
+		t.Errorf("expected team backend, got %q", pr.TeamName)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
@@ -82,18 +94,212 @@ func TestCreateNotFound(t *testing.T) {
 		AuthorID:        "oops",
 	}
 
+	mock.ExpectBegin()
+
 	mock.
-		ExpectQuery("SELECT team_name FROM users WHERE user_id").
+		ExpectQuery("SELECT t.team_id, t.team_name FROM users u LEFT JOIN team_members tm").
 		WithArgs("oops").
-		WillReturnRows(sqlmock.NewRows([]string{"team_name"}))
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}))
+
+	mock.ExpectRollback()
+
+	pr, err := repo.Create(context.Background(), req)
+	if pr != nil {
+		t.Errorf("must be nil PR")
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateWithTargetTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	req := model.PullRequestPayload{
+		PullRequestID:   "pr-1002",
+		PullRequestName: "Fix layout",
+		AuthorID:        "u1",
+		TeamName:        "frontend",
+	}
+
+	mock.ExpectBegin()
+
+	mock.
+		ExpectQuery("SELECT t.team_id, t.team_name FROM users u LEFT JOIN team_members tm").
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}).AddRow(1, "backend"))
+
+	mock.
+		ExpectQuery("SELECT team_id, team_name FROM teams WHERE team_name = \\$1").
+		WithArgs("frontend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}).AddRow(2, "frontend"))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, ARRAY\\(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id\\), COALESCE\\(u.level = 'senior', FALSE\\), rp.kind IS NOT NULL, u.timezone, .+ FROM team_members tm JOIN users u").
+		WithArgs(2, "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "skills", "senior", "preferred", "timezone", "work_start", "work_end", "holidays"}).AddRow("u5", "{}", false, false, "UTC", "", "", "{}"))
+
+	mock.
+		ExpectQuery("SELECT min_senior_reviewers FROM teams").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"min_senior_reviewers"}).AddRow(0))
+
+	// one reviewer leaves a seat empty, so the fallback chain is looked up
+	mock.
+		ExpectQuery("SELECT f.team_id, f.team_name FROM team_fallbacks tf").
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}))
+
+	mock.
+		ExpectExec("INSERT INTO pull_requests").
+		WithArgs("pr-1002", "Fix layout", "u1", statusToID("OPEN"), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.
+		ExpectExec("INSERT INTO pull_request_reviewers").
+		WithArgs("pr-1002", "u5").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectCommit()
 
 	pr, err := repo.Create(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.TeamName != "frontend" || len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u5" {
+		t.Errorf("unexpected pr: %+v", pr)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateTargetTeamNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+
+	mock.
+		ExpectQuery("SELECT t.team_id, t.team_name FROM users u LEFT JOIN team_members tm").
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}).AddRow(1, "backend"))
+
+	mock.
+		ExpectQuery("SELECT team_id, team_name FROM teams WHERE team_name").
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}))
+
+	mock.ExpectRollback()
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1002",
+		PullRequestName: "Fix layout",
+		AuthorID:        "u1",
+		TeamName:        "unknown",
+	})
 	if pr != nil {
 		t.Errorf("must be nil PR")
 	}
 	if !errors.Is(err, model.ErrNotFound) {
 		t.Errorf("expected ErrNotFound, got: %v", err)
 	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateWithOwners(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+
+	mock.
+		ExpectQuery("SELECT t.team_id, t.team_name FROM users u LEFT JOIN team_members tm").
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}).AddRow(1, "backend"))
+
+	mock.
+		ExpectQuery("SELECT position, pattern, owners FROM team_ownership_rules").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"position", "pattern", "owners"}).
+			AddRow(1, "*", "@u2").
+			AddRow(3, "internal/db/", "@u4 @acme/dba"))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, ARRAY\\(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id\\), COALESCE\\(u.level = 'senior', FALSE\\), rp.kind IS NOT NULL, u.timezone, .+ FROM users u JOIN reviewer_load rl").
+		WithArgs("u1", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "skills", "senior", "preferred", "timezone", "work_start", "work_end", "holidays"}).AddRow("u4", "{}", false, false, "UTC", "", "", "{}"))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, ARRAY\\(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id\\), COALESCE\\(u.level = 'senior', FALSE\\), rp.kind IS NOT NULL, u.timezone, .+ FROM team_members tm JOIN users u").
+		WithArgs(1, "u1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "skills", "senior", "preferred", "timezone", "work_start", "work_end", "holidays"}).AddRow("u4", "{}", false, false, "UTC", "", "", "{}").AddRow("u2", "{}", false, false, "UTC", "", "", "{}"))
+
+	mock.
+		ExpectQuery("SELECT min_senior_reviewers FROM teams").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"min_senior_reviewers"}).AddRow(0))
+
+	mock.
+		ExpectExec("INSERT INTO pull_requests").
+		WithArgs("pr-1003", "Tune pool", "u1", statusToID("OPEN"), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.
+		ExpectExec("INSERT INTO pull_request_reviewers").
+		WithArgs("pr-1003", "u4").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.
+		ExpectExec("INSERT INTO pull_request_reviewers").
+		WithArgs("pr-1003", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectCommit()
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1003",
+		PullRequestName: "Tune pool",
+		AuthorID:        "u1",
+		ChangedFiles:    []string{"internal/db/pool.go"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pr.ReviewerMatches) != 2 {
+		t.Fatalf("expected 2 reviewers, got %+v", pr.ReviewerMatches)
+	}
+	if m := pr.ReviewerMatches[0]; m.UserID != "u4" || m.MatchedRule == nil || m.MatchedRule.Line != 3 {
+		t.Errorf("unexpected owner match: %+v", m)
+	}
+	if m := pr.ReviewerMatches[1]; m.UserID != "u2" || m.MatchedRule != nil {
+		t.Errorf("unexpected pool match: %+v", m)
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
@@ -112,30 +318,33 @@ func TestMergeOpenToMerged(t *testing.T) {
 	reviewers := []string{"u2", "u3"}
 
 	mock.
-		ExpectQuery("SELECT pull_request_name, author_id, status_id, createdAt, mergedAt FROM pull_requests").
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name FROM pull_requests pr").
 		WithArgs(prID).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt",
-		}).AddRow("Add search", "u1", statusToID("OPEN"), created, nil))
+			"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_name",
+		}).AddRow("Add search", "u1", statusToID("OPEN"), created, nil, "backend"))
 
-	reviewerRows := sqlmock.NewRows([]string{"reviewer_id"}).AddRow(reviewers[0]).AddRow(reviewers[1])
+	reviewerRows := sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow(reviewers[0]).AddRow(reviewers[1])
 	mock.
-		ExpectQuery("SELECT reviewer_id FROM pr_reviewers WHERE pull_request_id").
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id").
 		WithArgs(prID).
 		WillReturnRows(reviewerRows)
 
 	mock.
-		ExpectExec("UPDATE pull_requests SET").
+		ExpectExec("UPDATE pull_requests SET status_id=\\$1, mergedAt=\\$2 WHERE pull_request_id=\\$3 AND status_id <> \\$1").
 		WithArgs(statusToID("MERGED"), sqlmock.AnyArg(), prID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	pr, err := repo.Merge(context.Background(), prID)
+	pr, merged, err := repo.Merge(context.Background(), prID)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 	if pr == nil {
 		t.Fatalf("expected non-nil PR")
 	}
+	if !merged {
+		t.Error("expected the call to report the merge")
+	}
 	if pr.Status != "MERGED" {
 		t.Errorf("expected status MERGED, got %v", pr.Status)
 	}
@@ -158,13 +367,13 @@ func TestMergeNotFound(t *testing.T) {
 	prID := "pr-404"
 
 	mock.
-		ExpectQuery("SELECT pull_request_name, author_id, status_id, createdAt, mergedAt FROM pull_requests").
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name FROM pull_requests pr").
 		WithArgs(prID).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt",
+			"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_name",
 		}))
 
-	pr, err := repo.Merge(context.Background(), prID)
+	pr, _, err := repo.Merge(context.Background(), prID)
 	if pr != nil {
 		t.Errorf("expected nil, got %+v", pr)
 	}
@@ -190,25 +399,28 @@ func TestMergeAlreadyMerged(t *testing.T) {
 	reviewers := []string{"u2", "u3"}
 
 	mock.
-		ExpectQuery("SELECT pull_request_name, author_id, status_id, createdAt, mergedAt FROM pull_requests").
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name FROM pull_requests pr").
 		WithArgs(prID).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt",
-		}).AddRow("Add search", "u1", statusToID("MERGED"), created, merged))
+			"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_name",
+		}).AddRow("Add search", "u1", statusToID("MERGED"), created, merged, "backend"))
 
-	reviewerRows := sqlmock.NewRows([]string{"reviewer_id"}).AddRow(reviewers[0]).AddRow(reviewers[1])
+	reviewerRows := sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow(reviewers[0]).AddRow(reviewers[1])
 	mock.
-		ExpectQuery("SELECT reviewer_id FROM pr_reviewers WHERE pull_request_id").
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id").
 		WithArgs(prID).
 		WillReturnRows(reviewerRows)
 
-	pr, err := repo.Merge(context.Background(), prID)
+	pr, ok, err := repo.Merge(context.Background(), prID)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 	if pr == nil {
 		t.Fatalf("expected non-nil PR, got nil")
 	}
+	if ok {
+		t.Error("expected a repeated merge to change nothing")
+	}
 	if pr.Status != "MERGED" {
 		t.Errorf("wrong status %v", pr.Status)
 	}
@@ -217,6 +429,53 @@ func TestMergeAlreadyMerged(t *testing.T) {
 	}
 }
 
+func TestMergeLostRace(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock db: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	prID := "pr-1001"
+	created := time.Now().Add(-2 * time.Hour)
+	merged := time.Now().Add(-time.Second).UTC()
+
+	mock.
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name FROM pull_requests pr").
+		WithArgs(prID).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_name",
+		}).AddRow("Add search", "u1", statusToID("OPEN"), created, nil, "backend"))
+	mock.
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id").
+		WithArgs(prID).
+		WillReturnRows(sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow("u2"))
+	// another merge updated the row in between
+	mock.
+		ExpectExec("UPDATE pull_requests SET").
+		WithArgs(statusToID("MERGED"), sqlmock.AnyArg(), prID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.
+		ExpectQuery("SELECT mergedAt FROM pull_requests WHERE pull_request_id = \\$1").
+		WithArgs(prID).
+		WillReturnRows(sqlmock.NewRows([]string{"mergedAt"}).AddRow(merged))
+
+	pr, ok, err := repo.Merge(context.Background(), prID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected the losing merge not to report the merge")
+	}
+	if pr.MergedAt == nil || !pr.MergedAt.Equal(merged) {
+		t.Errorf("expected the winning merge time, got %v", pr.MergedAt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestReassignSuccess(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -233,34 +492,40 @@ func TestReassignSuccess(t *testing.T) {
 
 	created := time.Now().Add(-1 * time.Hour)
 
+	mock.ExpectBegin()
 	mock.
-		ExpectQuery("SELECT pull_request_name, author_id, status_id, createdAt, mergedAt FROM pull_requests").
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, pr.team_id, t.team_name FROM pull_requests pr .+ FOR UPDATE OF pr").
 		WithArgs(prID).
 		WillReturnRows(sqlmock.NewRows(
-			[]string{"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt"},
-		).AddRow("Add search", author, statusToID("OPEN"), created, nil))
+			[]string{"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_id", "team_name"},
+		).AddRow("Add search", author, statusToID("OPEN"), created, nil, 1, "backend"))
 
 	mock.
-		ExpectQuery("SELECT reviewer_id FROM pr_reviewers WHERE pull_request_id").
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id").
 		WithArgs(prID).
-		WillReturnRows(sqlmock.NewRows([]string{"reviewer_id"}).AddRow("u3").AddRow(oldReviewer))
+		WillReturnRows(sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow("u3").AddRow(oldReviewer))
 
 	mock.
-		ExpectQuery("SELECT team_name FROM users WHERE user_id").
-		WithArgs(author).
-		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend"))
+		ExpectQuery("SELECT min_senior_reviewers FROM teams").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"min_senior_reviewers"}).AddRow(0))
 
 	mock.
-		ExpectQuery("SELECT user_id FROM users WHERE team_name = \\$1 AND is_active = TRUE AND user_id <>").
-		WithArgs("backend", author, oldReviewer).
-		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(newReviewer))
+		ExpectQuery("SELECT u.user_id, rp.kind IS NOT NULL, .+ FROM team_members tm JOIN users u").
+		WithArgs(1, author, `{"u3","u2"}`, false, 0, "").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "preferred", "timezone", "work_start", "work_end", "holidays"}).AddRow(newReviewer, false, "UTC", "", "", "{}"))
 
 	mock.
-		ExpectExec("UPDATE pr_reviewers SET reviewer_id = \\$1 WHERE pull_request_id").
+		ExpectExec("UPDATE pull_request_reviewers SET reviewer_user_id = \\$1, assigned_at = CURRENT_TIMESTAMP, reviewed_at = NULL, overdue_at = NULL WHERE pull_request_id").
 		WithArgs(newReviewer, prID, oldReviewer).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO reviewer_history").
+		WithArgs(prID, newReviewer, "reassigned", oldReviewer).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	pr, _, err := repo.Reassign(context.Background(), prID, oldReviewer)
+	pr, _, err := repo.Reassign(context.Background(), prID, oldReviewer, "")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -277,3 +542,334 @@ func TestReassignSuccess(t *testing.T) {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
+
+func TestReassignFromFallbackTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	prID := "pr-1001"
+	oldReviewer := "u2"
+	author := "u1"
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, pr.team_id, t.team_name FROM pull_requests pr .+ FOR UPDATE OF pr").
+		WithArgs(prID).
+		WillReturnRows(sqlmock.NewRows(
+			[]string{"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_id", "team_name"},
+		).AddRow("Add search", author, statusToID("OPEN"), time.Now(), nil, 1, "backend"))
+
+	mock.
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id").
+		WithArgs(prID).
+		WillReturnRows(sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow(oldReviewer))
+
+	mock.
+		ExpectQuery("SELECT min_senior_reviewers FROM teams").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"min_senior_reviewers"}).AddRow(0))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, rp.kind IS NOT NULL, .+ FROM team_members tm JOIN users u").
+		WithArgs(1, author, `{"u2"}`, false, 0, "").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "preferred", "timezone", "work_start", "work_end", "holidays"}))
+
+	mock.
+		ExpectQuery("SELECT f.team_id, f.team_name FROM team_fallbacks tf").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}).AddRow(3, "platform"))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, rp.kind IS NOT NULL, .+ FROM team_members tm JOIN users u").
+		WithArgs(3, author, `{"u2"}`, false, 0, "").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "preferred", "timezone", "work_start", "work_end", "holidays"}).AddRow("u7", false, "UTC", "", "", "{}"))
+
+	mock.
+		ExpectExec("UPDATE pull_request_reviewers SET reviewer_user_id = \\$1, assigned_at = CURRENT_TIMESTAMP, reviewed_at = NULL, overdue_at = NULL WHERE pull_request_id").
+		WithArgs("u7", prID, oldReviewer).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO reviewer_history").
+		WithArgs(prID, "u7", "reassigned", oldReviewer).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	pr, newReviewer, err := repo.Reassign(context.Background(), prID, oldReviewer, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if newReviewer != "u7" || len(pr.ReviewerMatches) != 1 || pr.ReviewerMatches[0].FallbackTeam != "platform" {
+		t.Errorf("expected u7 from platform, got %s %+v", newReviewer, pr.ReviewerMatches)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReassignNotAssigned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, pr.team_id, t.team_name FROM pull_requests pr .+ FOR UPDATE OF pr").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows(
+			[]string{"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_id", "team_name"},
+		).AddRow("Add search", "u1", statusToID("OPEN"), time.Now(), nil, 1, "backend"))
+
+	mock.
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow("u3"))
+	mock.ExpectRollback()
+
+	_, _, err = repo.Reassign(context.Background(), "pr-1001", "u2", "")
+	if !errors.Is(err, model.ErrNotAssigned) {
+		t.Errorf("expected ErrNotAssigned, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReassignNoCandidate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, pr.team_id, t.team_name FROM pull_requests pr .+ FOR UPDATE OF pr").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows(
+			[]string{"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_id", "team_name"},
+		).AddRow("Add search", "u1", statusToID("OPEN"), time.Now(), nil, 1, "backend"))
+
+	mock.
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow("u2").AddRow("u3"))
+
+	mock.
+		ExpectQuery("SELECT min_senior_reviewers FROM teams").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"min_senior_reviewers"}).AddRow(0))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, rp.kind IS NOT NULL, .+ FROM team_members tm JOIN users u .* u.user_id <> ALL\\(\\$3\\)").
+		WithArgs(1, "u1", `{"u2","u3"}`, false, 0, "u3").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "preferred", "timezone", "work_start", "work_end", "holidays"}))
+
+	mock.
+		ExpectQuery("SELECT f.team_id, f.team_name FROM team_fallbacks tf").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "team_name"}))
+	mock.ExpectRollback()
+
+	_, _, err = repo.Reassign(context.Background(), "pr-1001", "u2", "u3")
+	if !errors.Is(err, model.ErrNoCandidate) {
+		t.Errorf("expected ErrNoCandidate, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAddReviewerAlreadyAssigned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT author_id, status_id FROM pull_requests WHERE pull_request_id = \\$1 FOR UPDATE").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows([]string{"author_id", "status_id"}).AddRow("u1", statusToID("OPEN")))
+	mock.
+		ExpectQuery("SELECT is_active FROM users WHERE user_id = \\$1").
+		WithArgs("u2").
+		WillReturnRows(sqlmock.NewRows([]string{"is_active"}).AddRow(true))
+	mock.
+		ExpectExec("INSERT INTO pull_request_reviewers .* ON CONFLICT DO NOTHING").
+		WithArgs("pr-1001", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = repo.AddReviewer(context.Background(), "pr-1001", "u2")
+	if !errors.Is(err, model.ErrAlreadyAssigned) {
+		t.Errorf("expected ErrAlreadyAssigned, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRemoveReviewerSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT author_id, status_id FROM pull_requests WHERE pull_request_id = \\$1 FOR UPDATE").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows([]string{"author_id", "status_id"}).AddRow("u1", statusToID("OPEN")))
+	mock.
+		ExpectExec("DELETE FROM pull_request_reviewers WHERE pull_request_id = \\$1 AND reviewer_user_id = \\$2").
+		WithArgs("pr-1001", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO reviewer_history").
+		WithArgs("pr-1001", "u2", "removed", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.
+		ExpectQuery("SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name FROM pull_requests pr").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows(
+			[]string{"pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_name"},
+		).AddRow("Add search", "u1", statusToID("OPEN"), time.Now(), nil, "backend"))
+	mock.
+		ExpectQuery("SELECT reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id = \\$1 ORDER BY assigned_at").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows([]string{"reviewer_user_id"}).AddRow("u3"))
+
+	pr, err := repo.RemoveReviewer(context.Background(), "pr-1001", "u2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u3" {
+		t.Errorf("wrong reviewers after remove: %v", pr.AssignedReviewers)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListWithCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	after := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	first := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectQuery("SELECT pr.pull_request_id, .* FROM pull_requests pr LEFT JOIN teams t ON t.team_id = pr.team_id "+
+			"WHERE pr.status_id = \\$1 AND \\(pr.createdAt < \\$2 OR \\(pr.createdAt = \\$2 AND pr.pull_request_id < \\$3\\)\\) "+
+			"ORDER BY pr.createdAt DESC, pr.pull_request_id DESC LIMIT \\$4").
+		WithArgs(statusToID("OPEN"), after, "pr-3", 2).
+		WillReturnRows(sqlmock.NewRows(
+			[]string{"pull_request_id", "pull_request_name", "author_id", "status_id", "createdAt", "mergedAt", "team_name"},
+		).
+			AddRow("pr-2", "Fix layout", "u5", 1, first, nil, "frontend").
+			AddRow("pr-1", "Add search", "u1", 1, first, nil, "backend"))
+
+	mock.
+		ExpectQuery("SELECT pull_request_id, reviewer_user_id FROM pull_request_reviewers WHERE pull_request_id = ANY\\(\\$1\\)").
+		WithArgs(`{"pr-2"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "reviewer_user_id"}).AddRow("pr-2", "u2"))
+
+	page, err := repo.List(context.Background(), model.PullRequestFilter{
+		Status: "OPEN",
+		Sort:   model.PullRequestSortCreated,
+		Desc:   true,
+		Limit:  1,
+		After:  &model.Cursor{Key: after.Format(time.RFC3339Nano), ID: "pr-3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.PullRequests) != 1 || page.PullRequests[0].PullRequestID != "pr-2" || len(page.PullRequests[0].AssignedReviewers) != 1 {
+		t.Errorf("unexpected page: %+v", page.PullRequests)
+	}
+	if page.Next == nil || page.Next.ID != "pr-2" || page.Next.Key != first.Format(time.RFC3339Nano) {
+		t.Errorf("unexpected next cursor: %+v", page.Next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSubmitReviewNotAssigned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT author_id, status_id FROM pull_requests WHERE pull_request_id = \\$1 FOR UPDATE").
+		WithArgs("pr-1001").
+		WillReturnRows(sqlmock.NewRows([]string{"author_id", "status_id"}).AddRow("u1", statusToID("OPEN")))
+	mock.
+		ExpectExec("UPDATE pull_request_reviewers SET reviewed_at = COALESCE\\(reviewed_at, CURRENT_TIMESTAMP\\)").
+		WithArgs("pr-1001", "u9").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = repo.SubmitReview(context.Background(), "pr-1001", "u9")
+	if !errors.Is(err, model.ErrNotAssigned) {
+		t.Errorf("expected ErrNotAssigned, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMarkOverdueOnce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	now := time.Now()
+
+	mock.
+		ExpectExec("UPDATE pull_request_reviewers SET overdue_at = \\$1 WHERE .+ AND reviewed_at IS NULL AND overdue_at IS NULL").
+		WithArgs(now, "pr-1001", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	marked, err := repo.MarkOverdue(context.Background(), "pr-1001", "u2", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marked {
+		t.Error("expected a flagged seat to be left alone")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"time"
 )
 
 //go:generate mockgen -source=repository.go -destination=../mocks/mock_repository.go -package=mocks
@@ -10,15 +11,61 @@ import (
 type TeamRepository interface {
 	Add(ctx context.Context, team *model.Team) error
 	Get(ctx context.Context, teamName string) (*model.Team, error)
+	List(ctx context.Context) ([]*model.Team, error)
+	Update(ctx context.Context, update *model.TeamUpdate) ([]*model.ReleasedReview, error)
+	Delete(ctx context.Context, teamName string) ([]*model.ReleasedReview, error)
+	SetOwnershipRules(ctx context.Context, teamName string, rules []*model.OwnershipRule) error
+	GetOwnershipRules(ctx context.Context, teamName string) ([]*model.OwnershipRule, error)
+	SetFallbackTeams(ctx context.Context, teamName string, fallbacks []string) error
+	GetFallbackTeams(ctx context.Context, teamName string) ([]string, error)
+	SetReviewSLA(ctx context.Context, sla *model.ReviewSLA) error
+	GetReviewSLA(ctx context.Context, teamName string) (*model.ReviewSLA, error)
+	SetHolidays(ctx context.Context, holidays *model.TeamHolidays) error
+	GetHolidays(ctx context.Context, teamName string) (*model.TeamHolidays, error)
+	SetChat(ctx context.Context, chat *model.TeamChat) error
+	GetChat(ctx context.Context, teamName string) (*model.TeamChat, error)
+	Import(ctx context.Context, teams []*model.RosterTeam, dryRun bool) (*model.RosterDiff, []*model.ReleasedReview, error)
 }
 
 type UserRepository interface {
+	Create(ctx context.Context, user *model.User) (*model.User, error)
+	Get(ctx context.Context, userID string) (*model.User, error)
+	List(ctx context.Context, filter model.UserFilter) ([]*model.User, error)
+	Delete(ctx context.Context, userID string) (*model.UserDeletion, error)
 	SetIsActive(ctx context.Context, userID string, isActive bool) (*model.User, error)
-	GetReview(ctx context.Context, userID string) ([]*model.PullRequestShort, error)
+	SetTeamIsActive(ctx context.Context, userID string, teamName string, isActive bool) (*model.User, error)
+	Update(ctx context.Context, update *model.UserUpdate) (*model.User, []*model.ReleasedReview, error)
+	GetReview(ctx context.Context, userID string, filter model.ReviewFilter) (*model.ReviewPage, error)
+	GetReviewLoad(ctx context.Context, userID string) (*model.ReviewLoad, error)
+	SetReviewerPreferences(ctx context.Context, prefs *model.ReviewerPreferences) error
+	GetReviewerPreferences(ctx context.Context, userID string) (*model.ReviewerPreferences, error)
+	SetSchedule(ctx context.Context, schedule *model.WorkSchedule) error
+	GetSchedule(ctx context.Context, userID string) (*model.WorkSchedule, error)
+	ReviewQueues(ctx context.Context, assignedBefore time.Time) ([]*model.ReviewerQueue, error)
+	MarkReminded(ctx context.Context, userID string, at time.Time, notAfter time.Time) (bool, *time.Time, error)
+	UnmarkReminded(ctx context.Context, userID string, at time.Time, previous *time.Time) error
+	SetNotificationSettings(ctx context.Context, settings *model.NotificationSettings) error
+	GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error)
+	GetChatHandles(ctx context.Context, userIDs []string) (map[string]string, error)
 }
 
 type PullRequestRepository interface {
 	Create(ctx context.Context, req model.PullRequestPayload) (*model.PullRequest, error)
-	Merge(ctx context.Context, prID string) (*model.PullRequest, error)
-	Reassign(ctx context.Context, prID string, oldReviewerID string) (*model.PullRequest, string, error)
+	Merge(ctx context.Context, prID string) (*model.PullRequest, bool, error)
+	Reassign(ctx context.Context, prID string, oldReviewerID string, newReviewerID string) (*model.PullRequest, string, error)
+	AddReviewer(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error)
+	RemoveReviewer(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error)
+	Get(ctx context.Context, prID string) (*model.PullRequest, error)
+	List(ctx context.Context, filter model.PullRequestFilter) (*model.PullRequestPage, error)
+	SubmitReview(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error)
+	PendingReviews(ctx context.Context) ([]*model.PendingReview, error)
+	MarkOverdue(ctx context.Context, prID string, reviewerID string, at time.Time) (bool, error)
+	ListOverdue(ctx context.Context, teamName string) ([]*model.OverdueReview, error)
+}
+
+type StatsRepository interface {
+	ReviewerStats(ctx context.Context, filter model.StatsFilter) ([]*model.ReviewerStats, error)
+	PullRequestTimes(ctx context.Context, filter model.StatsFilter) ([]*model.PullRequestTimes, error)
+	ExportAssignments(ctx context.Context, filter model.StatsFilter, emit func(*model.AssignmentExport) error) error
+	ExportPullRequests(ctx context.Context, filter model.StatsFilter, emit func(*model.PullRequestExport) error) error
 }
@@ -0,0 +1,1212 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/ownership"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/pagination"
+	def "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/selection"
+	"strings"
+	"time"
+)
+
+var _ def.PullRequestRepository = (*repository)(nil)
+
+type repository struct {
+	db        *sql.DB
+	selection selection.Config
+}
+
+func NewRepository(db *sql.DB, cfg selection.Config) *repository {
+	return &repository{db: db, selection: cfg}
+}
+
+func (r *repository) Create(ctx context.Context, req model.PullRequestPayload) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, teamName, err := primaryTeam(ctx, tx, req.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.TeamName != "" {
+		teamID, teamName, err = teamByName(ctx, tx, req.TeamName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	existsQuery := `
+		SELECT EXISTS (SELECT 1 FROM pull_requests WHERE pull_request_id = ?)
+	`
+	var exists bool
+	if err := tx.QueryRowContext(ctx, existsQuery, req.PullRequestID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check pr error: %v", err)
+	}
+	if exists {
+		return nil, model.ErrPrExists
+	}
+
+	matches := make([]*model.ReviewerMatch, 0)
+	uncovered := req.RequiredSkills
+	if teamID.Valid {
+		owners, err := ownerCandidates(ctx, tx, teamID.Int64, req.AuthorID, req.ChangedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owners: %v", err)
+		}
+
+		pool, err := selectReviewers(ctx, tx, teamID.Int64, req.AuthorID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reviewers: %v", err)
+		}
+
+		minSeniors, err := seniorRule(ctx, tx, teamID.Int64)
+		if err != nil {
+			return nil, err
+		}
+
+		var history map[string]int
+		if window := r.selection.Window(); window > 0 {
+			history, err = recentReviewers(ctx, tx, req.AuthorID, window)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		in := selection.Input{
+			Limit:          2,
+			Owners:         owners,
+			Pool:           pool,
+			RequiredSkills: req.RequiredSkills,
+			MinSeniors:     minSeniors,
+			History:        history,
+			Now:            time.Now(),
+		}
+		matches, uncovered, err = selection.Pick(in)
+
+		// the fallback chain is only loaded when the team cannot fill the seats
+		if err == model.ErrNoSenior || (err == nil && len(matches) < in.Limit) {
+			in.Fallbacks, err = fallbackCandidates(ctx, tx, teamID.Int64, req.AuthorID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get fallback reviewers: %v", err)
+			}
+			matches, uncovered, err = selection.Pick(in)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	reviewers := selection.ReviewerIDs(matches)
+
+	addNewPRQuery := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	addReviewiers := `
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id, assigned_at)
+		VALUES (?, ?, ?)
+	`
+	now := time.Now().UTC()
+
+	_, err = tx.
+		ExecContext(ctx, addNewPRQuery, req.PullRequestID, req.PullRequestName, req.AuthorID, statusToID("OPEN"), teamID, now)
+
+	if err != nil {
+		return nil, fmt.Errorf("insert pr failed: %v", err)
+	}
+	for _, rid := range reviewers {
+		_, err := tx.ExecContext(ctx, addReviewiers, req.PullRequestID, rid, now)
+		if err != nil {
+			return nil, fmt.Errorf("insert reviewer failed: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit error: %v", err)
+	}
+
+	pr := &model.PullRequest{
+		PullRequestShort: model.PullRequestShort{
+			PullRequestID:   req.PullRequestID,
+			PullRequestName: req.PullRequestName,
+			AuthorID:        req.AuthorID,
+			Status:          "OPEN",
+		},
+		TeamName:          teamName.String,
+		AssignedReviewers: reviewers,
+		CreatedAt:         &now,
+		ReviewerMatches:   matches,
+		UncoveredSkills:   uncovered,
+	}
+	return pr, nil
+}
+
+// Merge marks the pull request MERGED. Merging it again changes nothing;
+// merged reports whether this call was the one that merged it.
+func (r *repository) Merge(ctx context.Context, prID string) (*model.PullRequest, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	pr, statusID, _, err := getPullRequest(ctx, tx, prID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if idToStatus(statusID) == "MERGED" {
+		return pr, false, nil
+	}
+
+	updatePrQuery := `
+		UPDATE pull_requests SET status_id = ?, mergedAt = ?
+		WHERE pull_request_id = ?
+	`
+	mergedNow := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, updatePrQuery, statusToID("MERGED"), mergedNow, prID)
+	if err != nil {
+		return nil, false, fmt.Errorf("merge error: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("commit error: %v", err)
+	}
+
+	pr.Status = "MERGED"
+	pr.MergedAt = &mergedNow
+	return pr, true, nil
+}
+
+// Reassign hands oldReviewerID's seat over to newReviewerID, or to a
+// reviewer picked from the team and its fallback chain when newReviewerID is
+// empty. Users already reviewing the pull request are never picked.
+func (r *repository) Reassign(ctx context.Context, prID string, oldReviewerID string, newReviewerID string) (*model.PullRequest, string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	pr, statusID, teamID, err := getPullRequest(ctx, tx, prID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if idToStatus(statusID) == "MERGED" {
+		return nil, "", model.ErrPrMerged
+	}
+
+	found := false
+	for _, rid := range pr.AssignedReviewers {
+		if rid == oldReviewerID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, "", model.ErrNotAssigned
+	}
+
+	// pull requests whose team was deleted fall back to the author's team
+	if !teamID.Valid {
+		var teamName sql.NullString
+		teamID, teamName, err = primaryTeam(ctx, tx, pr.AuthorID)
+		if err != nil {
+			return nil, "", err
+		}
+		pr.TeamName = teamName.String
+	}
+
+	if !teamID.Valid {
+		return nil, "", model.ErrNoCandidate
+	}
+
+	needSenior, err := replacementMustBeSenior(ctx, tx, teamID.Int64, pr.AssignedReviewers, oldReviewerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var history map[string]int
+	if window := r.selection.Window(); window > 0 {
+		history, err = recentReviewers(ctx, tx, pr.AuthorID, window)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	candidates, err := selectReviewers(ctx, tx, teamID.Int64, pr.AuthorID, pr.AssignedReviewers...)
+	if err != nil {
+		return nil, "", fmt.Errorf("get new reviewer error: %v", err)
+	}
+	newReviewer, ok := replacement(candidates, history, newReviewerID, needSenior)
+	fallbackTeam := ""
+	if !ok {
+		fallbacks, err := fallbackCandidates(ctx, tx, teamID.Int64, pr.AuthorID, pr.AssignedReviewers...)
+		if err != nil {
+			return nil, "", fmt.Errorf("get fallback reviewer error: %v", err)
+		}
+		for _, fb := range fallbacks {
+			if newReviewer, ok = replacement(fb.Candidates, history, newReviewerID, needSenior); ok {
+				fallbackTeam = fb.Team
+				break
+			}
+		}
+	}
+	if !ok && needSenior {
+		return nil, "", model.ErrNoSenior
+	}
+	if !ok {
+		return nil, "", model.ErrNoCandidate
+	}
+
+	updateReviewerQuery := `
+		UPDATE pull_request_reviewers
+		SET reviewer_user_id = ?, assigned_at = ?, reviewed_at = NULL, overdue_at = NULL
+		WHERE pull_request_id = ? AND reviewer_user_id = ?
+	`
+	_, err = tx.
+		ExecContext(ctx, updateReviewerQuery, newReviewer, time.Now().UTC(), prID, oldReviewerID)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("update reviewer error: %v", err)
+	}
+
+	if err := recordHistory(ctx, tx, prID, newReviewer, "reassigned", oldReviewerID); err != nil {
+		return nil, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("commit error: %v", err)
+	}
+
+	for i := range pr.AssignedReviewers {
+		if pr.AssignedReviewers[i] == oldReviewerID {
+			pr.AssignedReviewers[i] = newReviewer
+			break
+		}
+	}
+	pr.ReviewerMatches = []*model.ReviewerMatch{{UserID: newReviewer, FallbackTeam: fallbackTeam}}
+
+	return pr, newReviewer, nil
+}
+
+// Get loads a pull request with its reviewers.
+func (r *repository) Get(ctx context.Context, prID string) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	pr, _, _, err := getPullRequest(ctx, tx, prID)
+	return pr, err
+}
+
+// timestamp normalizes a stored or bound time so that values written by
+// CURRENT_TIMESTAMP and by the driver compare and sort alike.
+func timestamp(expr string) string {
+	return "strftime('%Y-%m-%d %H:%M:%f', " + expr + ")"
+}
+
+// List returns one page of the pull requests matching the filter, ordered by
+// the sort key and then by id.
+func (r *repository) List(ctx context.Context, filter model.PullRequestFilter) (*model.PullRequestPage, error) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	if filter.Status != "" {
+		conds = append(conds, "pr.status_id = ?")
+		args = append(args, statusToID(filter.Status))
+	}
+	if filter.AuthorID != "" {
+		conds = append(conds, "pr.author_id = ?")
+		args = append(args, filter.AuthorID)
+	}
+	if filter.ReviewerID != "" {
+		conds = append(conds, `EXISTS (
+			SELECT 1 FROM pull_request_reviewers prr
+			WHERE prr.pull_request_id = pr.pull_request_id AND prr.reviewer_user_id = ?
+		)`)
+		args = append(args, filter.ReviewerID)
+	}
+	if filter.TeamName != "" {
+		conds = append(conds, "t.team_name = ?")
+		args = append(args, filter.TeamName)
+	}
+	bounds := []struct {
+		column string
+		cmp    string
+		value  *time.Time
+	}{
+		{"pr.createdAt", ">=", filter.CreatedFrom},
+		{"pr.createdAt", "<", filter.CreatedTo},
+		{"pr.mergedAt", ">=", filter.MergedFrom},
+		{"pr.mergedAt", "<", filter.MergedTo},
+	}
+	for _, b := range bounds {
+		if b.value != nil {
+			conds = append(conds, timestamp(b.column)+" "+b.cmp+" "+timestamp("?"))
+			args = append(args, b.value.UTC())
+		}
+	}
+
+	sortKey := timestamp("pr.createdAt")
+	if filter.Sort == model.PullRequestSortName {
+		sortKey = "pr.pull_request_name"
+	}
+	direction, cmp := "ASC", ">"
+	if filter.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	// the cursor key is the sort key as selected below, so it compares as is
+	if filter.After != nil {
+		conds = append(conds, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND pr.pull_request_id %[2]s ?))", sortKey, cmp))
+		args = append(args, filter.After.Key, filter.After.Key, filter.After.ID)
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = pagination.DefaultLimit
+	}
+	args = append(args, limit+1)
+
+	listQuery := fmt.Sprintf(`
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, t.team_name, %[1]s
+		FROM pull_requests pr
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		%[2]s
+		ORDER BY %[1]s %[3]s, pr.pull_request_id %[3]s
+		LIMIT ?
+	`, sortKey, where, direction)
+
+	rows, err := r.db.QueryContext(ctx, listQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list pull requests error: %v", err)
+	}
+	defer rows.Close()
+
+	prs := make([]*model.PullRequest, 0)
+	keys := make([]string, 0)
+	byID := make(map[string]*model.PullRequest)
+	for rows.Next() {
+		var (
+			pr                  model.PullRequest
+			statusID            int
+			teamName            sql.NullString
+			createdAt, mergedAt sql.NullTime
+			key                 string
+		)
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &statusID, &createdAt, &mergedAt, &teamName, &key); err != nil {
+			return nil, fmt.Errorf("scan pull request error: %v", err)
+		}
+		pr.Status = idToStatus(statusID)
+		pr.TeamName = teamName.String
+		pr.AssignedReviewers = make([]string, 0)
+		if createdAt.Valid {
+			pr.CreatedAt = &createdAt.Time
+		}
+		if mergedAt.Valid {
+			pr.MergedAt = &mergedAt.Time
+		}
+		prs = append(prs, &pr)
+		keys = append(keys, key)
+		byID[pr.PullRequestID] = &pr
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list pull requests error: %v", err)
+	}
+	rows.Close()
+
+	page := &model.PullRequestPage{PullRequests: prs}
+	if len(prs) > limit {
+		page.PullRequests = prs[:limit]
+		page.Next = &model.Cursor{Key: keys[limit-1], ID: prs[limit-1].PullRequestID}
+	}
+	if len(page.PullRequests) == 0 {
+		return page, nil
+	}
+
+	ids := make([]any, 0, len(page.PullRequests))
+	for _, pr := range page.PullRequests {
+		ids = append(ids, pr.PullRequestID)
+	}
+	getReviewersQuery := fmt.Sprintf(`
+		SELECT pull_request_id, reviewer_user_id
+		FROM pull_request_reviewers
+		WHERE pull_request_id IN (%s)
+		ORDER BY assigned_at, reviewer_user_id
+	`, placeholders(len(ids)))
+	reviewerRows, err := r.db.QueryContext(ctx, getReviewersQuery, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("get reviewers error: %v", err)
+	}
+	defer reviewerRows.Close()
+
+	for reviewerRows.Next() {
+		var prID, reviewerID string
+		if err := reviewerRows.Scan(&prID, &reviewerID); err != nil {
+			return nil, fmt.Errorf("get reviewers error: %v", err)
+		}
+		if pr, ok := byID[prID]; ok {
+			pr.AssignedReviewers = append(pr.AssignedReviewers, reviewerID)
+		}
+	}
+	if err := reviewerRows.Err(); err != nil {
+		return nil, fmt.Errorf("get reviewers error: %v", err)
+	}
+	return page, nil
+}
+
+// AddReviewer assigns an extra reviewer to an open pull request. The reviewer
+// must be an active user other than the author.
+func (r *repository) AddReviewer(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	pr, statusID, _, err := getPullRequest(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if idToStatus(statusID) == "MERGED" {
+		return nil, model.ErrPrMerged
+	}
+
+	getUserQuery := `
+		SELECT is_active
+		FROM users
+		WHERE user_id = ?
+	`
+	var isActive bool
+	err = tx.QueryRowContext(ctx, getUserQuery, reviewerID).Scan(&isActive)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reviewer error: %v", err)
+	}
+	if !isActive || reviewerID == pr.AuthorID {
+		return nil, model.ErrInvalidReviewer
+	}
+	for _, rid := range pr.AssignedReviewers {
+		if rid == reviewerID {
+			return nil, model.ErrAlreadyAssigned
+		}
+	}
+
+	addReviewerQuery := `
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id, assigned_at)
+		VALUES (?, ?, ?)
+	`
+	if _, err := tx.ExecContext(ctx, addReviewerQuery, prID, reviewerID, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("insert reviewer failed: %v", err)
+	}
+
+	if err := recordHistory(ctx, tx, prID, reviewerID, "added", ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit error: %v", err)
+	}
+
+	pr.AssignedReviewers = append(pr.AssignedReviewers, reviewerID)
+	return pr, nil
+}
+
+// RemoveReviewer drops a reviewer from an open pull request without a
+// replacement.
+func (r *repository) RemoveReviewer(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	pr, statusID, _, err := getPullRequest(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if idToStatus(statusID) == "MERGED" {
+		return nil, model.ErrPrMerged
+	}
+
+	removeReviewerQuery := `
+		DELETE FROM pull_request_reviewers
+		WHERE pull_request_id = ? AND reviewer_user_id = ?
+	`
+	result, err := tx.ExecContext(ctx, removeReviewerQuery, prID, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("remove reviewer failed: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return nil, model.ErrNotAssigned
+	}
+
+	if err := recordHistory(ctx, tx, prID, reviewerID, "removed", ""); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit error: %v", err)
+	}
+
+	reviewers := make([]string, 0, len(pr.AssignedReviewers))
+	for _, rid := range pr.AssignedReviewers {
+		if rid != reviewerID {
+			reviewers = append(reviewers, rid)
+		}
+	}
+	pr.AssignedReviewers = reviewers
+	return pr, nil
+}
+
+// SubmitReview records that the reviewer has reviewed the open pull request,
+// which stops the SLA clock of their seat.
+func (r *repository) SubmitReview(ctx context.Context, prID string, reviewerID string) (*model.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	pr, statusID, _, err := getPullRequest(ctx, tx, prID)
+	if err != nil {
+		return nil, err
+	}
+	if idToStatus(statusID) == "MERGED" {
+		return nil, model.ErrPrMerged
+	}
+
+	submitReviewQuery := `
+		UPDATE pull_request_reviewers
+		SET reviewed_at = COALESCE(reviewed_at, ?)
+		WHERE pull_request_id = ? AND reviewer_user_id = ?
+	`
+	result, err := tx.ExecContext(ctx, submitReviewQuery, time.Now().UTC(), prID, reviewerID)
+	if err != nil {
+		return nil, fmt.Errorf("submit review error: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rows affected error: %v", err)
+	}
+	if rowsAffected == 0 {
+		return nil, model.ErrNotAssigned
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit error: %v", err)
+	}
+	return pr, nil
+}
+
+// PendingReviews lists the seats the SLA worker watches: those on OPEN pull
+// requests of teams with an SLA that are neither reviewed nor overdue yet.
+func (r *repository) PendingReviews(ctx context.Context) ([]*model.PendingReview, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			prr.reviewer_user_id,
+			prr.assigned_at,
+			t.team_name,
+			t.review_sla_minutes,
+			t.sla_auto_reassign,
+			u.timezone,
+			COALESCE(u.work_start, ''),
+			COALESCE(u.work_end, ''),
+			(SELECT GROUP_CONCAT(h.holiday) FROM team_holidays h WHERE h.team_id = t.team_id)
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr
+			ON pr.pull_request_id = prr.pull_request_id
+		JOIN teams t
+			ON t.team_id = pr.team_id
+		JOIN users u
+			ON u.user_id = prr.reviewer_user_id
+		WHERE pr.status_id = ?
+			AND t.review_sla_minutes IS NOT NULL
+			AND prr.reviewed_at IS NULL
+			AND prr.overdue_at IS NULL
+		ORDER BY %s, pr.pull_request_id, prr.reviewer_user_id
+	`, timestamp("prr.assigned_at"))
+	rows, err := r.db.QueryContext(ctx, query, statusToID("OPEN"))
+	if err != nil {
+		return nil, fmt.Errorf("select pending reviews error: %v", err)
+	}
+	defer rows.Close()
+
+	pending := make([]*model.PendingReview, 0)
+	for rows.Next() {
+		var (
+			p        = &model.PendingReview{}
+			holidays sql.NullString
+		)
+		err := rows.Scan(
+			&p.PullRequestID,
+			&p.PullRequestName,
+			&p.ReviewerID,
+			&p.AssignedAt,
+			&p.SLA.TeamName,
+			&p.SLA.Minutes,
+			&p.SLA.AutoReassign,
+			&p.Schedule.Timezone,
+			&p.Schedule.WorkStart,
+			&p.Schedule.WorkEnd,
+			&holidays,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan pending review error: %v", err)
+		}
+		if holidays.Valid {
+			p.Holidays = strings.Split(holidays.String, ",")
+		}
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate pending reviews error: %v", err)
+	}
+	return pending, nil
+}
+
+// MarkOverdue flags the seat as overdue. It reports false when the seat is
+// gone, reviewed or already flagged, so that each miss is handled once even
+// with several workers running.
+func (r *repository) MarkOverdue(ctx context.Context, prID string, reviewerID string, at time.Time) (bool, error) {
+	markOverdueQuery := `
+		UPDATE pull_request_reviewers
+		SET overdue_at = ?
+		WHERE pull_request_id = ?
+			AND reviewer_user_id = ?
+			AND reviewed_at IS NULL
+			AND overdue_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, markOverdueQuery, at.UTC(), prID, reviewerID)
+	if err != nil {
+		return false, fmt.Errorf("mark overdue error: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected error: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// ListOverdue lists the overdue seats on OPEN pull requests, of one team when
+// teamName is set, the longest overdue first.
+func (r *repository) ListOverdue(ctx context.Context, teamName string) ([]*model.OverdueReview, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			prr.reviewer_user_id,
+			COALESCE(t.team_name, ''),
+			prr.assigned_at,
+			prr.overdue_at
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr
+			ON pr.pull_request_id = prr.pull_request_id
+		LEFT JOIN teams t
+			ON t.team_id = pr.team_id
+		WHERE pr.status_id = ?
+			AND prr.overdue_at IS NOT NULL
+			AND prr.reviewed_at IS NULL
+			AND (? = '' OR t.team_name = ?)
+		ORDER BY %s, pr.pull_request_id, prr.reviewer_user_id
+	`, timestamp("prr.overdue_at"))
+	rows, err := r.db.QueryContext(ctx, query, statusToID("OPEN"), teamName, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("select overdue reviews error: %v", err)
+	}
+	defer rows.Close()
+
+	overdue := make([]*model.OverdueReview, 0)
+	for rows.Next() {
+		o := &model.OverdueReview{}
+		err := rows.Scan(
+			&o.PullRequestID,
+			&o.PullRequestName,
+			&o.ReviewerID,
+			&o.TeamName,
+			&o.AssignedAt,
+			&o.OverdueAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan overdue review error: %v", err)
+		}
+		overdue = append(overdue, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate overdue reviews error: %v", err)
+	}
+	return overdue, nil
+}
+
+// recordHistory stores a manual change of the pull request's reviewers.
+// replacedID is the reviewer a reassigned one took over from.
+func recordHistory(ctx context.Context, tx *sql.Tx, prID, reviewerID, action, replacedID string) error {
+	addHistoryQuery := `
+		INSERT INTO reviewer_history (pull_request_id, reviewer_user_id, action, replaced_user_id, created_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?)
+	`
+	if _, err := tx.ExecContext(ctx, addHistoryQuery, prID, reviewerID, action, replacedID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("record history error: %v", err)
+	}
+	return nil
+}
+
+// getPullRequest loads a pull request with its reviewers and returns the raw
+// status and team ids alongside it.
+func getPullRequest(ctx context.Context, tx *sql.Tx, prID string) (*model.PullRequest, int, sql.NullInt64, error) {
+	var (
+		name, author        string
+		statusID            int
+		teamID              sql.NullInt64
+		teamName            sql.NullString
+		createdAt, mergedAt sql.NullTime
+	)
+
+	getPrQuery := `
+		SELECT pr.pull_request_name, pr.author_id, pr.status_id, pr.createdAt, pr.mergedAt, pr.team_id, t.team_name
+		FROM pull_requests pr
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		WHERE pr.pull_request_id = ?
+	`
+	err := tx.
+		QueryRowContext(ctx, getPrQuery, prID).
+		Scan(&name, &author, &statusID, &createdAt, &mergedAt, &teamID, &teamName)
+
+	if err == sql.ErrNoRows {
+		return nil, 0, teamID, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, teamID, fmt.Errorf("select pr error: %v", err)
+	}
+
+	getReviewerIdQuery := `
+		SELECT reviewer_user_id
+		FROM pull_request_reviewers
+		WHERE pull_request_id = ?
+		ORDER BY assigned_at, reviewer_user_id
+	`
+	rows, err := tx.QueryContext(ctx, getReviewerIdQuery, prID)
+	if err != nil {
+		return nil, 0, teamID, fmt.Errorf("get reviewers error: %v", err)
+	}
+	defer rows.Close()
+
+	reviewers := make([]string, 0)
+	for rows.Next() {
+		var rid string
+		if err := rows.Scan(&rid); err != nil {
+			return nil, 0, teamID, err
+		}
+		reviewers = append(reviewers, rid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, teamID, fmt.Errorf("get reviewers error: %v", err)
+	}
+
+	pr := &model.PullRequest{
+		PullRequestShort: model.PullRequestShort{
+			PullRequestID:   prID,
+			PullRequestName: name,
+			AuthorID:        author,
+			Status:          idToStatus(statusID),
+		},
+		TeamName:          teamName.String,
+		AssignedReviewers: reviewers,
+	}
+	if createdAt.Valid {
+		pr.CreatedAt = &createdAt.Time
+	}
+	if mergedAt.Valid {
+		pr.MergedAt = &mergedAt.Time
+	}
+	return pr, statusID, teamID, nil
+}
+
+// primaryTeam returns the team the author joined first. The team is NULL for
+// authors without memberships; unknown and deleted authors are reported as
+// not found.
+func primaryTeam(ctx context.Context, tx *sql.Tx, authorID string) (sql.NullInt64, sql.NullString, error) {
+	getCommandQuery := `
+		SELECT t.team_id, t.team_name
+		FROM users u
+		LEFT JOIN team_members tm ON tm.user_id = u.user_id
+		LEFT JOIN teams t ON t.team_id = tm.team_id
+		WHERE u.user_id = ? AND u.deleted_at IS NULL
+		ORDER BY tm.joined_at, t.team_name
+		LIMIT 1
+	`
+
+	var (
+		teamID   sql.NullInt64
+		teamName sql.NullString
+	)
+	err := tx.
+		QueryRowContext(ctx, getCommandQuery, authorID).
+		Scan(&teamID, &teamName)
+
+	if err == sql.ErrNoRows {
+		return teamID, teamName, model.ErrNotFound
+	}
+	if err != nil {
+		return teamID, teamName, fmt.Errorf("get author team error: %v", err)
+	}
+	return teamID, teamName, nil
+}
+
+// teamByName resolves the explicit target team of a pull request.
+func teamByName(ctx context.Context, q *sql.Tx, teamName string) (sql.NullInt64, sql.NullString, error) {
+	getTeamQuery := `
+		SELECT team_id, team_name
+		FROM teams
+		WHERE team_name = ?
+	`
+
+	var (
+		teamID sql.NullInt64
+		name   sql.NullString
+	)
+	err := q.
+		QueryRowContext(ctx, getTeamQuery, teamName).
+		Scan(&teamID, &name)
+
+	if err == sql.ErrNoRows {
+		return teamID, name, model.ErrNotFound
+	}
+	if err != nil {
+		return teamID, name, fmt.Errorf("get team error: %v", err)
+	}
+	return teamID, name, nil
+}
+
+// recentReviewers counts how many of the author's latest pull requests, up to
+// window, each user reviewed.
+func recentReviewers(ctx context.Context, tx *sql.Tx, authorID string, window int) (map[string]int, error) {
+	getHistoryQuery := `
+		SELECT prr.reviewer_user_id, COUNT(*)
+		FROM pull_request_reviewers prr
+		WHERE prr.pull_request_id IN (
+			SELECT pull_request_id
+			FROM pull_requests
+			WHERE author_id = ?
+			ORDER BY createdAt DESC
+			LIMIT ?
+		)
+		GROUP BY prr.reviewer_user_id
+	`
+	rows, err := tx.QueryContext(ctx, getHistoryQuery, authorID, window)
+	if err != nil {
+		return nil, fmt.Errorf("get review history error: %v", err)
+	}
+	defer rows.Close()
+
+	history := make(map[string]int)
+	for rows.Next() {
+		var (
+			reviewerID string
+			count      int
+		)
+		if err := rows.Scan(&reviewerID, &count); err != nil {
+			return nil, fmt.Errorf("get review history error: %v", err)
+		}
+		history[reviewerID] = count
+	}
+	return history, rows.Err()
+}
+
+// seniorRule returns how many senior reviewers the team requires.
+func seniorRule(ctx context.Context, tx *sql.Tx, teamID int64) (int, error) {
+	getRuleQuery := `
+		SELECT min_senior_reviewers
+		FROM teams
+		WHERE team_id = ?
+	`
+	var minSeniors int
+	if err := tx.QueryRowContext(ctx, getRuleQuery, teamID).Scan(&minSeniors); err != nil {
+		return 0, fmt.Errorf("get seniority rule error: %v", err)
+	}
+	return minSeniors, nil
+}
+
+// replacementMustBeSenior tells whether dropping oldReviewerID would break
+// the team's seniority rule, capped at the number of assigned reviewers.
+func replacementMustBeSenior(ctx context.Context, tx *sql.Tx, teamID int64, reviewers []string, oldReviewerID string) (bool, error) {
+	minSeniors, err := seniorRule(ctx, tx, teamID)
+	if err != nil || minSeniors == 0 {
+		return false, err
+	}
+
+	args := make([]any, 0, len(reviewers))
+	for _, id := range reviewers {
+		if id != oldReviewerID {
+			args = append(args, id)
+		}
+	}
+
+	countSeniorsQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM users
+		WHERE user_id IN (%s) AND level = 'senior'
+	`, placeholders(len(args)))
+	var seniors int
+	if err := tx.QueryRowContext(ctx, countSeniorsQuery, args...).Scan(&seniors); err != nil {
+		return false, fmt.Errorf("count seniors error: %v", err)
+	}
+	return seniors < min(minSeniors, len(reviewers)), nil
+}
+
+// ownerCandidates matches the changed files against the team's ownership
+// rules and lists the active owners of every matched rule, author and users
+// at capacity excluded.
+func ownerCandidates(ctx context.Context, tx *sql.Tx, teamID int64, authorID string, files []string) ([]selection.Owners, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	getRulesQuery := `
+		SELECT position, pattern, owners
+		FROM team_ownership_rules
+		WHERE team_id = ?
+		ORDER BY position
+	`
+	rows, err := tx.QueryContext(ctx, getRulesQuery, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]*model.OwnershipRule, 0)
+	for rows.Next() {
+		var (
+			rule   model.OwnershipRule
+			owners string
+		)
+		if err := rows.Scan(&rule.Line, &rule.Pattern, &owners); err != nil {
+			return nil, err
+		}
+		rule.Owners = strings.Fields(owners)
+		rules = append(rules, &rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	owners := make([]selection.Owners, 0)
+	for _, rule := range ownership.MatchFiles(rules, files) {
+		userIDs, teamNames := ownership.SplitOwners(rule.Owners)
+
+		// SQLite has no arrays, so the IN lists are expanded per rule
+		getOwnersQuery := fmt.Sprintf(`
+			SELECT u.user_id, (SELECT GROUP_CONCAT(s.skill) FROM user_skills s WHERE s.user_id = u.user_id),
+				COALESCE(u.level = 'senior', 0), rp.kind IS NOT NULL,
+				u.timezone, COALESCE(u.work_start, ''), COALESCE(u.work_end, ''),
+				(SELECT GROUP_CONCAT(h.holiday) FROM team_holidays h WHERE h.team_id = ?)
+			FROM users u
+			JOIN reviewer_load rl ON rl.user_id = u.user_id
+			LEFT JOIN reviewer_preferences rp ON rp.author_id = ? AND rp.reviewer_id = u.user_id
+			LEFT JOIN team_members tm ON tm.user_id = u.user_id AND tm.is_active = TRUE
+			LEFT JOIN teams t ON t.team_id = tm.team_id
+			WHERE u.is_active = TRUE AND u.user_id <> ?
+				AND (u.user_id IN (%s) OR t.team_name IN (%s))
+				AND (rl.max_open_reviews IS NULL OR rl.open_reviews < rl.max_open_reviews)
+				AND rp.kind IS NOT 'blocked'
+			GROUP BY u.user_id
+			ORDER BY RANDOM()
+		`, placeholders(len(userIDs)), placeholders(len(teamNames)))
+
+		args := []any{teamID, authorID, authorID}
+		for _, id := range userIDs {
+			args = append(args, id)
+		}
+		for _, name := range teamNames {
+			args = append(args, name)
+		}
+
+		candidates, err := queryCandidates(ctx, tx, getOwnersQuery, args...)
+		if err != nil {
+			return nil, err
+		}
+		owners = append(owners, selection.Owners{Rule: rule, Candidates: candidates})
+	}
+	return owners, nil
+}
+
+// queryCandidates runs a query returning user ids with their skills joined by
+// commas, seniority, whether the author prefers them, their working hours and
+// the holidays of the team they are picked for, joined by commas.
+func queryCandidates(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]selection.Candidate, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates := make([]selection.Candidate, 0)
+	for rows.Next() {
+		var (
+			c                selection.Candidate
+			skills, holidays sql.NullString
+		)
+		err := rows.Scan(&c.UserID, &skills, &c.Senior, &c.Preferred,
+			&c.Schedule.Timezone, &c.Schedule.WorkStart, &c.Schedule.WorkEnd, &holidays)
+		if err != nil {
+			return nil, err
+		}
+		if skills.Valid {
+			c.Skills = strings.Split(skills.String, ",")
+		}
+		if holidays.Valid {
+			c.Holidays = strings.Split(holidays.String, ",")
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// selectReviewers lists, in random order, the members that are active both
+// in the team and globally and have spare review capacity, skipping the
+// author, the reviewers they blocked and the excluded user ids.
+func selectReviewers(ctx context.Context, tx *sql.Tx, teamID int64, authorID string, exclude ...string) ([]selection.Candidate, error) {
+	getReviewiersQuery := `
+		SELECT u.user_id, (SELECT GROUP_CONCAT(s.skill) FROM user_skills s WHERE s.user_id = u.user_id),
+			COALESCE(u.level = 'senior', 0), rp.kind IS NOT NULL,
+			u.timezone, COALESCE(u.work_start, ''), COALESCE(u.work_end, ''),
+			(SELECT GROUP_CONCAT(h.holiday) FROM team_holidays h WHERE h.team_id = ?)
+		FROM team_members tm
+		JOIN users u ON u.user_id = tm.user_id
+		JOIN reviewer_load rl ON rl.user_id = u.user_id
+		LEFT JOIN reviewer_preferences rp ON rp.author_id = ? AND rp.reviewer_id = u.user_id
+		WHERE tm.team_id = ? AND tm.is_active = TRUE AND u.is_active = TRUE
+			AND (rl.max_open_reviews IS NULL OR rl.open_reviews < rl.max_open_reviews)
+			AND rp.kind IS NOT 'blocked'
+		ORDER BY RANDOM()
+	`
+
+	candidates, err := queryCandidates(ctx, tx, getReviewiersQuery, teamID, authorID, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := map[string]bool{authorID: true}
+	for _, id := range exclude {
+		excluded[id] = true
+	}
+
+	reviewers := make([]selection.Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		if !excluded[c.UserID] {
+			reviewers = append(reviewers, c)
+		}
+	}
+	return reviewers, nil
+}
+
+// replacement returns the best ranked candidate that keeps the seniority
+// rule, members at work right now first. A non-empty nominee is the only
+// candidate considered.
+func replacement(candidates []selection.Candidate, history map[string]int, nominee string, needSenior bool) (string, bool) {
+	for _, c := range selection.Rank(candidates, history, time.Now()) {
+		if nominee != "" && c.UserID != nominee {
+			continue
+		}
+		if !needSenior || c.Senior {
+			return c.UserID, true
+		}
+	}
+	return "", false
+}
+
+// fallbackCandidates lists the reviewer pools of the team's fallback teams in
+// chain order, with the same filters as selectReviewers.
+func fallbackCandidates(ctx context.Context, tx *sql.Tx, teamID int64, authorID string, exclude ...string) ([]selection.Fallback, error) {
+	getFallbacksQuery := `
+		SELECT f.team_id, f.team_name
+		FROM team_fallbacks tf
+		JOIN teams f ON f.team_id = tf.fallback_team_id
+		WHERE tf.team_id = ?
+		ORDER BY tf.position
+	`
+	rows, err := tx.QueryContext(ctx, getFallbacksQuery, teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type team struct {
+		id   int64
+		name string
+	}
+	teams := make([]team, 0)
+	for rows.Next() {
+		var t team
+		if err := rows.Scan(&t.id, &t.name); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	fallbacks := make([]selection.Fallback, 0, len(teams))
+	for _, t := range teams {
+		candidates, err := selectReviewers(ctx, tx, t.id, authorID, exclude...)
+		if err != nil {
+			return nil, err
+		}
+		fallbacks = append(fallbacks, selection.Fallback{Team: t.name, Candidates: candidates})
+	}
+	return fallbacks, nil
+}
+
+func statusToID(status string) int {
+	switch status {
+	case "OPEN":
+		return 1
+	case "MERGED":
+		return 2
+	default:
+		return 0
+	}
+}
+
+func idToStatus(statusID int) string {
+	switch statusID {
+	case 1:
+		return "OPEN"
+	case 2:
+		return "MERGED"
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -0,0 +1,891 @@
+package pr
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/database"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/selection"
+)
+
+func newTestRepo(t *testing.T) (*repository, *sql.DB) {
+	t.Helper()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	seed := `
+		INSERT INTO teams (team_id, team_name) VALUES (1, 'backend'), (2, 'frontend');
+		INSERT INTO users (user_id, username, is_active) VALUES
+			('u1', 'Alice', TRUE),
+			('u2', 'Bob', TRUE),
+			('u3', 'Carol', TRUE),
+			('u4', 'Dan', FALSE),
+			('u5', 'Eve', TRUE);
+		INSERT INTO team_members (team_id, user_id, is_active) VALUES
+			(1, 'u1', TRUE),
+			(1, 'u2', TRUE),
+			(1, 'u3', TRUE),
+			(1, 'u4', TRUE),
+			(2, 'u5', TRUE);
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	return &repository{db: db}, db
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCreateSuccess(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Add search",
+		AuthorID:        "u1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pr.AssignedReviewers) != 2 || !contains(pr.AssignedReviewers, "u2") || !contains(pr.AssignedReviewers, "u3") {
+		t.Errorf("wrong reviewers: %v", pr.AssignedReviewers)
+	}
+	if pr.Status != "OPEN" || pr.CreatedAt == nil || pr.TeamName != "backend" {
+		t.Errorf("unexpected pr: %+v", pr)
+	}
+}
+
+func TestCreateNotFound(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-404",
+		PullRequestName: "Fail",
+		AuthorID:        "oops",
+	})
+	if pr != nil {
+		t.Errorf("must be nil PR")
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestCreateExists(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	req := model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Add search",
+		AuthorID:        "u1",
+	}
+
+	if _, err := repo.Create(context.Background(), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.Create(context.Background(), req); !errors.Is(err, model.ErrPrExists) {
+		t.Errorf("expected ErrPrExists, got: %v", err)
+	}
+}
+
+func TestCreateWithTargetTeam(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1002",
+		PullRequestName: "Fix layout",
+		AuthorID:        "u1",
+		TeamName:        "frontend",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.TeamName != "frontend" || len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u5" {
+		t.Errorf("unexpected pr: %+v", pr)
+	}
+}
+
+func TestCreateTargetTeamNotFound(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	_, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1002",
+		PullRequestName: "Fix layout",
+		AuthorID:        "u1",
+		TeamName:        "unknown",
+	})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestMergeIsIdempotent(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := repo.Create(ctx, model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Add search",
+		AuthorID:        "u1",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first, merged, err := repo.Merge(ctx, "pr-1001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !merged || first.Status != "MERGED" || first.MergedAt == nil || len(first.AssignedReviewers) != 2 {
+		t.Errorf("unexpected pr: %v %+v", merged, first)
+	}
+
+	second, merged, err := repo.Merge(ctx, "pr-1001")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged {
+		t.Error("expected a repeated merge to change nothing")
+	}
+	if second.MergedAt == nil || !second.MergedAt.Equal(*first.MergedAt) {
+		t.Errorf("mergedAt changed: %v -> %v", first.MergedAt, second.MergedAt)
+	}
+}
+
+func TestMergeNotFound(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	_, _, err := repo.Merge(context.Background(), "pr-404")
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestReassignSuccess(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`UPDATE users SET is_active = TRUE WHERE user_id = 'u4'`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	seed := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, replacedBy, err := repo.Reassign(ctx, "pr-1", "u2", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replacedBy == "u2" || replacedBy == "u1" || replacedBy == "u5" {
+		t.Errorf("unexpected replacement: %s", replacedBy)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != replacedBy {
+		t.Errorf("wrong reviewers after reassign: %v", pr.AssignedReviewers)
+	}
+}
+
+func TestReassignMerged(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 2, 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	_, _, err := repo.Reassign(context.Background(), "pr-1", "u2", "")
+	if !errors.Is(err, model.ErrPrMerged) {
+		t.Errorf("expected ErrPrMerged, got: %v", err)
+	}
+}
+
+func TestCreateSkipsInactiveMembership(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	if _, err := db.Exec(`UPDATE team_members SET is_active = FALSE WHERE team_id = 1 AND user_id = 'u3'`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Add search",
+		AuthorID:        "u1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u2" {
+		t.Errorf("wrong reviewers: %v", pr.AssignedReviewers)
+	}
+}
+
+func TestReassignDeletedTeamFallsBackToAuthorTeam(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		INSERT INTO teams (team_id, team_name) VALUES (3, 'legacy');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 3);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+		DELETE FROM teams WHERE team_id = 3;
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, replacedBy, err := repo.Reassign(context.Background(), "pr-1", "u2", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replacedBy != "u3" || pr.TeamName != "backend" {
+		t.Errorf("unexpected reassign result: %s %+v", replacedBy, pr)
+	}
+}
+
+func TestCreatePrefersOwners(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		INSERT INTO team_ownership_rules (team_id, position, pattern, owners) VALUES
+			(1, 1, '*', '@u2'),
+			(1, 2, '/web/', '@acme/frontend'),
+			(1, 3, 'vendor/', '');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Restyle",
+		AuthorID:        "u1",
+		ChangedFiles:    []string{"web/app.css", "vendor/lib.js"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pr.ReviewerMatches) != 2 {
+		t.Fatalf("expected 2 reviewers, got %+v", pr.ReviewerMatches)
+	}
+	owner := pr.ReviewerMatches[0]
+	if owner.UserID != "u5" || owner.MatchedRule == nil || owner.MatchedRule.Pattern != "/web/" {
+		t.Errorf("expected frontend owner first, got %+v", owner)
+	}
+	if pr.ReviewerMatches[1].MatchedRule != nil {
+		t.Errorf("expected pool reviewer second, got %+v", pr.ReviewerMatches[1])
+	}
+	if !contains(pr.AssignedReviewers, "u5") {
+		t.Errorf("wrong reviewers: %v", pr.AssignedReviewers)
+	}
+}
+
+func TestCreateCoversRequiredSkills(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		INSERT INTO user_skills (user_id, skill) VALUES
+			('u2', 'css'),
+			('u3', 'terraform');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Bump modules",
+		AuthorID:        "u1",
+		RequiredSkills:  []string{"terraform", "rust"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first := pr.ReviewerMatches[0]
+	if first.UserID != "u3" || len(first.MatchedSkills) != 1 || first.MatchedSkills[0] != "terraform" {
+		t.Errorf("expected the terraform reviewer first, got %+v", first)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Errorf("expected the pool to fill the second seat, got %v", pr.AssignedReviewers)
+	}
+	if len(pr.UncoveredSkills) != 1 || pr.UncoveredSkills[0] != "rust" {
+		t.Errorf("expected rust uncovered, got %v", pr.UncoveredSkills)
+	}
+}
+
+func TestCreateEnforcesSeniorRule(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		UPDATE teams SET min_senior_reviewers = 1 WHERE team_id = 1;
+		UPDATE users SET level = 'senior' WHERE user_id = 'u3';
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(ctx, model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Add search",
+		AuthorID:        "u1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !contains(pr.AssignedReviewers, "u3") {
+		t.Errorf("expected the senior among reviewers, got %v", pr.AssignedReviewers)
+	}
+
+	if _, err := db.Exec(`UPDATE users SET level = 'middle' WHERE user_id = 'u3'`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	_, err = repo.Create(ctx, model.PullRequestPayload{
+		PullRequestID:   "pr-1002",
+		PullRequestName: "Fix search",
+		AuthorID:        "u1",
+	})
+	if !errors.Is(err, model.ErrNoSenior) {
+		t.Errorf("expected ErrNoSenior, got: %v", err)
+	}
+}
+
+func TestReassignKeepsSeniorRule(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		UPDATE teams SET min_senior_reviewers = 1 WHERE team_id = 1;
+		UPDATE users SET is_active = TRUE WHERE user_id = 'u4';
+		UPDATE users SET level = 'senior' WHERE user_id IN ('u2', 'u4');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	_, replacedBy, err := repo.Reassign(ctx, "pr-1", "u2", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replacedBy != "u4" {
+		t.Errorf("expected the other senior, got %s", replacedBy)
+	}
+
+	if _, err := db.Exec(`UPDATE users SET level = 'junior' WHERE user_id = 'u2'`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	if _, _, err := repo.Reassign(ctx, "pr-1", "u4", ""); !errors.Is(err, model.ErrNoSenior) {
+		t.Errorf("expected ErrNoSenior, got: %v", err)
+	}
+}
+
+func TestCreateSkipsReviewersAtCapacity(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		UPDATE users SET max_open_reviews = 1 WHERE user_id = 'u2';
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u3', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Fix search",
+		AuthorID:        "u1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u3" {
+		t.Errorf("expected only u3 with spare capacity, got %v", pr.AssignedReviewers)
+	}
+}
+
+func TestCreateHonorsReviewerPreferences(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		UPDATE users SET is_active = TRUE WHERE user_id = 'u4';
+		INSERT INTO reviewer_preferences (author_id, reviewer_id, kind) VALUES
+			('u1', 'u2', 'blocked'),
+			('u1', 'u4', 'preferred');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(ctx, model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Add search",
+		AuthorID:        "u1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 || pr.AssignedReviewers[0] != "u4" || contains(pr.AssignedReviewers, "u2") {
+		t.Errorf("expected the preferred reviewer first and no blocked one, got %v", pr.AssignedReviewers)
+	}
+
+	seed = `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Fix search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u3');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	_, replacedBy, err := repo.Reassign(ctx, "pr-1", "u3", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replacedBy != "u4" {
+		t.Errorf("expected the preferred reviewer, got %s", replacedBy)
+	}
+}
+
+func TestCreateRotatesReviewers(t *testing.T) {
+	repo, db := newTestRepo(t)
+	repo.selection = selection.Config{Mode: selection.ModeRotate, HistoryWindow: 5}
+
+	seed := `
+		UPDATE users SET is_active = TRUE WHERE user_id = 'u4';
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES
+			('pr-1', 'Add search', 'u1', 1),
+			('pr-2', 'Fix search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES
+			('pr-1', 'u2'),
+			('pr-1', 'u3'),
+			('pr-2', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Tune search",
+		AuthorID:        "u1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 || pr.AssignedReviewers[0] != "u4" || pr.AssignedReviewers[1] != "u3" {
+		t.Errorf("expected the least recent reviewers, got %v", pr.AssignedReviewers)
+	}
+}
+
+func TestCreateFillsFromFallbackTeam(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		UPDATE users SET is_active = FALSE WHERE user_id = 'u3';
+		INSERT INTO team_fallbacks (team_id, position, fallback_team_id) VALUES (1, 1, 2);
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.Create(context.Background(), model.PullRequestPayload{
+		PullRequestID:   "pr-1001",
+		PullRequestName: "Add search",
+		AuthorID:        "u1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 || pr.AssignedReviewers[0] != "u2" || pr.AssignedReviewers[1] != "u5" {
+		t.Fatalf("wrong reviewers: %v", pr.AssignedReviewers)
+	}
+	if pr.ReviewerMatches[0].FallbackTeam != "" || pr.ReviewerMatches[1].FallbackTeam != "frontend" {
+		t.Errorf("unexpected matches: %+v %+v", pr.ReviewerMatches[0], pr.ReviewerMatches[1])
+	}
+}
+
+func TestReassignFromFallbackTeam(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		UPDATE users SET is_active = FALSE WHERE user_id = 'u3';
+		INSERT INTO team_fallbacks (team_id, position, fallback_team_id) VALUES (1, 1, 2);
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, replacedBy, err := repo.Reassign(context.Background(), "pr-1", "u2", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replacedBy != "u5" || len(pr.ReviewerMatches) != 1 || pr.ReviewerMatches[0].FallbackTeam != "frontend" {
+		t.Errorf("unexpected reassign result: %s %+v", replacedBy, pr.ReviewerMatches)
+	}
+}
+
+func TestReassignSkipsCoReviewer(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2'), ('pr-1', 'u3');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	// u3 is the only other active member but already reviews the pull request
+	if _, _, err := repo.Reassign(ctx, "pr-1", "u2", ""); !errors.Is(err, model.ErrNoCandidate) {
+		t.Fatalf("expected ErrNoCandidate, got: %v", err)
+	}
+	if _, _, err := repo.Reassign(ctx, "pr-1", "u2", "u3"); !errors.Is(err, model.ErrNoCandidate) {
+		t.Errorf("expected ErrNoCandidate for a co-reviewer nominee, got: %v", err)
+	}
+	if _, _, err := repo.Reassign(ctx, "pr-1", "u5", ""); !errors.Is(err, model.ErrNotAssigned) {
+		t.Errorf("expected ErrNotAssigned, got: %v", err)
+	}
+}
+
+func TestReassignToNominee(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		UPDATE users SET is_active = TRUE WHERE user_id = 'u4';
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	if _, _, err := repo.Reassign(ctx, "pr-1", "u2", "u1"); !errors.Is(err, model.ErrNoCandidate) {
+		t.Errorf("expected ErrNoCandidate for the author, got: %v", err)
+	}
+
+	pr, replacedBy, err := repo.Reassign(ctx, "pr-1", "u2", "u4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replacedBy != "u4" || len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u4" {
+		t.Errorf("unexpected reassign result: %s %v", replacedBy, pr.AssignedReviewers)
+	}
+}
+
+func TestAddAndRemoveReviewer(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, team_id) VALUES ('pr-1', 'Add search', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES ('pr-1', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	pr, err := repo.AddReviewer(ctx, "pr-1", "u5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 2 || pr.AssignedReviewers[1] != "u5" {
+		t.Errorf("wrong reviewers after add: %v", pr.AssignedReviewers)
+	}
+
+	for _, tc := range []struct {
+		reviewerID string
+		want       error
+	}{
+		{"u5", model.ErrAlreadyAssigned},
+		{"u1", model.ErrInvalidReviewer},
+		{"u4", model.ErrInvalidReviewer},
+		{"none", model.ErrNotFound},
+	} {
+		if _, err := repo.AddReviewer(ctx, "pr-1", tc.reviewerID); !errors.Is(err, tc.want) {
+			t.Errorf("%s: expected %v, got: %v", tc.reviewerID, tc.want, err)
+		}
+	}
+
+	pr, err = repo.RemoveReviewer(ctx, "pr-1", "u2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pr.AssignedReviewers) != 1 || pr.AssignedReviewers[0] != "u5" {
+		t.Errorf("wrong reviewers after remove: %v", pr.AssignedReviewers)
+	}
+	if _, err := repo.RemoveReviewer(ctx, "pr-1", "u2"); !errors.Is(err, model.ErrNotAssigned) {
+		t.Errorf("expected ErrNotAssigned, got: %v", err)
+	}
+
+	var actions string
+	if err := db.QueryRow(`SELECT GROUP_CONCAT(action) FROM (SELECT action FROM reviewer_history WHERE pull_request_id = 'pr-1' ORDER BY event_id)`).Scan(&actions); err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if actions != "added,removed" {
+		t.Errorf("unexpected history: %s", actions)
+	}
+
+	if _, err := db.Exec(`UPDATE pull_requests SET status_id = 2 WHERE pull_request_id = 'pr-1'`); err != nil {
+		t.Fatalf("failed to merge: %v", err)
+	}
+	if _, err := repo.AddReviewer(ctx, "pr-1", "u3"); !errors.Is(err, model.ErrPrMerged) {
+		t.Errorf("expected ErrPrMerged, got: %v", err)
+	}
+}
+
+func TestListPagesAndFilters(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	// pr-1 and pr-2 share a creation time written by CURRENT_TIMESTAMP's format
+	seed := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id, createdAt, mergedAt) VALUES
+			('pr-1', 'Add search', 'u1', 1, 1, '2025-01-01 10:00:00', NULL),
+			('pr-2', 'Fix layout', 'u5', 1, 2, '2025-01-01 10:00:00', NULL),
+			('pr-3', 'Bump deps', 'u1', 2, 1, '2025-01-02 10:00:00', '2025-01-03 10:00:00');
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES
+			('pr-1', 'u2'), ('pr-1', 'u3'), ('pr-3', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	if _, err := repo.Create(ctx, model.PullRequestPayload{PullRequestID: "pr-4", PullRequestName: "Add cache", AuthorID: "u1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	filter := model.PullRequestFilter{Sort: model.PullRequestSortCreated, Desc: true, Limit: 1}
+	for {
+		page, err := repo.List(ctx, filter)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, pr := range page.PullRequests {
+			got = append(got, pr.PullRequestID)
+		}
+		if page.Next == nil {
+			break
+		}
+		filter.After = page.Next
+	}
+	want := []string{"pr-4", "pr-3", "pr-2", "pr-1"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+
+	mergedFrom := time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name   string
+		filter model.PullRequestFilter
+		want   []string
+	}{
+		{"reviewer", model.PullRequestFilter{ReviewerID: "u2", Sort: model.PullRequestSortName}, []string{"pr-4", "pr-1", "pr-3"}},
+		{"team and status", model.PullRequestFilter{TeamName: "backend", Status: "OPEN", Sort: model.PullRequestSortName}, []string{"pr-4", "pr-1"}},
+		{"merged range", model.PullRequestFilter{MergedFrom: &mergedFrom}, []string{"pr-3"}},
+		{"author", model.PullRequestFilter{AuthorID: "u5"}, []string{"pr-2"}},
+	} {
+		page, err := repo.List(ctx, tc.filter)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		ids := make([]string, 0)
+		for _, pr := range page.PullRequests {
+			ids = append(ids, pr.PullRequestID)
+		}
+		if len(ids) != len(tc.want) || (len(ids) > 0 && ids[0] != tc.want[0]) || page.Next != nil {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, ids)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := repo.Get(ctx, "pr-1"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := repo.Create(ctx, model.PullRequestPayload{PullRequestID: "pr-1", PullRequestName: "Add search", AuthorID: "u1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pr, err := repo.Get(ctx, "pr-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.TeamName != "backend" || len(pr.AssignedReviewers) != 2 || pr.Status != "OPEN" {
+		t.Errorf("unexpected pr: %+v", pr)
+	}
+}
+
+func TestReviewSLATracking(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`UPDATE teams SET review_sla_minutes = 60 WHERE team_id = 1`); err != nil {
+		t.Fatalf("failed to set SLA: %v", err)
+	}
+	for _, id := range []string{"pr-1", "pr-2"} {
+		if _, err := repo.Create(ctx, model.PullRequestPayload{PullRequestID: id, PullRequestName: id, AuthorID: "u1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := repo.Create(ctx, model.PullRequestPayload{PullRequestID: "pr-3", PullRequestName: "pr-3", AuthorID: "u5"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := repo.SubmitReview(ctx, "pr-1", "u2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.SubmitReview(ctx, "pr-1", "u5"); !errors.Is(err, model.ErrNotAssigned) {
+		t.Errorf("expected ErrNotAssigned, got: %v", err)
+	}
+
+	// frontend has no SLA and u2 already reviewed pr-1
+	pending, err := repo.PendingReviews(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("expected 3 pending reviews, got %d", len(pending))
+	}
+	for _, p := range pending {
+		if p.SLA.TeamName != "backend" || p.SLA.Minutes != 60 || (p.PullRequestID == "pr-1" && p.ReviewerID == "u2") {
+			t.Errorf("unexpected pending review: %+v", p)
+		}
+	}
+
+	now := time.Now().UTC()
+	marked, err := repo.MarkOverdue(ctx, "pr-2", "u3", now)
+	if err != nil || !marked {
+		t.Fatalf("expected the review marked, got %v, %v", marked, err)
+	}
+	if marked, _ := repo.MarkOverdue(ctx, "pr-2", "u3", now); marked {
+		t.Error("a review must be marked overdue once")
+	}
+	if marked, _ := repo.MarkOverdue(ctx, "pr-1", "u2", now); marked {
+		t.Error("a reviewed seat must not be marked overdue")
+	}
+
+	overdue, err := repo.ListOverdue(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(overdue) != 1 || overdue[0].PullRequestID != "pr-2" || overdue[0].ReviewerID != "u3" || overdue[0].TeamName != "backend" {
+		t.Fatalf("unexpected overdue reviews: %+v", overdue)
+	}
+	if overdue, _ := repo.ListOverdue(ctx, "frontend"); len(overdue) != 0 {
+		t.Errorf("expected no overdue frontend reviews, got %d", len(overdue))
+	}
+
+	// the replacement starts with a fresh clock
+	if _, _, err := repo.Reassign(ctx, "pr-2", "u3", ""); !errors.Is(err, model.ErrNoCandidate) {
+		t.Fatalf("expected ErrNoCandidate, got: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE users SET is_active = TRUE WHERE user_id = 'u4'`); err != nil {
+		t.Fatalf("failed to activate u4: %v", err)
+	}
+	if _, _, err := repo.Reassign(ctx, "pr-2", "u3", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if overdue, _ := repo.ListOverdue(ctx, ""); len(overdue) != 0 {
+		t.Errorf("expected the reassigned seat to be on time, got %+v", overdue)
+	}
+}
+
+func TestCreatePrefersReviewersAtWork(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	// u6 works one hour a day that is not the current one, so they are off work
+	hour := time.Now().UTC().Hour()
+	start := (hour + 2) % 22
+	seed := `
+		INSERT INTO users (user_id, username, is_active, timezone, work_start, work_end) VALUES
+			('u6', 'Frank', TRUE, 'UTC', printf('%02d:00', ?), printf('%02d:00', ? + 1));
+		INSERT INTO team_members (team_id, user_id, is_active) VALUES (1, 'u6', TRUE);
+	`
+	if _, err := db.Exec(seed, start, start); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("pr-%d", i)
+		pr, err := repo.Create(ctx, model.PullRequestPayload{PullRequestID: id, PullRequestName: id, AuthorID: "u1"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if contains(pr.AssignedReviewers, "u6") {
+			t.Fatalf("expected reviewers at work to be picked, got %v", pr.AssignedReviewers)
+		}
+	}
+}
+
+func TestPendingReviewsCarryCalendars(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	setup := `
+		UPDATE teams SET review_sla_minutes = 60 WHERE team_id = 1;
+		UPDATE users SET timezone = 'Europe/Berlin', work_start = '09:00', work_end = '17:00' WHERE user_id = 'u2';
+		INSERT INTO team_holidays (team_id, holiday, name) VALUES (1, '2024-12-25', 'Christmas'), (1, '2024-12-26', '');
+	`
+	if _, err := db.Exec(setup); err != nil {
+		t.Fatalf("failed to set up: %v", err)
+	}
+	if _, err := repo.Create(ctx, model.PullRequestPayload{PullRequestID: "pr-1", PullRequestName: "pr-1", AuthorID: "u1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pending, err := repo.PendingReviews(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending reviews, got %d", len(pending))
+	}
+	for _, p := range pending {
+		if len(p.Holidays) != 2 {
+			t.Errorf("expected the team holidays, got %v", p.Holidays)
+		}
+		want := model.WorkSchedule{Timezone: "UTC"}
+		if p.ReviewerID == "u2" {
+			want = model.WorkSchedule{Timezone: "Europe/Berlin", WorkStart: "09:00", WorkEnd: "17:00"}
+		}
+		if p.Schedule != want {
+			t.Errorf("unexpected schedule of %s: %+v", p.ReviewerID, p.Schedule)
+		}
+	}
+}
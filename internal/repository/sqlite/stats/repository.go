@@ -0,0 +1,424 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	def "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"slices"
+	"strings"
+)
+
+var _ def.StatsRepository = (*repository)(nil)
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+// ReviewerStats counts the review load of every user, or of the team's
+// members when the filter names a team. Users without reviews are listed
+// with zero counts, so that idle reviewers show up too.
+func (r *repository) ReviewerStats(ctx context.Context, filter model.StatsFilter) ([]*model.ReviewerStats, error) {
+	var (
+		teamID        int
+		userConds     []string
+		userArgs      []any
+		seatConds     []string
+		seatArgs      []any
+		handoverConds = []string{"h.action IN ('reassigned', 'removed')"}
+		handoverArgs  []any
+	)
+	if filter.TeamName != "" {
+		var err error
+		if teamID, err = r.teamID(ctx, filter.TeamName); err != nil {
+			return nil, err
+		}
+		userConds = append(userConds, "u.user_id IN (SELECT tm.user_id FROM team_members tm WHERE tm.team_id = ?)")
+		userArgs = append(userArgs, teamID)
+		seatConds = append(seatConds, "p.team_id = ?")
+		seatArgs = append(seatArgs, teamID)
+		handoverConds = append(handoverConds, "p.team_id = ?")
+		handoverArgs = append(handoverArgs, teamID)
+	}
+	if filter.From != nil {
+		seatConds = append(seatConds, timestamp("rv.assigned_at")+" >= "+timestamp("?"))
+		seatArgs = append(seatArgs, filter.From.UTC())
+		handoverConds = append(handoverConds, timestamp("h.created_at")+" >= "+timestamp("?"))
+		handoverArgs = append(handoverArgs, filter.From.UTC())
+	}
+	if filter.To != nil {
+		seatConds = append(seatConds, timestamp("rv.assigned_at")+" < "+timestamp("?"))
+		seatArgs = append(seatArgs, filter.To.UTC())
+		handoverConds = append(handoverConds, timestamp("h.created_at")+" < "+timestamp("?"))
+		handoverArgs = append(handoverArgs, filter.To.UTC())
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			u.user_id,
+			u.username,
+			COALESCE(s.assigned, 0) + COALESCE(h.lost, 0),
+			COALESCE(s.open, 0),
+			COALESCE(s.completed, 0),
+			COALESCE(h.reassigned_away, 0)
+		FROM users u
+		LEFT JOIN (
+			SELECT
+				rv.reviewer_user_id AS user_id,
+				COUNT(*) AS assigned,
+				SUM(CASE WHEN p.status_id = 1 AND rv.reviewed_at IS NULL THEN 1 ELSE 0 END) AS open,
+				SUM(CASE WHEN rv.reviewed_at IS NOT NULL THEN 1 ELSE 0 END) AS completed
+			FROM pull_request_reviewers rv
+			JOIN pull_requests p ON p.pull_request_id = rv.pull_request_id
+			%s
+			GROUP BY rv.reviewer_user_id
+		) s ON s.user_id = u.user_id
+		LEFT JOIN (
+			SELECT
+				CASE WHEN h.action = 'reassigned' THEN h.replaced_user_id ELSE h.reviewer_user_id END AS user_id,
+				COUNT(*) AS lost,
+				SUM(CASE WHEN h.action = 'reassigned' THEN 1 ELSE 0 END) AS reassigned_away
+			FROM reviewer_history h
+			JOIN pull_requests p ON p.pull_request_id = h.pull_request_id
+			%s
+			GROUP BY 1
+		) h ON h.user_id = u.user_id
+		%s
+		ORDER BY u.user_id
+	`, where(seatConds), where(handoverConds), where(userConds))
+
+	args := append(append(seatArgs, handoverArgs...), userArgs...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reviewer stats: %v", err)
+	}
+	defer rows.Close()
+
+	stats := make([]*model.ReviewerStats, 0)
+	for rows.Next() {
+		s := &model.ReviewerStats{}
+		if err := rows.Scan(&s.UserID, &s.Username, &s.Assigned, &s.Open, &s.Completed, &s.ReassignedAway); err != nil {
+			return nil, fmt.Errorf("failed to scan reviewer stats: %v", err)
+		}
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reviewer stats: %v", err)
+	}
+	return stats, nil
+}
+
+// PullRequestTimes loads the milestones of the pull requests created in the
+// filter's range, optionally of one team only.
+func (r *repository) PullRequestTimes(ctx context.Context, filter model.StatsFilter) ([]*model.PullRequestTimes, error) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	if filter.TeamName != "" {
+		teamID, err := r.teamID(ctx, filter.TeamName)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, "pr.team_id = ?")
+		args = append(args, teamID)
+	}
+	if filter.From != nil {
+		conds = append(conds, timestamp("pr.createdAt")+" >= "+timestamp("?"))
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conds = append(conds, timestamp("pr.createdAt")+" < "+timestamp("?"))
+		args = append(args, filter.To.UTC())
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			pr.pull_request_id,
+			COALESCE(t.team_name, ''),
+			pr.author_id,
+			pr.createdAt,
+			(
+				SELECT rv.reviewed_at
+				FROM pull_request_reviewers rv
+				WHERE rv.pull_request_id = pr.pull_request_id AND rv.reviewed_at IS NOT NULL
+				ORDER BY %s
+				LIMIT 1
+			),
+			pr.mergedAt
+		FROM pull_requests pr
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		%s
+		ORDER BY %s, pr.pull_request_id
+	`, timestamp("rv.reviewed_at"), where(conds), timestamp("pr.createdAt"))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request times: %v", err)
+	}
+	defer rows.Close()
+
+	times := make([]*model.PullRequestTimes, 0)
+	for rows.Next() {
+		var (
+			t                     = &model.PullRequestTimes{}
+			firstReview, mergedAt sql.NullTime
+		)
+		if err := rows.Scan(&t.PullRequestID, &t.TeamName, &t.AuthorID, &t.CreatedAt, &firstReview, &mergedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pull request times: %v", err)
+		}
+		if firstReview.Valid {
+			t.FirstReviewAt = &firstReview.Time
+		}
+		if mergedAt.Valid {
+			t.MergedAt = &mergedAt.Time
+		}
+		times = append(times, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pull request times: %v", err)
+	}
+	return times, nil
+}
+
+// exportPageSize is how many rows an export reads at a time. Every page is
+// read in full and its rows released before any is emitted, so that a slow
+// consumer does not hold the only connection.
+const exportPageSize = 500
+
+// ExportAssignments hands the reviewer seats assigned in the filter's range
+// to emit one by one, in the order they were assigned, reading them a page
+// at a time.
+func (r *repository) ExportAssignments(ctx context.Context, filter model.StatsFilter, emit func(*model.AssignmentExport) error) error {
+	var (
+		conds []string
+		args  []any
+	)
+
+	if filter.TeamName != "" {
+		teamID, err := r.teamID(ctx, filter.TeamName)
+		if err != nil {
+			return err
+		}
+		conds = append(conds, "pr.team_id = ?")
+		args = append(args, teamID)
+	}
+	if filter.From != nil {
+		conds = append(conds, timestamp("rv.assigned_at")+" >= "+timestamp("?"))
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conds = append(conds, timestamp("rv.assigned_at")+" < "+timestamp("?"))
+		args = append(args, filter.To.UTC())
+	}
+
+	var last *model.AssignmentExport
+	for {
+		pageConds, pageArgs := slices.Clone(conds), slices.Clone(args)
+		if last != nil {
+			pageConds = append(pageConds, "("+timestamp("rv.assigned_at")+", rv.pull_request_id, rv.reviewer_user_id) > ("+timestamp("?")+", ?, ?)")
+			pageArgs = append(pageArgs, last.AssignedAt.UTC(), last.PullRequestID, last.ReviewerID)
+		}
+		page, err := r.assignmentPage(ctx, pageConds, pageArgs)
+		if err != nil {
+			return err
+		}
+		for _, a := range page {
+			if err := emit(a); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		last = page[len(page)-1]
+	}
+}
+
+func (r *repository) assignmentPage(ctx context.Context, conds []string, args []any) ([]*model.AssignmentExport, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			COALESCE(t.team_name, ''),
+			pr.author_id,
+			s.status_name,
+			rv.reviewer_user_id,
+			rv.assigned_at,
+			rv.reviewed_at,
+			rv.overdue_at
+		FROM pull_request_reviewers rv
+		JOIN pull_requests pr ON pr.pull_request_id = rv.pull_request_id
+		JOIN pull_request_statuses s ON s.status_id = pr.status_id
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		%s
+		ORDER BY %s, rv.pull_request_id, rv.reviewer_user_id
+		LIMIT %d
+	`, where(conds), timestamp("rv.assigned_at"), exportPageSize)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export assignments: %v", err)
+	}
+	defer rows.Close()
+
+	page := make([]*model.AssignmentExport, 0, exportPageSize)
+	for rows.Next() {
+		var (
+			a                     = &model.AssignmentExport{}
+			reviewedAt, overdueAt sql.NullTime
+		)
+		if err := rows.Scan(&a.PullRequestID, &a.PullRequestName, &a.TeamName, &a.AuthorID, &a.Status,
+			&a.ReviewerID, &a.AssignedAt, &reviewedAt, &overdueAt); err != nil {
+			return nil, fmt.Errorf("failed to scan assignment: %v", err)
+		}
+		if reviewedAt.Valid {
+			a.ReviewedAt = &reviewedAt.Time
+		}
+		if overdueAt.Valid {
+			a.OverdueAt = &overdueAt.Time
+		}
+		page = append(page, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate assignments: %v", err)
+	}
+	return page, nil
+}
+
+// ExportPullRequests hands the pull requests created in the filter's range
+// to emit one by one, the oldest first, reading them a page at a time.
+func (r *repository) ExportPullRequests(ctx context.Context, filter model.StatsFilter, emit func(*model.PullRequestExport) error) error {
+	var (
+		conds []string
+		args  []any
+	)
+
+	if filter.TeamName != "" {
+		teamID, err := r.teamID(ctx, filter.TeamName)
+		if err != nil {
+			return err
+		}
+		conds = append(conds, "pr.team_id = ?")
+		args = append(args, teamID)
+	}
+	if filter.From != nil {
+		conds = append(conds, timestamp("pr.createdAt")+" >= "+timestamp("?"))
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conds = append(conds, timestamp("pr.createdAt")+" < "+timestamp("?"))
+		args = append(args, filter.To.UTC())
+	}
+
+	var last *model.PullRequestExport
+	for {
+		pageConds, pageArgs := slices.Clone(conds), slices.Clone(args)
+		if last != nil {
+			pageConds = append(pageConds, "("+timestamp("pr.createdAt")+", pr.pull_request_id) > ("+timestamp("?")+", ?)")
+			pageArgs = append(pageArgs, last.CreatedAt.UTC(), last.PullRequestID)
+		}
+		page, err := r.pullRequestPage(ctx, pageConds, pageArgs)
+		if err != nil {
+			return err
+		}
+		for _, p := range page {
+			if err := emit(p); err != nil {
+				return err
+			}
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		last = page[len(page)-1]
+	}
+}
+
+func (r *repository) pullRequestPage(ctx context.Context, conds []string, args []any) ([]*model.PullRequestExport, error) {
+	// SQLite has no arrays, so the reviewers come as a list in assignment order
+	query := fmt.Sprintf(`
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			COALESCE(t.team_name, ''),
+			pr.author_id,
+			s.status_name,
+			pr.createdAt,
+			pr.mergedAt,
+			(
+				SELECT GROUP_CONCAT(reviewer_user_id)
+				FROM (
+					SELECT rv.reviewer_user_id
+					FROM pull_request_reviewers rv
+					WHERE rv.pull_request_id = pr.pull_request_id
+					ORDER BY %s, rv.reviewer_user_id
+				)
+			)
+		FROM pull_requests pr
+		JOIN pull_request_statuses s ON s.status_id = pr.status_id
+		LEFT JOIN teams t ON t.team_id = pr.team_id
+		%s
+		ORDER BY %s, pr.pull_request_id
+		LIMIT %d
+	`, timestamp("rv.assigned_at"), where(conds), timestamp("pr.createdAt"), exportPageSize)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export pull requests: %v", err)
+	}
+	defer rows.Close()
+
+	page := make([]*model.PullRequestExport, 0, exportPageSize)
+	for rows.Next() {
+		var (
+			p         = &model.PullRequestExport{Reviewers: []string{}}
+			mergedAt  sql.NullTime
+			reviewers sql.NullString
+		)
+		if err := rows.Scan(&p.PullRequestID, &p.PullRequestName, &p.TeamName, &p.AuthorID, &p.Status,
+			&p.CreatedAt, &mergedAt, &reviewers); err != nil {
+			return nil, fmt.Errorf("failed to scan pull request: %v", err)
+		}
+		if mergedAt.Valid {
+			p.MergedAt = &mergedAt.Time
+		}
+		if reviewers.Valid {
+			p.Reviewers = strings.Split(reviewers.String, ",")
+		}
+		page = append(page, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pull requests: %v", err)
+	}
+	return page, nil
+}
+
+func (r *repository) teamID(ctx context.Context, teamName string) (int, error) {
+	var teamID int
+	err := r.db.QueryRowContext(ctx, "SELECT team_id FROM teams WHERE team_name = ?", teamName).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		return 0, model.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get team: %v", err)
+	}
+	return teamID, nil
+}
+
+// timestamp normalizes a stored or bound time for comparison.
+func timestamp(expr string) string {
+	return "strftime('%Y-%m-%d %H:%M:%f', " + expr + ")"
+}
+
+func where(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
@@ -0,0 +1,232 @@
+package stats
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/database"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func newTestRepo(t *testing.T) (*repository, *sql.DB) {
+	t.Helper()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	seed := `
+		INSERT INTO teams (team_id, team_name) VALUES (1, 'backend'), (2, 'frontend');
+		INSERT INTO users (user_id, username) VALUES ('u1', 'Alice'), ('u2', 'Bob'), ('u3', 'Carol'), ('u4', 'Dan');
+		INSERT INTO team_members (team_id, user_id) VALUES (1, 'u1'), (1, 'u2'), (1, 'u3'), (2, 'u4');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id) VALUES
+			('pr-1', 'Add search', 'u1', 1, 1),
+			('pr-2', 'Add readme', 'u1', 2, 1),
+			('pr-3', 'Fix login', 'u4', 1, 1),
+			('pr-4', 'Restyle', 'u4', 1, 2);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id, assigned_at, reviewed_at) VALUES
+			('pr-1', 'u2', '2024-03-01 10:00:00', NULL),
+			('pr-2', 'u2', '2024-03-02 10:00:00', NULL),
+			('pr-3', 'u2', '2024-03-03 10:00:00', '2024-03-03 12:00:00'),
+			('pr-3', 'u3', '2024-03-10 10:00:00', NULL),
+			('pr-4', 'u2', '2024-03-04 10:00:00', NULL);
+		INSERT INTO reviewer_history (pull_request_id, reviewer_user_id, action, replaced_user_id, created_at) VALUES
+			('pr-3', 'u3', 'reassigned', 'u1', '2024-03-10 10:00:00'),
+			('pr-4', 'u4', 'removed', NULL, '2024-03-05 10:00:00');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	return &repository{db: db}, db
+}
+
+func TestReviewerStats(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	stats, err := repo.ReviewerStats(context.Background(), model.StatsFilter{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	want := []model.ReviewerStats{
+		// the seats u1 and u4 lost still count as assigned to them
+		{UserID: "u1", Username: "Alice", Assigned: 1, ReassignedAway: 1},
+		// pr-2 was merged without Bob's review
+		{UserID: "u2", Username: "Bob", Assigned: 4, Open: 2, Completed: 1},
+		{UserID: "u3", Username: "Carol", Assigned: 1, Open: 1},
+		{UserID: "u4", Username: "Dan", Assigned: 1},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("expected %d reviewers, got %d", len(want), len(stats))
+	}
+	for i := range want {
+		if *stats[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], *stats[i])
+		}
+	}
+}
+
+func TestReviewerStatsByTeamAndRange(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	from := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	stats, err := repo.ReviewerStats(context.Background(), model.StatsFilter{TeamName: "backend", From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(stats) != 3 {
+		t.Fatalf("expected the backend members only, got %d", len(stats))
+	}
+	if bob := stats[1]; bob.Assigned != 2 || bob.Open != 0 || bob.Completed != 1 {
+		t.Errorf("unexpected stats of Bob: %+v", bob)
+	}
+	if stats[0].Assigned != 0 || stats[0].ReassignedAway != 0 || stats[2].Assigned != 0 {
+		t.Errorf("expected changes outside the range left out, got %+v and %+v", stats[0], stats[2])
+	}
+
+	if _, err := repo.ReviewerStats(context.Background(), model.StatsFilter{TeamName: "none"}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestPullRequestTimes(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	seed := `
+		UPDATE pull_requests SET createdAt = '2024-03-01 09:00:00';
+		UPDATE pull_requests SET mergedAt = '2024-03-02 09:00:00' WHERE pull_request_id = 'pr-2';
+		UPDATE pull_request_reviewers SET reviewed_at = '2024-03-01 11:00:00' WHERE pull_request_id = 'pr-3' AND reviewer_user_id = 'u3';
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	times, err := repo.PullRequestTimes(context.Background(), model.StatsFilter{TeamName: "backend", From: &from})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(times) != 3 {
+		t.Fatalf("expected the backend pull requests, got %d", len(times))
+	}
+	if pr := times[1]; pr.PullRequestID != "pr-2" || pr.FirstReviewAt != nil || pr.MergedAt == nil || pr.MergedAt.Sub(pr.CreatedAt) != 24*time.Hour {
+		t.Errorf("unexpected times of pr-2: %+v", pr)
+	}
+	if pr := times[2]; pr.FirstReviewAt == nil || pr.FirstReviewAt.Sub(pr.CreatedAt) != 2*time.Hour {
+		t.Errorf("expected the earliest review of pr-3, got %+v", pr)
+	}
+
+	to := from
+	if times, _ := repo.PullRequestTimes(context.Background(), model.StatsFilter{To: &to}); len(times) != 0 {
+		t.Errorf("expected no pull requests before the range, got %d", len(times))
+	}
+}
+
+func TestExportAssignments(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	from := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	var got []*model.AssignmentExport
+	err := repo.ExportAssignments(context.Background(), model.StatsFilter{TeamName: "backend", From: &from, To: &to},
+		func(a *model.AssignmentExport) error {
+			got = append(got, a)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(got) != 2 || got[0].PullRequestID != "pr-2" || got[1].PullRequestID != "pr-3" {
+		t.Fatalf("unexpected assignments: %+v", got)
+	}
+	if a := got[0]; a.Status != "MERGED" || a.TeamName != "backend" || a.ReviewedAt != nil {
+		t.Errorf("unexpected assignment of pr-2: %+v", a)
+	}
+	if a := got[1]; a.ReviewedAt == nil || a.ReviewedAt.Sub(a.AssignedAt) != 2*time.Hour {
+		t.Errorf("unexpected assignment of pr-3: %+v", a)
+	}
+}
+
+func TestExportPullRequests(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	var got []*model.PullRequestExport
+	err := repo.ExportPullRequests(context.Background(), model.StatsFilter{TeamName: "backend"},
+		func(p *model.PullRequestExport) error {
+			got = append(got, p)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected the backend pull requests, got %d", len(got))
+	}
+	if pr := got[2]; pr.PullRequestID != "pr-3" || len(pr.Reviewers) != 2 || pr.Reviewers[0] != "u2" || pr.Reviewers[1] != "u3" {
+		t.Errorf("expected the reviewers of pr-3 in assignment order, got %+v", pr)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.ExportPullRequests(context.Background(), model.StatsFilter{}, func(*model.PullRequestExport) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected the export to stop at the first error, got %v after %d rows", err, calls)
+	}
+}
+
+func TestExportPagesThroughTies(t *testing.T) {
+	repo, db := newTestRepo(t)
+
+	// more than two pages of pull requests and seats created at the same time
+	seed := `
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1200)
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id, createdAt)
+		SELECT printf('bulk-%04d', i), 'Bulk', 'u1', 1, 2, '2024-05-01 10:00:00' FROM n;
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id, assigned_at)
+		SELECT pull_request_id, 'u4', '2024-05-01 10:00:00' FROM pull_requests WHERE pull_request_id LIKE 'bulk-%';
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	err := repo.ExportPullRequests(context.Background(), model.StatsFilter{TeamName: "frontend"}, func(p *model.PullRequestExport) error {
+		if seen[p.PullRequestID] {
+			t.Errorf("pull request %s exported twice", p.PullRequestID)
+		}
+		seen[p.PullRequestID] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	// pr-4 and the bulk ones
+	if len(seen) != 1201 {
+		t.Errorf("expected 1201 pull requests, got %d", len(seen))
+	}
+
+	var assignments int
+	err = repo.ExportAssignments(context.Background(), model.StatsFilter{TeamName: "frontend"}, func(*model.AssignmentExport) error {
+		assignments++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if assignments != 1201 {
+		t.Errorf("expected 1201 assignments, got %d", assignments)
+	}
+}
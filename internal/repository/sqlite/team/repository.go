@@ -0,0 +1,944 @@
+package team
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	def "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/roster"
+	"sort"
+	"strings"
+	"time"
+)
+
+var _ def.TeamRepository = (*repository)(nil)
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Add(ctx context.Context, team *model.Team) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	// SQLite reports a skipped DO NOTHING insert as zero affected rows, which
+	// is simpler to check than an empty RETURNING set.
+	createTeamQuery := `
+		INSERT INTO teams (team_name, min_senior_reviewers)
+		VALUES (?, ?)
+		ON CONFLICT (team_name) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, createTeamQuery, team.TeamName, team.MinSeniorReviewers)
+	if err != nil {
+		return fmt.Errorf("failed to create team: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return model.ErrTeamExists
+	}
+
+	teamID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get team id: %v", err)
+	}
+
+	if err := upsertMembers(ctx, tx, teamID, team.Members); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get returns the team with its members. A team without members is returned
+// with an empty roster; only an unknown team is reported as not found.
+func (r *repository) Get(ctx context.Context, teamName string) (*model.Team, error) {
+	query := `
+		SELECT
+			t.min_senior_reviewers,
+			u.user_id,
+			u.username,
+			tm.is_active AND u.is_active,
+			(SELECT GROUP_CONCAT(s.skill) FROM user_skills s WHERE s.user_id = u.user_id),
+			COALESCE(u.level, '')
+		FROM teams t
+		LEFT JOIN team_members tm
+			ON tm.team_id = t.team_id
+		LEFT JOIN users u
+			ON u.user_id = tm.user_id
+		WHERE t.team_name = ?
+		ORDER BY u.user_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %v", err)
+	}
+	defer rows.Close()
+
+	var team *model.Team
+	for rows.Next() {
+		var (
+			minSeniors       int
+			userID, username sql.NullString
+			isActive         sql.NullBool
+			skills           sql.NullString
+			level            string
+		)
+		if err := rows.Scan(&minSeniors, &userID, &username, &isActive, &skills, &level); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+
+		if team == nil {
+			team = &model.Team{
+				TeamName:           teamName,
+				Members:            make([]*model.TeamMember, 0),
+				MinSeniorReviewers: minSeniors,
+			}
+		}
+		if userID.Valid {
+			team.Members = append(team.Members, &model.TeamMember{
+				UserID:   userID.String,
+				Username: username.String,
+				IsActive: isActive.Bool,
+				Skills:   splitSkills(skills),
+				Level:    level,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %v", err)
+	}
+
+	if team == nil {
+		return nil, model.ErrNotFound
+	}
+	return team, nil
+}
+
+func (r *repository) List(ctx context.Context) ([]*model.Team, error) {
+	query := `
+		SELECT
+			t.team_name,
+			t.min_senior_reviewers,
+			u.user_id,
+			u.username,
+			tm.is_active AND u.is_active,
+			(SELECT GROUP_CONCAT(s.skill) FROM user_skills s WHERE s.user_id = u.user_id),
+			COALESCE(u.level, '')
+		FROM teams t
+		LEFT JOIN team_members tm
+			ON tm.team_id = t.team_id
+		LEFT JOIN users u
+			ON u.user_id = tm.user_id
+		ORDER BY t.team_name, u.user_id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %v", err)
+	}
+	defer rows.Close()
+
+	teams := make([]*model.Team, 0)
+	for rows.Next() {
+		var (
+			teamName         string
+			minSeniors       int
+			userID, username sql.NullString
+			isActive         sql.NullBool
+			skills           sql.NullString
+			level            string
+		)
+		if err := rows.Scan(&teamName, &minSeniors, &userID, &username, &isActive, &skills, &level); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %v", err)
+		}
+
+		if len(teams) == 0 || teams[len(teams)-1].TeamName != teamName {
+			teams = append(teams, &model.Team{
+				TeamName:           teamName,
+				Members:            make([]*model.TeamMember, 0),
+				MinSeniorReviewers: minSeniors,
+			})
+		}
+		if userID.Valid {
+			team := teams[len(teams)-1]
+			team.Members = append(team.Members, &model.TeamMember{
+				UserID:   userID.String,
+				Username: username.String,
+				IsActive: isActive.Bool,
+				Skills:   splitSkills(skills),
+				Level:    level,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate teams: %v", err)
+	}
+
+	return teams, nil
+}
+
+func (r *repository) Update(ctx context.Context, update *model.TeamUpdate) ([]*model.ReleasedReview, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := getTeamID(ctx, tx, update.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.NewTeamName != "" && update.NewTeamName != update.TeamName {
+		renameTeamQuery := `
+			UPDATE OR IGNORE teams
+			SET team_name = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE team_id = ?
+		`
+		result, err := tx.ExecContext(ctx, renameTeamQuery, update.NewTeamName, teamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rename team: %v", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return nil, model.ErrTeamExists
+		}
+	}
+
+	if update.MinSeniorReviewers != nil {
+		setRuleQuery := `
+			UPDATE teams
+			SET min_senior_reviewers = ?, updated_at = CURRENT_TIMESTAMP
+			WHERE team_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, setRuleQuery, *update.MinSeniorReviewers, teamID); err != nil {
+			return nil, fmt.Errorf("failed to update seniority rule: %v", err)
+		}
+	}
+
+	if err := upsertMembers(ctx, tx, teamID, update.AddMembers); err != nil {
+		return nil, err
+	}
+
+	released := make([]*model.ReleasedReview, 0)
+	removeQuery := `
+		DELETE FROM team_members
+		WHERE team_id = ? AND user_id = ?
+		RETURNING user_id
+	`
+	for _, userID := range update.RemoveMembers {
+		removed, err := removeMembers(ctx, tx, removeQuery, teamID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		rr, err := releaseOpenReviews(ctx, tx, teamID, removed)
+		if err != nil {
+			return nil, err
+		}
+		released = append(released, rr...)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return released, nil
+}
+
+func (r *repository) Delete(ctx context.Context, teamName string) ([]*model.ReleasedReview, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := getTeamID(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	removeQuery := `
+		DELETE FROM team_members
+		WHERE team_id = ?
+		RETURNING user_id
+	`
+	removed, err := removeMembers(ctx, tx, removeQuery, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := releaseOpenReviews(ctx, tx, teamID, removed)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteTeamQuery := `
+		DELETE FROM teams
+		WHERE team_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, deleteTeamQuery, teamID); err != nil {
+		return nil, fmt.Errorf("failed to delete team: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return released, nil
+}
+
+// SetOwnershipRules replaces the team's ownership rules as a whole.
+func (r *repository) SetOwnershipRules(ctx context.Context, teamName string, rules []*model.OwnershipRule) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := getTeamID(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+
+	clearRulesQuery := `
+		DELETE FROM team_ownership_rules
+		WHERE team_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearRulesQuery, teamID); err != nil {
+		return fmt.Errorf("failed to clear ownership rules: %v", err)
+	}
+
+	addRuleQuery := `
+		INSERT INTO team_ownership_rules (team_id, position, pattern, owners)
+		VALUES (?, ?, ?, ?)
+	`
+	for _, rule := range rules {
+		_, err := tx.ExecContext(ctx, addRuleQuery,
+			teamID,
+			rule.Line,
+			rule.Pattern,
+			strings.Join(rule.Owners, " "))
+		if err != nil {
+			return fmt.Errorf("failed to add ownership rule: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+func (r *repository) GetOwnershipRules(ctx context.Context, teamName string) ([]*model.OwnershipRule, error) {
+	query := `
+		SELECT
+			r.position,
+			r.pattern,
+			r.owners
+		FROM teams t
+		LEFT JOIN team_ownership_rules r
+			ON r.team_id = t.team_id
+		WHERE t.team_name = ?
+		ORDER BY r.position
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ownership rules: %v", err)
+	}
+	defer rows.Close()
+
+	found := false
+	rules := make([]*model.OwnershipRule, 0)
+	for rows.Next() {
+		var (
+			line            sql.NullInt64
+			pattern, owners sql.NullString
+		)
+		if err := rows.Scan(&line, &pattern, &owners); err != nil {
+			return nil, fmt.Errorf("failed to scan ownership rule: %v", err)
+		}
+		found = true
+		if line.Valid {
+			rules = append(rules, &model.OwnershipRule{
+				Line:    int(line.Int64),
+				Pattern: pattern.String,
+				Owners:  strings.Fields(owners.String),
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ownership rules: %v", err)
+	}
+
+	if !found {
+		return nil, model.ErrNotFound
+	}
+	return rules, nil
+}
+
+// SetFallbackTeams replaces the chain of teams that lend reviewers when the
+// team itself cannot fill the reviewer seats. Unknown teams are reported as
+// not found.
+func (r *repository) SetFallbackTeams(ctx context.Context, teamName string, fallbacks []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := getTeamID(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+
+	clearFallbacksQuery := `
+		DELETE FROM team_fallbacks
+		WHERE team_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearFallbacksQuery, teamID); err != nil {
+		return fmt.Errorf("failed to clear fallback teams: %v", err)
+	}
+
+	addFallbackQuery := `
+		INSERT INTO team_fallbacks (team_id, position, fallback_team_id)
+		SELECT ?, ?, team_id
+		FROM teams
+		WHERE team_name = ?
+	`
+	for i, fallback := range fallbacks {
+		result, err := tx.ExecContext(ctx, addFallbackQuery, teamID, i+1, fallback)
+		if err != nil {
+			return fmt.Errorf("failed to add fallback team: %v", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return model.ErrNotFound
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+func (r *repository) GetFallbackTeams(ctx context.Context, teamName string) ([]string, error) {
+	query := `
+		SELECT f.team_name
+		FROM teams t
+		LEFT JOIN team_fallbacks tf
+			ON tf.team_id = t.team_id
+		LEFT JOIN teams f
+			ON f.team_id = tf.fallback_team_id
+		WHERE t.team_name = ?
+		ORDER BY tf.position
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fallback teams: %v", err)
+	}
+	defer rows.Close()
+
+	found := false
+	fallbacks := make([]string, 0)
+	for rows.Next() {
+		var fallback sql.NullString
+		if err := rows.Scan(&fallback); err != nil {
+			return nil, fmt.Errorf("failed to scan fallback team: %v", err)
+		}
+		found = true
+		if fallback.Valid {
+			fallbacks = append(fallbacks, fallback.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fallback teams: %v", err)
+	}
+
+	if !found {
+		return nil, model.ErrNotFound
+	}
+	return fallbacks, nil
+}
+
+// SetReviewSLA stores the team's review SLA. Zero minutes turn it off.
+func (r *repository) SetReviewSLA(ctx context.Context, sla *model.ReviewSLA) error {
+	query := `
+		UPDATE teams
+		SET review_sla_minutes = NULLIF(?, 0), sla_auto_reassign = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE team_name = ?
+	`
+	result, err := r.db.ExecContext(ctx, query, sla.Minutes, sla.AutoReassign, sla.TeamName)
+	if err != nil {
+		return fmt.Errorf("failed to set review sla: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetReviewSLA(ctx context.Context, teamName string) (*model.ReviewSLA, error) {
+	query := `
+		SELECT COALESCE(review_sla_minutes, 0), sla_auto_reassign
+		FROM teams
+		WHERE team_name = ?
+	`
+	sla := &model.ReviewSLA{TeamName: teamName}
+	err := r.db.QueryRowContext(ctx, query, teamName).Scan(&sla.Minutes, &sla.AutoReassign)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review sla: %v", err)
+	}
+	return sla, nil
+}
+
+// SetChat points the team's messages at an incoming webhook. An empty URL
+// stops them.
+func (r *repository) SetChat(ctx context.Context, chat *model.TeamChat) error {
+	query := `
+		UPDATE teams
+		SET chat_webhook_url = NULLIF(?, ''), chat_direct_messages = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE team_name = ?
+	`
+	result, err := r.db.ExecContext(ctx, query, chat.WebhookURL, chat.DirectMessages, chat.TeamName)
+	if err != nil {
+		return fmt.Errorf("failed to set team chat: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetChat(ctx context.Context, teamName string) (*model.TeamChat, error) {
+	query := `
+		SELECT COALESCE(chat_webhook_url, ''), chat_direct_messages
+		FROM teams
+		WHERE team_name = ?
+	`
+	chat := &model.TeamChat{TeamName: teamName}
+	err := r.db.QueryRowContext(ctx, query, teamName).Scan(&chat.WebhookURL, &chat.DirectMessages)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team chat: %v", err)
+	}
+	return chat, nil
+}
+
+// SetHolidays replaces the team's holiday calendar.
+func (r *repository) SetHolidays(ctx context.Context, holidays *model.TeamHolidays) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := getTeamID(ctx, tx, holidays.TeamName)
+	if err != nil {
+		return err
+	}
+
+	clearHolidaysQuery := `
+		DELETE FROM team_holidays
+		WHERE team_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearHolidaysQuery, teamID); err != nil {
+		return fmt.Errorf("failed to clear holidays: %v", err)
+	}
+
+	addHolidayQuery := `
+		INSERT INTO team_holidays (team_id, holiday, name)
+		VALUES (?, ?, ?)
+		ON CONFLICT (team_id, holiday)
+		DO UPDATE SET name = excluded.name
+	`
+	for _, holiday := range holidays.Holidays {
+		if _, err := tx.ExecContext(ctx, addHolidayQuery, teamID, holiday.Date, holiday.Name); err != nil {
+			return fmt.Errorf("failed to add holiday: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+func (r *repository) GetHolidays(ctx context.Context, teamName string) (*model.TeamHolidays, error) {
+	query := `
+		SELECT h.holiday, h.name
+		FROM teams t
+		LEFT JOIN team_holidays h
+			ON h.team_id = t.team_id
+		WHERE t.team_name = ?
+		ORDER BY h.holiday
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holidays: %v", err)
+	}
+	defer rows.Close()
+
+	var holidays *model.TeamHolidays
+	for rows.Next() {
+		var date, name sql.NullString
+		if err := rows.Scan(&date, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %v", err)
+		}
+		if holidays == nil {
+			holidays = &model.TeamHolidays{TeamName: teamName, Holidays: make([]*model.Holiday, 0)}
+		}
+		if date.Valid {
+			holidays.Holidays = append(holidays.Holidays, &model.Holiday{Date: date.String, Name: name.String})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate holidays: %v", err)
+	}
+
+	if holidays == nil {
+		return nil, model.ErrNotFound
+	}
+	return holidays, nil
+}
+
+// Import replaces the members of the roster's teams in one transaction,
+// creating the teams that do not exist yet and changing the seniority rules
+// the roster sets. Users dropped from a team lose their reviews on its open
+// pull requests; other teams are left untouched. The diff is taken inside
+// the transaction, so it is exactly what the import changes. With dryRun the
+// transaction is rolled back, and the result only previews the import.
+func (r *repository) Import(ctx context.Context, teams []*model.RosterTeam, dryRun bool) (*model.RosterDiff, []*model.ReleasedReview, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	current := make([]*model.Team, 0, len(teams))
+	teamIDs := make(map[string]int64, len(teams))
+	rules := make(map[string]int, len(teams))
+	for _, t := range teams {
+		team, teamID, err := getRosterTeam(ctx, tx, t.TeamName)
+		if errors.Is(err, model.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		current = append(current, team)
+		teamIDs[t.TeamName] = teamID
+		rules[t.TeamName] = team.MinSeniorReviewers
+	}
+	diff := roster.Diff(current, teams)
+
+	createTeamQuery := `
+		INSERT INTO teams (team_name, min_senior_reviewers)
+		VALUES (?, ?)
+	`
+	setRuleQuery := `
+		UPDATE teams
+		SET min_senior_reviewers = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE team_id = ?
+	`
+	released := make([]*model.ReleasedReview, 0)
+	for _, team := range teams {
+		teamID, exists := teamIDs[team.TeamName]
+		switch {
+		case !exists:
+			minSeniors := 0
+			if team.MinSeniorReviewers != nil {
+				minSeniors = *team.MinSeniorReviewers
+			}
+			result, err := tx.ExecContext(ctx, createTeamQuery, team.TeamName, minSeniors)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create team: %v", err)
+			}
+			if teamID, err = result.LastInsertId(); err != nil {
+				return nil, nil, fmt.Errorf("failed to get team id: %v", err)
+			}
+		case team.MinSeniorReviewers != nil && *team.MinSeniorReviewers != rules[team.TeamName]:
+			if _, err := tx.ExecContext(ctx, setRuleQuery, *team.MinSeniorReviewers, teamID); err != nil {
+				return nil, nil, fmt.Errorf("failed to update seniority rule: %v", err)
+			}
+		}
+
+		if err := upsertMembers(ctx, tx, teamID, team.Members); err != nil {
+			return nil, nil, err
+		}
+
+		args := []any{teamID}
+		removeQuery := `
+			DELETE FROM team_members
+			WHERE team_id = ?
+			RETURNING user_id
+		`
+		if len(team.Members) > 0 {
+			for _, m := range team.Members {
+				args = append(args, m.UserID)
+			}
+			removeQuery = fmt.Sprintf(`
+				DELETE FROM team_members
+				WHERE team_id = ? AND user_id NOT IN (%s)
+				RETURNING user_id
+			`, strings.TrimSuffix(strings.Repeat("?, ", len(team.Members)), ", "))
+		}
+		removed, err := removeMembers(ctx, tx, removeQuery, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rr, err := releaseOpenReviews(ctx, tx, teamID, removed)
+		if err != nil {
+			return nil, nil, err
+		}
+		released = append(released, rr...)
+	}
+
+	if dryRun {
+		return diff, released, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return diff, released, nil
+}
+
+// getRosterTeam reads the team's seniority rule and the activity of its
+// members.
+func getRosterTeam(ctx context.Context, tx *sql.Tx, teamName string) (*model.Team, int64, error) {
+	getTeamQuery := `
+		SELECT team_id, min_senior_reviewers
+		FROM teams
+		WHERE team_name = ?
+	`
+	team := &model.Team{TeamName: teamName, Members: make([]*model.TeamMember, 0)}
+	var teamID int64
+	err := tx.QueryRowContext(ctx, getTeamQuery, teamName).Scan(&teamID, &team.MinSeniorReviewers)
+	if err == sql.ErrNoRows {
+		return nil, 0, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get team: %v", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT user_id, is_active FROM team_members WHERE team_id = ?", teamID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get team members: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		m := &model.TeamMember{}
+		if err := rows.Scan(&m.UserID, &m.IsActive); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan team member: %v", err)
+		}
+		team.Members = append(team.Members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate team members: %v", err)
+	}
+	return team, teamID, nil
+}
+
+func getTeamID(ctx context.Context, tx *sql.Tx, teamName string) (int64, error) {
+	getTeamQuery := `
+		SELECT team_id
+		FROM teams
+		WHERE team_name = ?
+	`
+	var teamID int64
+	err := tx.QueryRowContext(ctx, getTeamQuery, teamName).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		return 0, model.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get team: %v", err)
+	}
+	return teamID, nil
+}
+
+// upsertMembers creates missing users, brings deleted ones back as active
+// users and adds them to the team. Users that already belong to other teams
+// keep those memberships.
+func upsertMembers(ctx context.Context, tx *sql.Tx, teamID int64, members []*model.TeamMember) error {
+	updateOrInsertQuery := `
+		INSERT INTO users (user_id, username, level)
+		VALUES (?, ?, NULLIF(?, ''))
+		ON CONFLICT (user_id)
+		DO UPDATE SET
+			username = excluded.username,
+			level = COALESCE(excluded.level, users.level),
+			is_active = users.is_active OR users.deleted_at IS NOT NULL,
+			deleted_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	addMembershipQuery := `
+		INSERT INTO team_members (team_id, user_id, is_active)
+		VALUES (?, ?, ?)
+		ON CONFLICT (team_id, user_id)
+		DO UPDATE SET is_active = excluded.is_active
+	`
+
+	for _, member := range members {
+		_, err := tx.ExecContext(ctx, updateOrInsertQuery,
+			member.UserID,
+			member.Username,
+			member.Level)
+		if err != nil {
+			return fmt.Errorf("failed to add member to team: %v", err)
+		}
+
+		_, err = tx.ExecContext(ctx, addMembershipQuery,
+			teamID,
+			member.UserID,
+			member.IsActive)
+		if err != nil {
+			return fmt.Errorf("failed to add member to team: %v", err)
+		}
+
+		if member.Skills != nil {
+			if err := replaceSkills(ctx, tx, member.UserID, member.Skills); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replaceSkills overwrites the user's skill tags.
+func replaceSkills(ctx context.Context, tx *sql.Tx, userID string, skills []string) error {
+	clearSkillsQuery := `
+		DELETE FROM user_skills
+		WHERE user_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearSkillsQuery, userID); err != nil {
+		return fmt.Errorf("failed to clear skills: %v", err)
+	}
+
+	addSkillQuery := `
+		INSERT INTO user_skills (user_id, skill)
+		VALUES (?, ?)
+	`
+	for _, skill := range skills {
+		if _, err := tx.ExecContext(ctx, addSkillQuery, userID, skill); err != nil {
+			return fmt.Errorf("failed to add skill: %v", err)
+		}
+	}
+	return nil
+}
+
+// removeMembers runs a membership delete query returning the ids of the users
+// that left the team.
+func removeMembers(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove members: %v", err)
+	}
+	defer rows.Close()
+
+	userIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %v", err)
+	}
+	return userIDs, nil
+}
+
+// releaseOpenReviews drops the users' assignments on OPEN pull requests filed
+// against the team. Assignments on merged pull requests are kept as history.
+func releaseOpenReviews(ctx context.Context, tx *sql.Tx, teamID int64, userIDs []string) ([]*model.ReleasedReview, error) {
+	releaseQuery := `
+		DELETE FROM pull_request_reviewers
+		WHERE reviewer_user_id = ?
+			AND pull_request_id IN (
+				SELECT pull_request_id
+				FROM pull_requests
+				WHERE status_id = 1 AND team_id = ?
+			)
+		RETURNING pull_request_id, reviewer_user_id
+	`
+
+	released := make([]*model.ReleasedReview, 0)
+	for _, userID := range userIDs {
+		rows, err := tx.QueryContext(ctx, releaseQuery, userID, teamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to release open reviews: %v", err)
+		}
+
+		for rows.Next() {
+			rr := &model.ReleasedReview{}
+			if err := rows.Scan(&rr.PullRequestID, &rr.UserID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan released review: %v", err)
+			}
+			released = append(released, rr)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate released reviews: %v", err)
+		}
+	}
+
+	addHistoryQuery := `
+		INSERT INTO reviewer_history (pull_request_id, reviewer_user_id, action, created_at)
+		VALUES (?, ?, 'removed', ?)
+	`
+	now := time.Now().UTC()
+	for _, rr := range released {
+		if _, err := tx.ExecContext(ctx, addHistoryQuery, rr.PullRequestID, rr.UserID, now); err != nil {
+			return nil, fmt.Errorf("record history error: %v", err)
+		}
+	}
+	return released, nil
+}
+
+// splitSkills turns a GROUP_CONCAT result into a sorted tag list.
+func splitSkills(skills sql.NullString) []string {
+	if !skills.Valid {
+		return nil
+	}
+	tags := strings.Split(skills.String, ",")
+	sort.Strings(tags)
+	return tags
+}
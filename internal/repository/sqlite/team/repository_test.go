@@ -0,0 +1,623 @@
+package team
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/database"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func newTestRepo(t *testing.T) *repository {
+	t.Helper()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &repository{db: db}
+}
+
+func TestAddAndGet(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	team := &model.Team{
+		TeamName: "backend",
+		Members: []*model.TeamMember{
+			{UserID: "u1", Username: "Alice", IsActive: true},
+			{UserID: "u2", Username: "Bob", IsActive: false},
+		},
+	}
+
+	if err := repo.Add(ctx, team); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.Get(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(got.Members))
+	}
+	for _, m := range got.Members {
+		if m.UserID == "u2" && m.IsActive {
+			t.Errorf("expected u2 to be inactive")
+		}
+	}
+}
+
+func TestAddTeamExists(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	team := &model.Team{
+		TeamName: "backend",
+		Members:  []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}},
+	}
+	if err := repo.Add(ctx, team); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err := repo.Add(ctx, team)
+	if !errors.Is(err, model.ErrTeamExists) {
+		t.Errorf("expected ErrTeamExists, got: %v", err)
+	}
+}
+
+func TestAddKeepsExistingMemberships(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{
+		TeamName: "backend",
+		Members:  []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Add(ctx, &model.Team{
+		TeamName: "frontend",
+		Members:  []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, name := range []string{"backend", "frontend"} {
+		team, err := repo.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(team.Members) != 1 || team.Members[0].UserID != "u1" {
+			t.Errorf("unexpected %s members: %+v", name, team.Members)
+		}
+	}
+}
+
+func TestGetTeamNotFound(t *testing.T) {
+	repo := newTestRepo(t)
+
+	team, err := repo.Get(context.Background(), "unknownteam")
+	if team != nil {
+		t.Errorf("expected nil team, got %+v", team)
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestGetEmptyTeam(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	team := &model.Team{
+		TeamName:           "backend",
+		Members:            []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}},
+		MinSeniorReviewers: 1,
+	}
+	if err := repo.Add(ctx, team); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.Update(ctx, &model.TeamUpdate{TeamName: "backend", RemoveMembers: []string{"u1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.Get(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Members) != 0 || got.MinSeniorReviewers != 1 {
+		t.Errorf("expected an empty team keeping its rule, got %+v", got)
+	}
+}
+
+func TestList(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, team := range []*model.Team{
+		{TeamName: "frontend", Members: []*model.TeamMember{{UserID: "u2", Username: "Bob", IsActive: true}}},
+		{TeamName: "backend", Members: []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}}},
+	} {
+		if err := repo.Add(ctx, team); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := repo.Update(ctx, &model.TeamUpdate{TeamName: "frontend", RemoveMembers: []string{"u2"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	teams, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(teams) != 2 || teams[0].TeamName != "backend" || teams[1].TeamName != "frontend" {
+		t.Fatalf("unexpected teams: %+v", teams)
+	}
+	if len(teams[0].Members) != 1 || len(teams[1].Members) != 0 {
+		t.Errorf("unexpected members: %+v, %+v", teams[0].Members, teams[1].Members)
+	}
+}
+
+func TestUpdateRenameAddRemove(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{
+		TeamName: "backend",
+		Members: []*model.TeamMember{
+			{UserID: "u1", Username: "Alice", IsActive: true},
+			{UserID: "u2", Username: "Bob", IsActive: true},
+		},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seed := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id) VALUES
+			('pr-1', 'Open', 'u1', 1, 1),
+			('pr-2', 'Merged', 'u1', 2, 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES
+			('pr-1', 'u2'),
+			('pr-2', 'u2');
+	`
+	if _, err := repo.db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	released, err := repo.Update(ctx, &model.TeamUpdate{
+		TeamName:      "backend",
+		NewTeamName:   "platform",
+		AddMembers:    []*model.TeamMember{{UserID: "u3", Username: "Carol", IsActive: true}},
+		RemoveMembers: []string{"u2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(released) != 1 || released[0].PullRequestID != "pr-1" {
+		t.Errorf("expected only the open review to be released, got %+v", released)
+	}
+
+	if _, err := repo.Get(ctx, "backend"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected old team name to be gone, got: %v", err)
+	}
+	team, err := repo.Get(ctx, "platform")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := map[string]bool{}
+	for _, m := range team.Members {
+		ids[m.UserID] = true
+	}
+	if len(ids) != 2 || !ids["u1"] || !ids["u3"] {
+		t.Errorf("unexpected members: %v", ids)
+	}
+
+	var remaining int
+	if err := repo.db.QueryRow(`SELECT COUNT(*) FROM pull_request_reviewers WHERE reviewer_user_id = 'u2'`).Scan(&remaining); err != nil {
+		t.Fatalf("failed to count reviews: %v", err)
+	}
+	if remaining != 1 {
+		t.Errorf("expected merged review to be kept, got %d rows", remaining)
+	}
+}
+
+func TestUpdateRenameConflict(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"backend", "frontend"} {
+		if err := repo.Add(ctx, &model.Team{TeamName: name}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	_, err := repo.Update(ctx, &model.TeamUpdate{TeamName: "backend", NewTeamName: "frontend"})
+	if !errors.Is(err, model.ErrTeamExists) {
+		t.Errorf("expected ErrTeamExists, got: %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{
+		TeamName: "backend",
+		Members:  []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := repo.Delete(ctx, "backend"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.Delete(ctx, "backend"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	var memberships int
+	if err := repo.db.QueryRow(`SELECT COUNT(*) FROM team_members WHERE user_id = 'u1'`).Scan(&memberships); err != nil {
+		t.Fatalf("failed to count memberships: %v", err)
+	}
+	if memberships != 0 {
+		t.Errorf("expected memberships to be removed, got %d", memberships)
+	}
+	var isActive bool
+	if err := repo.db.QueryRow(`SELECT is_active FROM users WHERE user_id = 'u1'`).Scan(&isActive); err != nil {
+		t.Fatalf("expected user to be kept: %v", err)
+	}
+	if !isActive {
+		t.Errorf("expected user activity to be untouched")
+	}
+}
+
+func TestOwnershipRules(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{TeamName: "backend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rules, err := repo.GetOwnershipRules(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Errorf("expected no rules, got %+v", rules)
+	}
+
+	for _, set := range [][]*model.OwnershipRule{
+		{{Line: 1, Pattern: "*", Owners: []string{"@u1"}}},
+		{
+			{Line: 2, Pattern: "*.go", Owners: []string{"@u2", "@acme/platform"}},
+			{Line: 5, Pattern: "vendor/"},
+		},
+	} {
+		if err := repo.SetOwnershipRules(ctx, "backend", set); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	rules, err = repo.GetOwnershipRules(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Line != 2 || len(rules[0].Owners) != 2 || len(rules[1].Owners) != 0 {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	if _, err := repo.GetOwnershipRules(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := repo.SetOwnershipRules(ctx, "none", nil); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestAddKeepsSkillsWhenOmitted(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{
+		TeamName: "backend",
+		Members:  []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true, Skills: []string{"sql", "go"}}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Add(ctx, &model.Team{
+		TeamName: "platform",
+		Members:  []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}},
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	team, err := repo.Get(ctx, "platform")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	skills := team.Members[0].Skills
+	if len(skills) != 2 || skills[0] != "go" || skills[1] != "sql" {
+		t.Errorf("unexpected skills: %v", skills)
+	}
+}
+
+func TestSeniorityRule(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	team := &model.Team{
+		TeamName: "backend",
+		Members: []*model.TeamMember{
+			{UserID: "u1", Username: "Alice", IsActive: true, Level: model.LevelSenior},
+			{UserID: "u2", Username: "Bob", IsActive: true},
+		},
+		MinSeniorReviewers: 1,
+	}
+	if err := repo.Add(ctx, team); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rule := 2
+	_, err := repo.Update(ctx, &model.TeamUpdate{
+		TeamName:           "backend",
+		AddMembers:         []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}},
+		MinSeniorReviewers: &rule,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.Get(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.MinSeniorReviewers != 2 {
+		t.Errorf("expected rule 2, got %d", got.MinSeniorReviewers)
+	}
+	if got.Members[0].Level != model.LevelSenior || got.Members[1].Level != "" {
+		t.Errorf("omitted level must be kept: %+v %+v", got.Members[0], got.Members[1])
+	}
+}
+
+func TestFallbackTeams(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"backend", "frontend", "platform"} {
+		if err := repo.Add(ctx, &model.Team{TeamName: name}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := repo.SetFallbackTeams(ctx, "backend", []string{"platform", "frontend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fallbacks, err := repo.GetFallbackTeams(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fallbacks) != 2 || fallbacks[0] != "platform" || fallbacks[1] != "frontend" {
+		t.Errorf("unexpected fallbacks: %v", fallbacks)
+	}
+
+	// an unknown fallback team leaves the stored chain untouched
+	if err := repo.SetFallbackTeams(ctx, "backend", []string{"none"}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if fallbacks, _ := repo.GetFallbackTeams(ctx, "backend"); len(fallbacks) != 2 {
+		t.Errorf("expected the chain kept, got %v", fallbacks)
+	}
+
+	if _, err := repo.Delete(ctx, "platform"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fallbacks, _ := repo.GetFallbackTeams(ctx, "backend"); len(fallbacks) != 1 || fallbacks[0] != "frontend" {
+		t.Errorf("expected the deleted team dropped, got %v", fallbacks)
+	}
+
+	if _, err := repo.GetFallbackTeams(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestReviewSLA(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{TeamName: "backend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sla, err := repo.GetReviewSLA(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sla.Minutes != 0 || sla.AutoReassign {
+		t.Errorf("expected no SLA by default, got %+v", sla)
+	}
+
+	if err := repo.SetReviewSLA(ctx, &model.ReviewSLA{TeamName: "backend", Minutes: 480, AutoReassign: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sla, _ := repo.GetReviewSLA(ctx, "backend"); sla.Minutes != 480 || !sla.AutoReassign {
+		t.Errorf("unexpected SLA: %+v", sla)
+	}
+
+	if err := repo.SetReviewSLA(ctx, &model.ReviewSLA{TeamName: "backend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sla, _ := repo.GetReviewSLA(ctx, "backend"); sla.Minutes != 0 {
+		t.Errorf("expected the SLA turned off, got %+v", sla)
+	}
+
+	if err := repo.SetReviewSLA(ctx, &model.ReviewSLA{TeamName: "none", Minutes: 60}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestHolidays(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{TeamName: "backend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	holidays := &model.TeamHolidays{TeamName: "backend", Holidays: []*model.Holiday{
+		{Date: "2024-12-26"},
+		{Date: "2024-12-25", Name: "Christmas"},
+	}}
+	if err := repo.SetHolidays(ctx, holidays); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.GetHolidays(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Holidays) != 2 || got.Holidays[0].Date != "2024-12-25" || got.Holidays[0].Name != "Christmas" {
+		t.Errorf("unexpected holidays: %+v", got.Holidays)
+	}
+
+	if err := repo.SetHolidays(ctx, &model.TeamHolidays{TeamName: "backend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := repo.GetHolidays(ctx, "backend"); len(got.Holidays) != 0 {
+		t.Errorf("expected the holidays cleared, got %+v", got.Holidays)
+	}
+
+	if _, err := repo.GetHolidays(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestChat(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if err := repo.Add(ctx, &model.Team{TeamName: "backend"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := repo.GetChat(ctx, "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *got != (model.TeamChat{TeamName: "backend"}) {
+		t.Errorf("expected no chat by default, got %+v", got)
+	}
+
+	chat := &model.TeamChat{TeamName: "backend", WebhookURL: "https://chat.example.com/hooks/1", DirectMessages: true}
+	if err := repo.SetChat(ctx, chat); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := repo.GetChat(ctx, "backend"); *got != *chat {
+		t.Errorf("unexpected chat: %+v", got)
+	}
+
+	if err := repo.SetChat(ctx, &model.TeamChat{TeamName: "none"}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := repo.GetChat(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, team := range []*model.Team{
+		{TeamName: "backend", Members: []*model.TeamMember{
+			{UserID: "u1", Username: "Alice", IsActive: true},
+			{UserID: "u2", Username: "Bob", IsActive: true},
+		}},
+		{TeamName: "frontend", Members: []*model.TeamMember{{UserID: "u3", Username: "Carol", IsActive: true}}},
+	} {
+		if err := repo.Add(ctx, team); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	seed := `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id) VALUES
+			('pr-1', 'Open', 'u1', 1, 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES
+			('pr-1', 'u2');
+	`
+	if _, err := repo.db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	one := 1
+	roster := []*model.RosterTeam{
+		{TeamName: "backend", MinSeniorReviewers: &one, Members: []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: false}}},
+		{TeamName: "data", Members: []*model.TeamMember{{UserID: "u2", Username: "Bob", IsActive: true}}},
+	}
+
+	// a dry run previews the import and changes nothing
+	preview, previewReleased, err := repo.Import(ctx, roster, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(previewReleased) != 1 || len(preview.CreatedTeams) != 1 {
+		t.Errorf("unexpected preview: %+v %+v", preview, previewReleased)
+	}
+	if _, err := repo.Get(ctx, "data"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected the dry run to be rolled back, got: %v", err)
+	}
+
+	diff, released, err := repo.Import(ctx, roster, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(released) != 1 || released[0].PullRequestID != "pr-1" || released[0].UserID != "u2" {
+		t.Errorf("expected the open review of Bob to be released, got %+v", released)
+	}
+	wantChanges := []model.RosterChange{
+		{Action: model.RosterActivityChanged, UserID: "u1", TeamName: "backend", IsActive: false},
+		{Action: model.RosterMoved, UserID: "u2", TeamName: "data", FromTeam: "backend", IsActive: true},
+	}
+	if len(diff.Changes) != len(wantChanges) {
+		t.Fatalf("unexpected changes: %+v", diff.Changes)
+	}
+	for i := range wantChanges {
+		if *diff.Changes[i] != wantChanges[i] {
+			t.Errorf("expected %+v, got %+v", wantChanges[i], *diff.Changes[i])
+		}
+	}
+	if len(diff.SeniorityChanges) != 1 || *diff.SeniorityChanges[0] != (model.SeniorityChange{TeamName: "backend", From: 0, To: 1}) {
+		t.Errorf("unexpected seniority changes: %+v", diff.SeniorityChanges)
+	}
+	if backend, err := repo.Get(ctx, "backend"); err != nil || backend.MinSeniorReviewers != 1 {
+		t.Errorf("expected the seniority rule of backend to change, got %+v, %v", backend, err)
+	}
+
+	teams, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	members := map[string][]string{}
+	for _, team := range teams {
+		for _, m := range team.Members {
+			members[team.TeamName] = append(members[team.TeamName], m.UserID)
+			if m.UserID == "u1" && m.IsActive {
+				t.Errorf("expected Alice to be inactive in backend")
+			}
+		}
+	}
+	if len(teams) != 3 || len(members["backend"]) != 1 || len(members["data"]) != 1 || members["data"][0] != "u2" ||
+		len(members["frontend"]) != 1 {
+		t.Errorf("unexpected teams after import: %v", members)
+	}
+
+	if _, _, err := repo.Import(ctx, []*model.RosterTeam{{TeamName: "frontend", Members: []*model.TeamMember{}}}, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if frontend, err := repo.Get(ctx, "frontend"); err != nil || len(frontend.Members) != 0 {
+		t.Errorf("expected an empty roster to remove every member, got: %+v, %v", frontend, err)
+	}
+}
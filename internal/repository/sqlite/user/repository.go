@@ -0,0 +1,1166 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/pagination"
+	def "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"sort"
+	"strings"
+	"time"
+)
+
+var _ def.UserRepository = (*repository)(nil)
+
+type repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *repository {
+	return &repository{db: db}
+}
+
+// Create adds a user and, when TeamName is set, makes them a member of that
+// existing team. A taken user_id or username is reported as ErrUserExists,
+// except that the user_id of a deleted user is taken over: the record comes
+// back with the new profile and skills.
+func (r *repository) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	takenQuery := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM users
+			WHERE username = ? AND user_id <> ?
+		)
+	`
+	var taken bool
+	if err := tx.QueryRowContext(ctx, takenQuery, user.Username, user.UserID).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("failed to check username: %v", err)
+	}
+	if taken {
+		return nil, model.ErrUserExists
+	}
+
+	createUserQuery := `
+		INSERT INTO users (user_id, username, is_active, level, max_open_reviews, email, chat_handle)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''))
+		ON CONFLICT (user_id)
+		DO UPDATE SET
+			username = excluded.username,
+			is_active = excluded.is_active,
+			level = excluded.level,
+			max_open_reviews = excluded.max_open_reviews,
+			email = excluded.email,
+			chat_handle = excluded.chat_handle,
+			last_reminded_at = NULL,
+			deleted_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE users.deleted_at IS NOT NULL
+	`
+	result, err := tx.ExecContext(ctx, createUserQuery,
+		user.UserID,
+		user.Username,
+		user.IsActive,
+		user.Level,
+		user.MaxOpenReviews,
+		user.Email,
+		user.ChatHandle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, model.ErrUserExists
+	}
+
+	// a deleted user taken over keeps none of their old skills
+	clearSkillsQuery := `
+		DELETE FROM user_skills
+		WHERE user_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearSkillsQuery, user.UserID); err != nil {
+		return nil, fmt.Errorf("failed to clear skills: %v", err)
+	}
+
+	addSkillQuery := `
+		INSERT INTO user_skills (user_id, skill)
+		VALUES (?, ?)
+	`
+	for _, skill := range user.Skills {
+		if _, err := tx.ExecContext(ctx, addSkillQuery, user.UserID, skill); err != nil {
+			return nil, fmt.Errorf("failed to add skill: %v", err)
+		}
+	}
+
+	if user.TeamName != "" {
+		addMembershipQuery := `
+			INSERT INTO team_members (team_id, user_id)
+			SELECT team_id, ?
+			FROM teams
+			WHERE team_name = ?
+		`
+		result, err := tx.ExecContext(ctx, addMembershipQuery, user.UserID, user.TeamName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add user to team: %v", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %v", err)
+		}
+
+		if rowsAffected == 0 {
+			return nil, model.ErrNotFound
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %v", err)
+	}
+
+	created, err := r.getUser(ctx, user.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get created user: %v", err)
+	}
+
+	return created, nil
+}
+
+func (r *repository) Get(ctx context.Context, userID string) (*model.User, error) {
+	user, err := r.getUser(ctx, userID)
+	if err == model.ErrNotFound {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %v", err)
+	}
+	return user, nil
+}
+
+// List returns the users matching the filter, ordered by user_id. Deleted
+// users are left out.
+func (r *repository) List(ctx context.Context, filter model.UserFilter) ([]*model.User, error) {
+	conds := []string{"u.deleted_at IS NULL"}
+	args := []any{}
+
+	if filter.TeamName != "" {
+		conds = append(conds, `EXISTS (
+			SELECT 1
+			FROM team_members ftm
+			JOIN teams ft ON ft.team_id = ftm.team_id
+			WHERE ftm.user_id = u.user_id AND ft.team_name = ?
+		)`)
+		args = append(args, filter.TeamName)
+	}
+	if filter.IsActive != nil {
+		conds = append(conds, "u.is_active = ?")
+		args = append(args, *filter.IsActive)
+	}
+
+	users, err := r.queryUsers(ctx, strings.Join(conds, " AND "), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %v", err)
+	}
+	return users, nil
+}
+
+// Delete retires the user. Users who author OPEN pull requests are kept and
+// ErrHasOpenPrs is returned. Otherwise their seats on OPEN pull requests are
+// released, they leave every team and are marked deleted: the record stays
+// behind for the pull requests and history that refer to it, but Get, List
+// and a repeated Delete no longer find it.
+func (r *repository) Delete(ctx context.Context, userID string) (*model.UserDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	checkUserQuery := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pull_requests
+			WHERE author_id = u.user_id AND status_id = 1
+		)
+		FROM users u
+		WHERE u.user_id = ? AND u.deleted_at IS NULL
+	`
+	var authorsOpen bool
+	err = tx.QueryRowContext(ctx, checkUserQuery, userID).Scan(&authorsOpen)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user: %v", err)
+	}
+	if authorsOpen {
+		return nil, model.ErrHasOpenPrs
+	}
+
+	deletion := &model.UserDeletion{UserID: userID}
+
+	releaseQuery := `
+		DELETE FROM pull_request_reviewers
+		WHERE reviewer_user_id = ?
+			AND pull_request_id IN (
+				SELECT pull_request_id
+				FROM pull_requests
+				WHERE status_id = 1
+			)
+		RETURNING pull_request_id, reviewer_user_id
+	`
+	deletion.ReleasedReviews, err = releaseOpenReviews(ctx, tx, releaseQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	leaveTeamsQuery := `
+		DELETE FROM team_members
+		WHERE user_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, leaveTeamsQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to remove memberships: %v", err)
+	}
+
+	retireUserQuery := `
+		UPDATE users
+		SET is_active = FALSE, deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, retireUserQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to retire user: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return deletion, nil
+}
+
+func (r *repository) SetIsActive(ctx context.Context, userID string, isActive bool) (*model.User, error) {
+	query := `
+		UPDATE users
+		SET is_active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, isActive, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user active status: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, model.ErrNotFound
+	}
+
+	user, err := r.getUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated user: %v", err)
+	}
+
+	return user, nil
+}
+
+func (r *repository) SetTeamIsActive(ctx context.Context, userID string, teamName string, isActive bool) (*model.User, error) {
+	query := `
+		UPDATE team_members
+		SET is_active = ?
+		WHERE user_id = ?
+			AND team_id = (SELECT team_id FROM teams WHERE team_name = ?)
+	`
+	result, err := r.db.ExecContext(ctx, query, isActive, userID, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update team membership status: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, model.ErrNotFound
+	}
+
+	user, err := r.getUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get updated user: %v", err)
+	}
+
+	return user, nil
+}
+
+// Update renames the user, replaces their skill tags and moves them between
+// teams in one transaction. Moving a user releases their seats on the OPEN
+// pull requests of the team they leave.
+func (r *repository) Update(ctx context.Context, update *model.UserUpdate) (*model.User, []*model.ReleasedReview, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	if update.Username != "" {
+		takenQuery := `
+			SELECT EXISTS (
+				SELECT 1
+				FROM users
+				WHERE username = ? AND user_id <> ?
+			)
+		`
+		var taken bool
+		if err := tx.QueryRowContext(ctx, takenQuery, update.Username, update.UserID).Scan(&taken); err != nil {
+			return nil, nil, fmt.Errorf("failed to check username: %v", err)
+		}
+		if taken {
+			return nil, nil, model.ErrUserExists
+		}
+	}
+
+	query := `
+		UPDATE users
+		SET
+			username = COALESCE(NULLIF(?, ''), username),
+			level = COALESCE(NULLIF(?, ''), level),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, update.Username, update.Level, update.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to update user: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, nil, model.ErrNotFound
+	}
+
+	if update.Skills != nil {
+		clearSkillsQuery := `
+			DELETE FROM user_skills
+			WHERE user_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, clearSkillsQuery, update.UserID); err != nil {
+			return nil, nil, fmt.Errorf("failed to clear skills: %v", err)
+		}
+
+		addSkillQuery := `
+			INSERT INTO user_skills (user_id, skill)
+			VALUES (?, ?)
+		`
+		for _, skill := range update.Skills {
+			if _, err := tx.ExecContext(ctx, addSkillQuery, update.UserID, skill); err != nil {
+				return nil, nil, fmt.Errorf("failed to add skill: %v", err)
+			}
+		}
+	}
+
+	if update.MaxOpenReviews != nil {
+		setCapacityQuery := `
+			UPDATE users
+			SET max_open_reviews = NULLIF(?, 0)
+			WHERE user_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, setCapacityQuery, *update.MaxOpenReviews, update.UserID); err != nil {
+			return nil, nil, fmt.Errorf("failed to set review capacity: %v", err)
+		}
+	}
+
+	if update.Email != "" {
+		setEmailQuery := `
+			UPDATE users
+			SET email = ?
+			WHERE user_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, setEmailQuery, update.Email, update.UserID); err != nil {
+			return nil, nil, fmt.Errorf("failed to set email: %v", err)
+		}
+	}
+
+	if update.ChatHandle != "" {
+		setChatHandleQuery := `
+			UPDATE users
+			SET chat_handle = ?
+			WHERE user_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, setChatHandleQuery, update.ChatHandle, update.UserID); err != nil {
+			return nil, nil, fmt.Errorf("failed to set chat handle: %v", err)
+		}
+	}
+
+	released := make([]*model.ReleasedReview, 0)
+	if update.TeamName != "" {
+		released, err = moveToTeam(ctx, tx, update.UserID, update.TeamName)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit: %v", err)
+	}
+
+	user, err := r.getUser(ctx, update.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get updated user: %v", err)
+	}
+
+	return user, released, nil
+}
+
+// GetReview returns one page of the pull requests assigned to the reviewer,
+// the oldest assignment first.
+func (r *repository) GetReview(ctx context.Context, reviewerID string, filter model.ReviewFilter) (*model.ReviewPage, error) {
+	// assignment times are normalized so that values written by
+	// CURRENT_TIMESTAMP and by the driver compare alike; the cursor key is the
+	// normalized value
+	const assignedKey = "strftime('%Y-%m-%d %H:%M:%f', prr.assigned_at)"
+
+	conds := []string{"prr.reviewer_user_id = ?"}
+	args := []any{reviewerID}
+
+	if filter.Status != "" {
+		conds = append(conds, "ps.status_name = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.After != nil {
+		conds = append(conds, "("+assignedKey+" > ? OR ("+assignedKey+" = ? AND pr.pull_request_id > ?))")
+		args = append(args, filter.After.Key, filter.After.Key, filter.After.ID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = pagination.DefaultLimit
+	}
+	args = append(args, limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT
+			pr.pull_request_id,
+			pr.pull_request_name,
+			pr.author_id,
+			ps.status_name,
+			prr.assigned_at,
+			%[1]s
+		FROM pull_requests pr
+		INNER JOIN pull_request_statuses ps
+			ON pr.status_id = ps.status_id
+		INNER JOIN pull_request_reviewers prr
+			ON pr.pull_request_id = prr.pull_request_id
+		WHERE %[2]s
+		ORDER BY %[1]s, pr.pull_request_id
+		LIMIT ?
+	`, assignedKey, strings.Join(conds, " AND "))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select pull requests with current reviewer id: %v", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	reviews := make([]*model.ReviewAssignment, 0)
+	keys := make([]string, 0)
+	for rows.Next() {
+		var (
+			review = &model.ReviewAssignment{}
+			key    string
+		)
+		err = rows.Scan(
+			&review.PullRequestID,
+			&review.PullRequestName,
+			&review.AuthorID,
+			&review.Status,
+			&review.AssignedAt,
+			&key,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan pull request: %v", err)
+		}
+		review.AgeSeconds = int64(now.Sub(review.AssignedAt).Seconds())
+		reviews = append(reviews, review)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pull requests: %v", err)
+	}
+
+	page := &model.ReviewPage{Reviews: reviews}
+	if len(reviews) > limit {
+		page.Reviews = reviews[:limit]
+		page.Next = &model.Cursor{Key: keys[limit-1], ID: reviews[limit-1].PullRequestID}
+	}
+	return page, nil
+}
+
+// GetReviewLoad counts the user's open reviews against their capacity.
+func (r *repository) GetReviewLoad(ctx context.Context, userID string) (*model.ReviewLoad, error) {
+	query := `
+		SELECT open_reviews, max_open_reviews
+		FROM reviewer_load
+		WHERE user_id = ?
+	`
+	load := &model.ReviewLoad{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&load.OpenReviews, &load.MaxOpenReviews)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review load: %v", err)
+	}
+	return load, nil
+}
+
+// SetReviewerPreferences replaces the author's blocked and preferred
+// reviewers. Unknown users, the author included, are reported as not found.
+func (r *repository) SetReviewerPreferences(ctx context.Context, prefs *model.ReviewerPreferences) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	userIDs := append([]string{prefs.UserID}, prefs.Blocked...)
+	userIDs = append(userIDs, prefs.Preferred...)
+
+	args := make([]any, 0, len(userIDs))
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	countUsersQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM users
+		WHERE user_id IN (%s)
+	`, strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", "))
+	var found int
+	if err := tx.QueryRowContext(ctx, countUsersQuery, args...).Scan(&found); err != nil {
+		return fmt.Errorf("failed to check users: %v", err)
+	}
+	if found != len(userIDs) {
+		return model.ErrNotFound
+	}
+
+	clearQuery := `
+		DELETE FROM reviewer_preferences
+		WHERE author_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearQuery, prefs.UserID); err != nil {
+		return fmt.Errorf("failed to clear preferences: %v", err)
+	}
+
+	addQuery := `
+		INSERT INTO reviewer_preferences (author_id, reviewer_id, kind)
+		VALUES (?, ?, ?)
+	`
+	groups := []struct {
+		kind      string
+		reviewers []string
+	}{
+		{"blocked", prefs.Blocked},
+		{"preferred", prefs.Preferred},
+	}
+	for _, group := range groups {
+		for _, reviewerID := range group.reviewers {
+			if _, err := tx.ExecContext(ctx, addQuery, prefs.UserID, reviewerID, group.kind); err != nil {
+				return fmt.Errorf("failed to add preference: %v", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+// GetReviewerPreferences lists the author's blocked and preferred reviewers.
+func (r *repository) GetReviewerPreferences(ctx context.Context, userID string) (*model.ReviewerPreferences, error) {
+	query := `
+		SELECT p.reviewer_id, p.kind
+		FROM users u
+		LEFT JOIN reviewer_preferences p ON p.author_id = u.user_id
+		WHERE u.user_id = ?
+		ORDER BY p.reviewer_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %v", err)
+	}
+	defer rows.Close()
+
+	var prefs *model.ReviewerPreferences
+	for rows.Next() {
+		var reviewerID, kind sql.NullString
+		if err := rows.Scan(&reviewerID, &kind); err != nil {
+			return nil, fmt.Errorf("failed to scan preference: %v", err)
+		}
+		if prefs == nil {
+			prefs = &model.ReviewerPreferences{
+				UserID:    userID,
+				Blocked:   make([]string, 0),
+				Preferred: make([]string, 0),
+			}
+		}
+		switch kind.String {
+		case "blocked":
+			prefs.Blocked = append(prefs.Blocked, reviewerID.String)
+		case "preferred":
+			prefs.Preferred = append(prefs.Preferred, reviewerID.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate preferences: %v", err)
+	}
+
+	if prefs == nil {
+		return nil, model.ErrNotFound
+	}
+	return prefs, nil
+}
+
+// SetSchedule stores the user's timezone and working hours. An empty
+// timezone is stored as UTC and empty hours clear them.
+func (r *repository) SetSchedule(ctx context.Context, schedule *model.WorkSchedule) error {
+	query := `
+		UPDATE users
+		SET timezone = COALESCE(NULLIF(?, ''), 'UTC'), work_start = NULLIF(?, ''), work_end = NULLIF(?, ''),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = ?
+	`
+	result, err := r.db.ExecContext(ctx, query, schedule.Timezone, schedule.WorkStart, schedule.WorkEnd, schedule.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to set schedule: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetSchedule(ctx context.Context, userID string) (*model.WorkSchedule, error) {
+	query := `
+		SELECT timezone, COALESCE(work_start, ''), COALESCE(work_end, '')
+		FROM users
+		WHERE user_id = ?
+	`
+	schedule := &model.WorkSchedule{UserID: userID}
+	err := r.db.
+		QueryRowContext(ctx, query, userID).
+		Scan(&schedule.Timezone, &schedule.WorkStart, &schedule.WorkEnd)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule: %v", err)
+	}
+	return schedule, nil
+}
+
+// ReviewQueues lists the active reviewers with OPEN reviews they have not
+// submitted yet and that were assigned at or before assignedBefore, each with
+// those reviews, the oldest first.
+func (r *repository) ReviewQueues(ctx context.Context, assignedBefore time.Time) ([]*model.ReviewerQueue, error) {
+	// assignment times are normalized so that values written by
+	// CURRENT_TIMESTAMP and by the driver compare alike
+	const assignedKey = "strftime('%Y-%m-%d %H:%M:%f', prr.assigned_at)"
+
+	query := fmt.Sprintf(`
+		SELECT
+			u.user_id,
+			u.username,
+			u.timezone,
+			COALESCE(u.work_start, ''),
+			COALESCE(u.work_end, ''),
+			(
+				SELECT GROUP_CONCAT(DISTINCT h.holiday)
+				FROM team_holidays h
+				JOIN team_members tm ON tm.team_id = h.team_id
+				WHERE tm.user_id = u.user_id
+			),
+			pr.pull_request_id,
+			pr.pull_request_name,
+			pr.author_id,
+			COALESCE(t.team_name, ''),
+			prr.assigned_at
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr
+			ON pr.pull_request_id = prr.pull_request_id
+		LEFT JOIN teams t
+			ON t.team_id = pr.team_id
+		JOIN users u
+			ON u.user_id = prr.reviewer_user_id
+		WHERE pr.status_id = 1
+			AND u.is_active = TRUE
+			AND prr.reviewed_at IS NULL
+			AND %[1]s <= strftime('%%Y-%%m-%%d %%H:%%M:%%f', ?)
+		ORDER BY u.user_id, %[1]s, pr.pull_request_id
+	`, assignedKey)
+	rows, err := r.db.QueryContext(ctx, query, assignedBefore.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to select review queues: %v", err)
+	}
+	defer rows.Close()
+
+	queues := make([]*model.ReviewerQueue, 0)
+	var queue *model.ReviewerQueue
+	for rows.Next() {
+		var (
+			q        model.ReviewerQueue
+			holidays sql.NullString
+			review   = &model.ReviewAssignment{}
+		)
+		err := rows.Scan(
+			&q.UserID,
+			&q.Username,
+			&q.Schedule.Timezone,
+			&q.Schedule.WorkStart,
+			&q.Schedule.WorkEnd,
+			&holidays,
+			&review.PullRequestID,
+			&review.PullRequestName,
+			&review.AuthorID,
+			&review.TeamName,
+			&review.AssignedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review queue: %v", err)
+		}
+		review.Status = "OPEN"
+
+		if queue == nil || queue.UserID != q.UserID {
+			queue = &q
+			queue.Schedule.UserID = q.UserID
+			if holidays.Valid {
+				queue.Holidays = strings.Split(holidays.String, ",")
+			}
+			queues = append(queues, queue)
+		}
+		queue.Reviews = append(queue.Reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate review queues: %v", err)
+	}
+	return queues, nil
+}
+
+// MarkReminded records that the user was sent a reminder at at and returns
+// the reminder it replaced, if any. It reports false, leaving the record
+// alone, when the previous reminder was sent after notAfter, which keeps
+// reminders apart even with several schedulers running.
+func (r *repository) MarkReminded(ctx context.Context, userID string, at time.Time, notAfter time.Time) (bool, *time.Time, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	previousQuery := `
+		SELECT last_reminded_at
+		FROM users
+		WHERE user_id = ?
+	`
+	var previous sql.NullTime
+	err = tx.QueryRowContext(ctx, previousQuery, userID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get last reminder: %v", err)
+	}
+
+	query := `
+		UPDATE users
+		SET last_reminded_at = ?
+		WHERE user_id = ? AND (
+			last_reminded_at IS NULL
+			OR strftime('%Y-%m-%d %H:%M:%f', last_reminded_at) <= strftime('%Y-%m-%d %H:%M:%f', ?)
+		)
+	`
+	result, err := tx.ExecContext(ctx, query, at.UTC(), userID, notAfter.UTC())
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to mark reminded: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return false, nil, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	if !previous.Valid {
+		return true, nil, nil
+	}
+	return true, &previous.Time, nil
+}
+
+// UnmarkReminded takes back the reminder MarkReminded recorded at at, when it
+// could not be delivered, and puts previous back. A later reminder is left
+// alone.
+func (r *repository) UnmarkReminded(ctx context.Context, userID string, at time.Time, previous *time.Time) error {
+	var restored any
+	if previous != nil {
+		restored = previous.UTC()
+	}
+	query := `
+		UPDATE users
+		SET last_reminded_at = ?
+		WHERE user_id = ?
+			AND strftime('%Y-%m-%d %H:%M:%f', last_reminded_at) = strftime('%Y-%m-%d %H:%M:%f', ?)
+	`
+	if _, err := r.db.ExecContext(ctx, query, restored, userID, at.UTC()); err != nil {
+		return fmt.Errorf("failed to unmark reminded: %v", err)
+	}
+	return nil
+}
+
+// SetNotificationSettings replaces the notification kinds the user opted
+// out of.
+func (r *repository) SetNotificationSettings(ctx context.Context, settings *model.NotificationSettings) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE user_id = ?)", settings.UserID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get user: %v", err)
+	}
+	if !exists {
+		return model.ErrNotFound
+	}
+
+	clearOptOutsQuery := `
+		DELETE FROM notification_opt_outs
+		WHERE user_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, clearOptOutsQuery, settings.UserID); err != nil {
+		return fmt.Errorf("failed to clear opt-outs: %v", err)
+	}
+
+	addOptOutQuery := `
+		INSERT INTO notification_opt_outs (user_id, kind)
+		VALUES (?, ?)
+	`
+	for _, kind := range settings.OptOut {
+		if _, err := tx.ExecContext(ctx, addOptOutQuery, settings.UserID, kind); err != nil {
+			return fmt.Errorf("failed to add opt-out: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+// GetNotificationSettings loads the user's email and the notification kinds
+// they opted out of.
+func (r *repository) GetNotificationSettings(ctx context.Context, userID string) (*model.NotificationSettings, error) {
+	query := `
+		SELECT u.username, COALESCE(u.email, ''), o.kind
+		FROM users u
+		LEFT JOIN notification_opt_outs o ON o.user_id = u.user_id
+		WHERE u.user_id = ?
+		ORDER BY o.kind
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification settings: %v", err)
+	}
+	defer rows.Close()
+
+	var settings *model.NotificationSettings
+	for rows.Next() {
+		var (
+			username, email string
+			kind            sql.NullString
+		)
+		if err := rows.Scan(&username, &email, &kind); err != nil {
+			return nil, fmt.Errorf("failed to scan notification settings: %v", err)
+		}
+		if settings == nil {
+			settings = &model.NotificationSettings{
+				UserID:   userID,
+				Username: username,
+				Email:    email,
+				OptOut:   make([]string, 0),
+			}
+		}
+		if kind.Valid {
+			settings.OptOut = append(settings.OptOut, kind.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notification settings: %v", err)
+	}
+
+	if settings == nil {
+		return nil, model.ErrNotFound
+	}
+	return settings, nil
+}
+
+// GetChatHandles maps the users that have a chat handle to it.
+func (r *repository) GetChatHandles(ctx context.Context, userIDs []string) (map[string]string, error) {
+	if len(userIDs) == 0 {
+		return map[string]string{}, nil
+	}
+
+	args := make([]any, 0, len(userIDs))
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+	query := fmt.Sprintf(`
+		SELECT user_id, chat_handle
+		FROM users
+		WHERE user_id IN (%s) AND chat_handle IS NOT NULL
+	`, strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", "))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat handles: %v", err)
+	}
+	defer rows.Close()
+
+	handles := make(map[string]string, len(userIDs))
+	for rows.Next() {
+		var userID, handle string
+		if err := rows.Scan(&userID, &handle); err != nil {
+			return nil, fmt.Errorf("failed to scan chat handle: %v", err)
+		}
+		handles[userID] = handle
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate chat handles: %v", err)
+	}
+	return handles, nil
+}
+
+// getUser loads a user that is not deleted with all of their team
+// memberships, the earliest joined team first.
+func (r *repository) getUser(ctx context.Context, userID string) (*model.User, error) {
+	users, err := r.queryUsers(ctx, "u.user_id = ? AND u.deleted_at IS NULL", userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, model.ErrNotFound
+	}
+	return users[0], nil
+}
+
+// queryUsers loads the users matching cond, ordered by user_id, each with all
+// of their team memberships, the earliest joined team first.
+func (r *repository) queryUsers(ctx context.Context, cond string, args ...any) ([]*model.User, error) {
+	query := `
+		SELECT
+			u.user_id,
+			u.username,
+			u.is_active,
+			t.team_name,
+			tm.is_active,
+			(SELECT GROUP_CONCAT(s.skill) FROM user_skills s WHERE s.user_id = u.user_id),
+			COALESCE(u.level, ''),
+			u.max_open_reviews,
+			COALESCE(u.email, ''),
+			COALESCE(u.chat_handle, '')
+		FROM users u
+		LEFT JOIN team_members tm
+			ON tm.user_id = u.user_id
+		LEFT JOIN teams t
+			ON t.team_id = tm.team_id
+		WHERE ` + cond + `
+		ORDER BY u.user_id, tm.joined_at, t.team_name
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]*model.User, 0)
+	for rows.Next() {
+		var (
+			u            model.User
+			teamName     sql.NullString
+			teamIsActive sql.NullBool
+			skills       sql.NullString
+		)
+		if err := rows.Scan(&u.UserID, &u.Username, &u.IsActive, &teamName, &teamIsActive, &skills, &u.Level, &u.MaxOpenReviews, &u.Email, &u.ChatHandle); err != nil {
+			return nil, err
+		}
+		if skills.Valid {
+			u.Skills = strings.Split(skills.String, ",")
+			sort.Strings(u.Skills)
+		}
+		if len(users) == 0 || users[len(users)-1].UserID != u.UserID {
+			u.Teams = make([]*model.Membership, 0)
+			users = append(users, &u)
+		}
+		user := users[len(users)-1]
+		if teamName.Valid {
+			user.Teams = append(user.Teams, &model.Membership{
+				TeamName: teamName.String,
+				IsActive: teamIsActive.Bool,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if len(user.Teams) > 0 {
+			user.TeamName = user.Teams[0].TeamName
+		}
+	}
+	return users, nil
+}
+
+// moveToTeam swaps the user's primary team for the named one, keeping its
+// join time so that the new team stays primary. Seats on OPEN pull requests of
+// the team left behind are released.
+func moveToTeam(ctx context.Context, tx *sql.Tx, userID, teamName string) ([]*model.ReleasedReview, error) {
+	released := make([]*model.ReleasedReview, 0)
+
+	var teamID int64
+	err := tx.QueryRowContext(ctx, "SELECT team_id FROM teams WHERE team_name = ?", teamName).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %v", err)
+	}
+
+	// joined_at is carried over as stored so that it sorts exactly like the
+	// membership it replaces
+	primaryQuery := `
+		SELECT tm.team_id, CAST(tm.joined_at AS TEXT)
+		FROM team_members tm
+		JOIN teams t ON t.team_id = tm.team_id
+		WHERE tm.user_id = ?
+		ORDER BY tm.joined_at, t.team_name
+		LIMIT 1
+	`
+	var (
+		primaryID int64
+		joinedAt  sql.NullString
+	)
+	err = tx.QueryRowContext(ctx, primaryQuery, userID).Scan(&primaryID, &joinedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get primary team: %v", err)
+	}
+	if primaryID == teamID {
+		return released, nil
+	}
+
+	if joinedAt.Valid {
+		leaveQuery := `
+			DELETE FROM team_members
+			WHERE team_id = ? AND user_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, leaveQuery, primaryID, userID); err != nil {
+			return nil, fmt.Errorf("failed to leave team: %v", err)
+		}
+
+		releaseQuery := `
+			DELETE FROM pull_request_reviewers
+			WHERE reviewer_user_id = ?
+				AND pull_request_id IN (
+					SELECT pull_request_id
+					FROM pull_requests
+					WHERE status_id = 1 AND team_id = ?
+				)
+			RETURNING pull_request_id, reviewer_user_id
+		`
+		released, err = releaseOpenReviews(ctx, tx, releaseQuery, userID, primaryID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	joinQuery := `
+		INSERT INTO team_members (team_id, user_id, joined_at)
+		VALUES (?, ?, COALESCE(?, CURRENT_TIMESTAMP))
+		ON CONFLICT (team_id, user_id)
+		DO UPDATE SET joined_at = excluded.joined_at
+	`
+	if _, err := tx.ExecContext(ctx, joinQuery, teamID, userID, joinedAt); err != nil {
+		return nil, fmt.Errorf("failed to join team: %v", err)
+	}
+	return released, nil
+}
+
+// releaseOpenReviews runs a DELETE ... RETURNING pull_request_id,
+// reviewer_user_id over pull_request_reviewers and collects what it dropped.
+func releaseOpenReviews(ctx context.Context, tx *sql.Tx, releaseQuery string, args ...any) ([]*model.ReleasedReview, error) {
+	rows, err := tx.QueryContext(ctx, releaseQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release open reviews: %v", err)
+	}
+	defer rows.Close()
+
+	released := make([]*model.ReleasedReview, 0)
+	for rows.Next() {
+		rr := &model.ReleasedReview{}
+		if err := rows.Scan(&rr.PullRequestID, &rr.UserID); err != nil {
+			return nil, fmt.Errorf("failed to scan released review: %v", err)
+		}
+		released = append(released, rr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate released reviews: %v", err)
+	}
+	rows.Close()
+
+	if err := recordReleased(ctx, tx, released); err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
+// recordReleased logs the released seats as removed in the reviewer history.
+func recordReleased(ctx context.Context, tx *sql.Tx, released []*model.ReleasedReview) error {
+	addHistoryQuery := `
+		INSERT INTO reviewer_history (pull_request_id, reviewer_user_id, action, created_at)
+		VALUES (?, ?, 'removed', ?)
+	`
+	now := time.Now().UTC()
+	for _, rr := range released {
+		if _, err := tx.ExecContext(ctx, addHistoryQuery, rr.PullRequestID, rr.UserID, now); err != nil {
+			return fmt.Errorf("record history error: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,716 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/database"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func newTestRepo(t *testing.T) (*repository, *sql.DB) {
+	t.Helper()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &repository{db: db}, db
+}
+
+func TestSetIsActiveSuccess(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO teams (team_id, team_name) VALUES (1, 'backend'), (2, 'platform');
+		INSERT INTO users (user_id, username) VALUES ('u1', 'Alice');
+		INSERT INTO team_members (team_id, user_id, joined_at) VALUES
+			(1, 'u1', '2024-01-01 00:00:00'),
+			(2, 'u1', '2024-02-01 00:00:00');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	user, err := repo.SetIsActive(ctx, "u1", false)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.IsActive || user.TeamName != "backend" || len(user.Teams) != 2 {
+		t.Errorf("unexpected user: %+v", user)
+	}
+}
+
+func TestSetTeamIsActive(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO teams (team_id, team_name) VALUES (1, 'backend'), (2, 'platform');
+		INSERT INTO users (user_id, username) VALUES ('u1', 'Alice');
+		INSERT INTO team_members (team_id, user_id) VALUES (1, 'u1'), (2, 'u1');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	user, err := repo.SetTeamIsActive(ctx, "u1", "platform", false)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if !user.IsActive {
+		t.Errorf("global flag must not change: %+v", user)
+	}
+	for _, m := range user.Teams {
+		if m.IsActive != (m.TeamName == "backend") {
+			t.Errorf("unexpected membership: %+v", m)
+		}
+	}
+
+	_, err = repo.SetTeamIsActive(ctx, "u1", "frontend", true)
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestSetIsActiveUserNotFound(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	_, err := repo.SetIsActive(context.Background(), "none", true)
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestGetReview(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO users (user_id, username) VALUES
+			('u1', 'Alice'),
+			('u2', 'Bob');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id) VALUES
+			('pr-1', 'Add search', 'u1', 1),
+			('pr-2', 'Fix bug', 'u1', 2);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id, assigned_at) VALUES
+			('pr-1', 'u2', datetime('now', '-2 hours')),
+			('pr-2', 'u2', datetime('now', '-3 hours'));
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	page, err := repo.GetReview(ctx, "u2", model.ReviewFilter{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(page.Reviews) != 2 || page.Next != nil {
+		t.Fatalf("expected 2 pull requests on one page, got %d", len(page.Reviews))
+	}
+	// the oldest assignment comes first
+	if page.Reviews[0].PullRequestID != "pr-2" || page.Reviews[0].Status != "MERGED" ||
+		page.Reviews[1].PullRequestID != "pr-1" || page.Reviews[1].Status != "OPEN" {
+		t.Errorf("unexpected reviews: %+v, %+v", page.Reviews[0], page.Reviews[1])
+	}
+	if age := page.Reviews[1].AgeSeconds; age < 7190 || age > 7300 {
+		t.Errorf("expected an age of about two hours, got %ds", age)
+	}
+
+	page, err = repo.GetReview(ctx, "u2", model.ReviewFilter{Status: "OPEN"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(page.Reviews) != 1 || page.Reviews[0].PullRequestID != "pr-1" {
+		t.Errorf("expected only pr-1 to be open, got %d reviews", len(page.Reviews))
+	}
+}
+
+func TestGetReviewPages(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO users (user_id, username) VALUES
+			('u1', 'Alice'),
+			('u2', 'Bob');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id) VALUES
+			('pr-1', 'One', 'u1', 1),
+			('pr-2', 'Two', 'u1', 1),
+			('pr-3', 'Three', 'u1', 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id, assigned_at) VALUES
+			('pr-1', 'u2', '2024-01-02 10:00:00'),
+			('pr-2', 'u2', '2024-01-01 10:00:00'),
+			('pr-3', 'u2', '2024-01-02 10:00:00');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	var got []string
+	filter := model.ReviewFilter{Limit: 2}
+	for {
+		page, err := repo.GetReview(ctx, "u2", filter)
+		if err != nil {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		for _, review := range page.Reviews {
+			got = append(got, review.PullRequestID)
+		}
+		if page.Next == nil {
+			break
+		}
+		filter.After = page.Next
+	}
+
+	if strings.Join(got, ",") != "pr-2,pr-1,pr-3" {
+		t.Errorf("unexpected order: %v", got)
+	}
+}
+
+func TestReviewCapacity(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO users (user_id, username) VALUES
+			('u1', 'Alice'),
+			('u2', 'Bob');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id) VALUES
+			('pr-1', 'Add search', 'u1', 1),
+			('pr-2', 'Fix bug', 'u1', 2);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES
+			('pr-1', 'u2'),
+			('pr-2', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	capacity := 2
+	user, _, err := repo.Update(ctx, &model.UserUpdate{UserID: "u2", MaxOpenReviews: &capacity})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.MaxOpenReviews == nil || *user.MaxOpenReviews != 2 {
+		t.Errorf("unexpected capacity: %v", user.MaxOpenReviews)
+	}
+
+	load, err := repo.GetReviewLoad(ctx, "u2")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if load.OpenReviews != 1 || *load.MaxOpenReviews != 2 {
+		t.Errorf("merged reviews must not count: %+v", load)
+	}
+
+	unlimited := 0
+	if _, _, err := repo.Update(ctx, &model.UserUpdate{UserID: "u2", MaxOpenReviews: &unlimited}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	load, err = repo.GetReviewLoad(ctx, "u2")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if load.MaxOpenReviews != nil {
+		t.Errorf("expected the limit lifted, got %d", *load.MaxOpenReviews)
+	}
+
+	if _, err := repo.GetReviewLoad(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestUpdateSkills(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`INSERT INTO users (user_id, username) VALUES ('u1', 'Alice')`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	user, _, err := repo.Update(ctx, &model.UserUpdate{UserID: "u1", Skills: []string{"terraform", "go"}})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.Username != "Alice" || len(user.Skills) != 2 || user.Skills[0] != "go" {
+		t.Errorf("unexpected user: %+v", user)
+	}
+
+	user, _, err = repo.Update(ctx, &model.UserUpdate{UserID: "u1", Username: "Alicia"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.Username != "Alicia" || len(user.Skills) != 2 {
+		t.Errorf("skills must survive a rename: %+v", user)
+	}
+
+	user, _, err = repo.Update(ctx, &model.UserUpdate{UserID: "u1", Skills: []string{}})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(user.Skills) != 0 {
+		t.Errorf("expected skills cleared, got %v", user.Skills)
+	}
+
+	user, _, err = repo.Update(ctx, &model.UserUpdate{UserID: "u1", Level: model.LevelSenior})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.Level != model.LevelSenior || user.Username != "Alicia" {
+		t.Errorf("unexpected user: %+v", user)
+	}
+
+	if _, _, err := repo.Update(ctx, &model.UserUpdate{UserID: "none", Username: "x"}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestReviewerPreferences(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO users (user_id, username) VALUES
+			('u1', 'Alice'),
+			('u2', 'Bob'),
+			('u3', 'Carol');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	prefs := &model.ReviewerPreferences{UserID: "u1", Blocked: []string{"u2"}, Preferred: []string{"u3"}}
+	if err := repo.SetReviewerPreferences(ctx, prefs); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	got, err := repo.GetReviewerPreferences(ctx, "u1")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got.Blocked) != 1 || got.Blocked[0] != "u2" || len(got.Preferred) != 1 || got.Preferred[0] != "u3" {
+		t.Errorf("unexpected preferences: %+v", got)
+	}
+
+	if err := repo.SetReviewerPreferences(ctx, &model.ReviewerPreferences{UserID: "u1"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	got, err = repo.GetReviewerPreferences(ctx, "u1")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got.Blocked) != 0 || len(got.Preferred) != 0 {
+		t.Errorf("expected preferences cleared, got %+v", got)
+	}
+
+	unknown := &model.ReviewerPreferences{UserID: "u1", Blocked: []string{"none"}}
+	if err := repo.SetReviewerPreferences(ctx, unknown); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := repo.GetReviewerPreferences(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestCreateGetAndListUsers(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`INSERT INTO teams (team_id, team_name) VALUES (1, 'backend'), (2, 'platform')`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	capacity := 2
+	created, err := repo.Create(ctx, &model.User{
+		TeamMember:     model.TeamMember{UserID: "u1", Username: "Alice", IsActive: true, Skills: []string{"go"}, Level: model.LevelSenior},
+		TeamName:       "backend",
+		MaxOpenReviews: &capacity,
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if created.TeamName != "backend" || created.Level != model.LevelSenior || len(created.Skills) != 1 || *created.MaxOpenReviews != 2 {
+		t.Errorf("unexpected user: %+v", created)
+	}
+
+	if _, err := repo.Create(ctx, &model.User{TeamMember: model.TeamMember{UserID: "u2", Username: "Bob"}, TeamName: "platform"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if _, err := repo.Create(ctx, &model.User{TeamMember: model.TeamMember{UserID: "u3", Username: "Carol", IsActive: true}}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	_, err = repo.Create(ctx, &model.User{TeamMember: model.TeamMember{UserID: "u4", Username: "Alice"}})
+	if !errors.Is(err, model.ErrUserExists) {
+		t.Errorf("expected ErrUserExists for a taken username, got: %v", err)
+	}
+	_, err = repo.Create(ctx, &model.User{TeamMember: model.TeamMember{UserID: "u4", Username: "Dan"}, TeamName: "frontend"})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown team, got: %v", err)
+	}
+	if _, err := repo.Get(ctx, "u4"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("a failed create must leave no user behind, got: %v", err)
+	}
+
+	user, err := repo.Get(ctx, "u3")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.TeamName != "" || len(user.Teams) != 0 {
+		t.Errorf("expected a user without teams, got: %+v", user)
+	}
+
+	ids := func(users []*model.User) string {
+		list := make([]string, 0, len(users))
+		for _, u := range users {
+			list = append(list, u.UserID)
+		}
+		return strings.Join(list, ",")
+	}
+
+	all, err := repo.List(ctx, model.UserFilter{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if ids(all) != "u1,u2,u3" {
+		t.Errorf("unexpected users: %s", ids(all))
+	}
+
+	active := true
+	filtered, err := repo.List(ctx, model.UserFilter{IsActive: &active})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if ids(filtered) != "u1,u3" {
+		t.Errorf("unexpected active users: %s", ids(filtered))
+	}
+
+	filtered, err = repo.List(ctx, model.UserFilter{TeamName: "platform"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if ids(filtered) != "u2" {
+		t.Errorf("unexpected platform users: %s", ids(filtered))
+	}
+}
+
+func TestUpdateMovesTeam(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO teams (team_id, team_name) VALUES (1, 'backend'), (2, 'platform'), (3, 'data');
+		INSERT INTO users (user_id, username) VALUES ('u1', 'Alice'), ('u2', 'Bob');
+		INSERT INTO team_members (team_id, user_id, joined_at) VALUES
+			(1, 'u2', '2024-01-01 00:00:00'),
+			(3, 'u2', '2024-02-01 00:00:00');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id) VALUES
+			('pr-1', 'Open', 'u1', 1, 1),
+			('pr-2', 'Merged', 'u1', 2, 1),
+			('pr-3', 'Data', 'u1', 1, 3);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES
+			('pr-1', 'u2'),
+			('pr-2', 'u2'),
+			('pr-3', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	user, released, err := repo.Update(ctx, &model.UserUpdate{UserID: "u2", TeamName: "platform"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.TeamName != "platform" || len(user.Teams) != 2 || user.Teams[1].TeamName != "data" {
+		t.Errorf("expected platform to replace backend as the primary team: %+v", user.Teams)
+	}
+	if len(released) != 1 || released[0].PullRequestID != "pr-1" {
+		t.Errorf("expected only the open backend review to be released, got: %v", released)
+	}
+
+	if _, _, err := repo.Update(ctx, &model.UserUpdate{UserID: "u2", TeamName: "frontend"}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, _, err := repo.Update(ctx, &model.UserUpdate{UserID: "u2", Username: "Alice"}); !errors.Is(err, model.ErrUserExists) {
+		t.Errorf("expected ErrUserExists, got: %v", err)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO users (user_id, username) VALUES ('u1', 'Alice'), ('u2', 'Bob');
+		INSERT INTO user_skills (user_id, skill) VALUES ('u2', 'rust');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id) VALUES
+			('pr-1', 'Open', 'u1', 1),
+			('pr-2', 'Merged', 'u2', 2),
+			('pr-3', 'Merged', 'u1', 2);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id) VALUES
+			('pr-1', 'u2'),
+			('pr-3', 'u2');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	if _, err := repo.Delete(ctx, "u1"); !errors.Is(err, model.ErrHasOpenPrs) {
+		t.Errorf("expected ErrHasOpenPrs, got: %v", err)
+	}
+
+	deletion, err := repo.Delete(ctx, "u2")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(deletion.ReleasedReviews) != 1 || deletion.ReleasedReviews[0].PullRequestID != "pr-1" {
+		t.Errorf("unexpected released reviews: %v", deletion.ReleasedReviews)
+	}
+
+	var prs, seats, logged int
+	counts := `
+		SELECT
+			(SELECT COUNT(*) FROM pull_requests),
+			(SELECT COUNT(*) FROM pull_request_reviewers WHERE reviewer_user_id = 'u2'),
+			(SELECT COUNT(*) FROM reviewer_history WHERE reviewer_user_id = 'u2' AND action = 'removed')
+	`
+	if err := db.QueryRow(counts).Scan(&prs, &seats, &logged); err != nil {
+		t.Fatalf("failed to count: %v", err)
+	}
+	if prs != 3 || seats != 1 || logged != 1 {
+		t.Errorf("expected pull requests and the merged seat kept and the release logged, got %d, %d, %d", prs, seats, logged)
+	}
+
+	// the record stays for the pull requests, but the user is gone
+	var retired bool
+	if err := db.QueryRow("SELECT deleted_at IS NOT NULL AND NOT is_active FROM users WHERE user_id = 'u2'").Scan(&retired); err != nil {
+		t.Fatalf("failed to load the record: %v", err)
+	}
+	if !retired {
+		t.Error("expected the record to be marked deleted")
+	}
+	if _, err := repo.Get(ctx, "u2"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	users, err := repo.List(ctx, model.UserFilter{})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(users) != 1 || users[0].UserID != "u1" {
+		t.Errorf("expected the deleted user left out, got %+v", users)
+	}
+	if _, err := repo.SetIsActive(ctx, "u2", true); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := repo.Delete(ctx, "u2"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected a repeated delete to find nothing, got: %v", err)
+	}
+
+	if _, err := repo.Delete(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	// the user_id is free to take over, the username of another user is not
+	if _, err := repo.Create(ctx, &model.User{TeamMember: model.TeamMember{UserID: "u2", Username: "Alice", IsActive: true}}); !errors.Is(err, model.ErrUserExists) {
+		t.Errorf("expected ErrUserExists, got: %v", err)
+	}
+	user, err := repo.Create(ctx, &model.User{
+		TeamMember: model.TeamMember{UserID: "u2", Username: "Robert", IsActive: true, Skills: []string{"go"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if user.Username != "Robert" || !user.IsActive || len(user.Skills) != 1 || user.Skills[0] != "go" {
+		t.Errorf("expected the user back with the new profile, got %+v", user)
+	}
+	if _, err := repo.Create(ctx, &model.User{TeamMember: model.TeamMember{UserID: "u2", Username: "Bob"}}); !errors.Is(err, model.ErrUserExists) {
+		t.Errorf("expected ErrUserExists, got: %v", err)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`INSERT INTO users (user_id, username) VALUES ('u1', 'Alice')`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	got, err := repo.GetSchedule(ctx, "u1")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if *got != (model.WorkSchedule{UserID: "u1", Timezone: "UTC"}) {
+		t.Errorf("expected UTC without hours by default, got %+v", got)
+	}
+
+	schedule := &model.WorkSchedule{UserID: "u1", Timezone: "Asia/Tokyo", WorkStart: "10:00", WorkEnd: "19:00"}
+	if err := repo.SetSchedule(ctx, schedule); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, _ := repo.GetSchedule(ctx, "u1"); *got != *schedule {
+		t.Errorf("unexpected schedule: %+v", got)
+	}
+
+	if err := repo.SetSchedule(ctx, &model.WorkSchedule{UserID: "u1"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, _ := repo.GetSchedule(ctx, "u1"); got.Timezone != "UTC" || got.WorkStart != "" {
+		t.Errorf("expected the schedule reset, got %+v", got)
+	}
+
+	if err := repo.SetSchedule(ctx, &model.WorkSchedule{UserID: "none"}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestReviewQueuesAndReminders(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO teams (team_id, team_name) VALUES (1, 'backend');
+		INSERT INTO users (user_id, username, is_active) VALUES
+			('u1', 'Alice', TRUE),
+			('u2', 'Bob', TRUE),
+			('u3', 'Carol', FALSE);
+		INSERT INTO team_members (team_id, user_id) VALUES (1, 'u2');
+		INSERT INTO team_holidays (team_id, holiday) VALUES (1, '2024-12-25');
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status_id, team_id) VALUES
+			('pr-1', 'Old', 'u1', 1, 1),
+			('pr-2', 'New', 'u1', 1, 1),
+			('pr-3', 'Merged', 'u1', 2, 1),
+			('pr-4', 'Reviewed', 'u1', 1, 1);
+		INSERT INTO pull_request_reviewers (pull_request_id, reviewer_user_id, assigned_at, reviewed_at) VALUES
+			('pr-1', 'u2', '2024-03-01 10:00:00', NULL),
+			('pr-2', 'u2', '2024-03-04 10:00:00', NULL),
+			('pr-3', 'u2', '2024-03-01 10:00:00', NULL),
+			('pr-4', 'u2', '2024-03-01 10:00:00', '2024-03-01 11:00:00'),
+			('pr-1', 'u3', '2024-03-01 10:00:00', NULL);
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	queues, err := repo.ReviewQueues(ctx, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(queues) != 1 || queues[0].UserID != "u2" {
+		t.Fatalf("expected only u2's queue, got %+v", queues)
+	}
+	q := queues[0]
+	if len(q.Reviews) != 1 || q.Reviews[0].PullRequestID != "pr-1" || q.Reviews[0].TeamName != "backend" {
+		t.Errorf("unexpected reviews: %+v", q.Reviews)
+	}
+	if len(q.Holidays) != 1 || q.Holidays[0] != "2024-12-25" || q.Schedule.Timezone != "UTC" {
+		t.Errorf("unexpected calendar: %+v %v", q.Schedule, q.Holidays)
+	}
+
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	if ok, previous, err := repo.MarkReminded(ctx, "u2", now, now.Add(-24*time.Hour)); err != nil || !ok || previous != nil {
+		t.Fatalf("expected the first reminder to go out, got %v, %v, %v", ok, previous, err)
+	}
+	later := now.Add(time.Hour)
+	if ok, _, _ := repo.MarkReminded(ctx, "u2", later, later.Add(-24*time.Hour)); ok {
+		t.Error("expected a second reminder within a day to be held back")
+	}
+	nextDay := now.Add(24 * time.Hour)
+	ok, previous, err := repo.MarkReminded(ctx, "u2", nextDay, nextDay.Add(-24*time.Hour))
+	if err != nil || !ok {
+		t.Fatalf("expected a reminder a day later to go out, got %v, %v", ok, err)
+	}
+	if previous == nil || !previous.Equal(now) {
+		t.Errorf("expected the first reminder as the previous one, got %v", previous)
+	}
+
+	// an undelivered reminder is taken back, but only the one recorded
+	if err := repo.UnmarkReminded(ctx, "u2", now, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	retry := nextDay.Add(time.Hour)
+	if ok, _, _ := repo.MarkReminded(ctx, "u2", retry, retry.Add(-24*time.Hour)); ok {
+		t.Error("expected a stale unmark to leave the reminder alone")
+	}
+	if err := repo.UnmarkReminded(ctx, "u2", nextDay, previous); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the first reminder is back in place, not cleared
+	if ok, _, _ := repo.MarkReminded(ctx, "u2", now.Add(2*time.Hour), now.Add(-time.Hour)); ok {
+		t.Error("expected the restored reminder to hold back an early one")
+	}
+	if ok, _, _ := repo.MarkReminded(ctx, "u2", retry, retry.Add(-24*time.Hour)); !ok {
+		t.Error("expected the retry to go out once the reminder was taken back")
+	}
+}
+
+func TestNotificationSettings(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := db.Exec(`INSERT INTO users (user_id, username) VALUES ('u1', 'Alice')`); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	update := &model.UserUpdate{UserID: "u1", Email: "alice@example.com"}
+	if user, _, err := repo.Update(ctx, update); err != nil || user.Email != "alice@example.com" {
+		t.Fatalf("unexpected update: %+v, %v", user, err)
+	}
+
+	settings := &model.NotificationSettings{UserID: "u1", OptOut: []string{model.NoticeDigest, model.NoticeAssigned}}
+	if err := repo.SetNotificationSettings(ctx, settings); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	got, err := repo.GetNotificationSettings(ctx, "u1")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got.Username != "Alice" || got.Email != "alice@example.com" ||
+		strings.Join(got.OptOut, ",") != "assigned,digest" {
+		t.Errorf("unexpected settings: %+v", got)
+	}
+
+	if err := repo.SetNotificationSettings(ctx, &model.NotificationSettings{UserID: "u1"}); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got, _ := repo.GetNotificationSettings(ctx, "u1"); len(got.OptOut) != 0 {
+		t.Errorf("expected the opt-outs cleared, got %v", got.OptOut)
+	}
+
+	if err := repo.SetNotificationSettings(ctx, &model.NotificationSettings{UserID: "none"}); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := repo.GetNotificationSettings(ctx, "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestGetChatHandles(t *testing.T) {
+	repo, db := newTestRepo(t)
+	ctx := context.Background()
+
+	seed := `
+		INSERT INTO users (user_id, username, chat_handle) VALUES
+			('u1', 'Alice', 'alice'),
+			('u2', 'Bob', NULL),
+			('u3', 'Carol', '<@U024BE7LH>');
+	`
+	if _, err := db.Exec(seed); err != nil {
+		t.Fatalf("failed to seed: %v", err)
+	}
+
+	handles, err := repo.GetChatHandles(ctx, []string{"u1", "u2", "u3", "none"})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(handles) != 2 || handles["u1"] != "alice" || handles["u3"] != "<@U024BE7LH>" {
+		t.Errorf("unexpected handles: %v", handles)
+	}
+}
@@ -0,0 +1,180 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestReviewerStatsByTeamAndRange(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	mock.
+		ExpectQuery("SELECT team_id FROM teams WHERE team_name = \\$1").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(1))
+
+	mock.
+		ExpectQuery("WHERE p.team_id = \\$1 AND rv.assigned_at >= \\$2 AND rv.assigned_at < \\$3").
+		WithArgs(1, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "assigned", "open", "completed", "reassigned_away"}).
+			AddRow("u1", "Alice", 0, 0, 0, 1).
+			AddRow("u2", "Bob", 3, 1, 2, 0))
+
+	stats, err := repo.ReviewerStats(context.Background(), model.StatsFilter{TeamName: "backend", From: &from, To: &to})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(stats) != 2 || stats[1].Assigned != 3 || stats[0].ReassignedAway != 1 {
+		t.Errorf("unexpected stats: %+v, %+v", stats[0], stats[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReviewerStatsTeamNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectQuery("SELECT team_id FROM teams WHERE team_name = \\$1").
+		WithArgs("none").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}))
+
+	_, err = repo.ReviewerStats(context.Background(), model.StatsFilter{TeamName: "none"})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPullRequestTimes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	reviewedAt := from.Add(2 * time.Hour)
+
+	mock.
+		ExpectQuery("SELECT MIN\\(rv.reviewed_at\\) .+ WHERE pr.createdAt >= \\$1").
+		WithArgs(from).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "team_name", "author_id", "createdAt", "first_review", "mergedAt"}).
+			AddRow("pr-1", "backend", "u1", from, reviewedAt, nil).
+			AddRow("pr-2", "", "u2", from, nil, nil))
+
+	times, err := repo.PullRequestTimes(context.Background(), model.StatsFilter{From: &from})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(times) != 2 || times[0].FirstReviewAt == nil || !times[0].FirstReviewAt.Equal(reviewedAt) ||
+		times[0].MergedAt != nil || times[1].FirstReviewAt != nil {
+		t.Errorf("unexpected times: %+v, %+v", times[0], times[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExportPullRequests(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.
+		ExpectQuery("ARRAY\\(.+ WHERE pr.createdAt < \\$1").
+		WithArgs(to).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "team_name", "author_id", "status_name", "createdAt", "mergedAt", "reviewers"}).
+			AddRow("pr-1", "Add search", "backend", "u1", "OPEN", createdAt, nil, "{u2,u3}").
+			AddRow("pr-2", "Add readme", "", "u1", "MERGED", createdAt, createdAt, "{}"))
+
+	var got []*model.PullRequestExport
+	err = repo.ExportPullRequests(context.Background(), model.StatsFilter{To: &to}, func(p *model.PullRequestExport) error {
+		got = append(got, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(got) != 2 || len(got[0].Reviewers) != 2 || got[0].MergedAt != nil ||
+		got[1].Reviewers == nil || len(got[1].Reviewers) != 0 || got[1].MergedAt == nil {
+		t.Errorf("unexpected pull requests: %+v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestExportAssignmentsPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	assignedAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	columns := []string{"pull_request_id", "pull_request_name", "team_name", "author_id", "status_name", "reviewer_user_id", "assigned_at", "reviewed_at", "overdue_at"}
+
+	first := sqlmock.NewRows(columns)
+	for i := range exportPageSize {
+		first.AddRow(fmt.Sprintf("pr-%03d", i), "Bulk", "backend", "u1", "OPEN", "u2", assignedAt, nil, nil)
+	}
+	mock.
+		ExpectQuery("ORDER BY rv.assigned_at, rv.pull_request_id, rv.reviewer_user_id LIMIT 500").
+		WillReturnRows(first)
+	mock.
+		ExpectQuery("WHERE \\(rv.assigned_at, rv.pull_request_id, rv.reviewer_user_id\\) > \\(\\$1, \\$2, \\$3\\)").
+		WithArgs(assignedAt, "pr-499", "u2").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("pr-500", "Bulk", "backend", "u1", "OPEN", "u2", assignedAt, nil, nil))
+
+	var got int
+	err = repo.ExportAssignments(context.Background(), model.StatsFilter{}, func(*model.AssignmentExport) error {
+		got++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if got != exportPageSize+1 {
+		t.Errorf("expected %d assignments, got %d", exportPageSize+1, got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -3,9 +3,14 @@ package team
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
 	def "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/roster"
+	"github.com/lib/pq"
+	"sort"
+	"strings"
 )
 
 var _ def.TeamRepository = (*repository)(nil)
@@ -26,14 +31,14 @@ func (r *repository) Add(ctx context.Context, team *model.Team) error {
 	defer tx.Rollback()
 
 	createTeamQuery := `
-        INSERT INTO teams (team_name)
-        VALUES ($1)
+        INSERT INTO teams (team_name, min_senior_reviewers)
+        VALUES ($1, $2)
         ON CONFLICT (team_name) DO NOTHING
         RETURNING team_id
     `
 
 	var teamID int
-	err = tx.QueryRowContext(ctx, createTeamQuery, team.TeamName).Scan(&teamID)
+	err = tx.QueryRowContext(ctx, createTeamQuery, team.TeamName, team.MinSeniorReviewers).Scan(&teamID)
 	if err == sql.ErrNoRows {
 		return model.ErrTeamExists
 	}
@@ -42,62 +47,875 @@ func (r *repository) Add(ctx context.Context, team *model.Team) error {
 		return fmt.Errorf("failed to create team: %v", err)
 	}
 
-	updateOrInsertQuery := `
-		INSERT INTO users (user_id, username, team_name, is_active)
+	if err := upsertMembers(ctx, tx, teamID, team.Members); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get returns the team with its members. A team without members is returned
+// with an empty roster; only an unknown team is reported as not found.
+func (r *repository) Get(ctx context.Context, teamName string) (*model.Team, error) {
+	query := `
+		SELECT
+			t.min_senior_reviewers,
+			u.user_id,
+			u.username,
+			tm.is_active AND u.is_active,
+			ARRAY(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id ORDER BY s.skill),
+			COALESCE(u.level, '')
+		FROM teams t
+		LEFT JOIN team_members tm
+			ON tm.team_id = t.team_id
+		LEFT JOIN users u
+			ON u.user_id = tm.user_id
+		WHERE t.team_name = $1
+		ORDER BY u.user_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %v", err)
+	}
+	defer rows.Close()
+
+	var team *model.Team
+	for rows.Next() {
+		var (
+			minSeniors       int
+			userID, username sql.NullString
+			isActive         sql.NullBool
+			skills           []string
+			level            string
+		)
+		if err := rows.Scan(&minSeniors, &userID, &username, &isActive, pq.Array(&skills), &level); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %v", err)
+		}
+
+		if team == nil {
+			team = &model.Team{
+				TeamName:           teamName,
+				Members:            make([]*model.TeamMember, 0),
+				MinSeniorReviewers: minSeniors,
+			}
+		}
+		if userID.Valid {
+			team.Members = append(team.Members, &model.TeamMember{
+				UserID:   userID.String,
+				Username: username.String,
+				IsActive: isActive.Bool,
+				Skills:   skills,
+				Level:    level,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %v", err)
+	}
+
+	if team == nil {
+		return nil, model.ErrNotFound
+	}
+	return team, nil
+}
+
+func (r *repository) List(ctx context.Context) ([]*model.Team, error) {
+	query := `
+		SELECT
+			t.team_name,
+			t.min_senior_reviewers,
+			u.user_id,
+			u.username,
+			tm.is_active AND u.is_active,
+			ARRAY(SELECT s.skill FROM user_skills s WHERE s.user_id = u.user_id ORDER BY s.skill),
+			COALESCE(u.level, '')
+		FROM teams t
+		LEFT JOIN team_members tm
+			ON tm.team_id = t.team_id
+		LEFT JOIN users u
+			ON u.user_id = tm.user_id
+		ORDER BY t.team_name, u.user_id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %v", err)
+	}
+	defer rows.Close()
+
+	teams := make([]*model.Team, 0)
+	for rows.Next() {
+		var (
+			teamName         string
+			minSeniors       int
+			userID, username sql.NullString
+			isActive         sql.NullBool
+			skills           []string
+			level            string
+		)
+		if err := rows.Scan(&teamName, &minSeniors, &userID, &username, &isActive, pq.Array(&skills), &level); err != nil {
+			return nil, fmt.Errorf("failed to scan team: %v", err)
+		}
+
+		if len(teams) == 0 || teams[len(teams)-1].TeamName != teamName {
+			teams = append(teams, &model.Team{
+				TeamName:           teamName,
+				Members:            make([]*model.TeamMember, 0),
+				MinSeniorReviewers: minSeniors,
+			})
+		}
+		if userID.Valid {
+			team := teams[len(teams)-1]
+			team.Members = append(team.Members, &model.TeamMember{
+				UserID:   userID.String,
+				Username: username.String,
+				IsActive: isActive.Bool,
+				Skills:   skills,
+				Level:    level,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate teams: %v", err)
+	}
+
+	return teams, nil
+}
+
+func (r *repository) Update(ctx context.Context, update *model.TeamUpdate) ([]*model.ReleasedReview, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := lockTeam(ctx, tx, update.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.NewTeamName != "" && update.NewTeamName != update.TeamName {
+		renameTeamQuery := `
+			UPDATE teams
+			SET team_name = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE team_id = $2 AND NOT EXISTS (
+				SELECT 1 FROM teams WHERE team_name = $1
+			)
+		`
+		result, err := tx.ExecContext(ctx, renameTeamQuery, update.NewTeamName, teamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rename team: %v", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return nil, model.ErrTeamExists
+		}
+	}
+
+	if update.MinSeniorReviewers != nil {
+		setRuleQuery := `
+			UPDATE teams
+			SET min_senior_reviewers = $1, updated_at = CURRENT_TIMESTAMP
+			WHERE team_id = $2
+		`
+		if _, err := tx.ExecContext(ctx, setRuleQuery, *update.MinSeniorReviewers, teamID); err != nil {
+			return nil, fmt.Errorf("failed to update seniority rule: %v", err)
+		}
+	}
+
+	if err := upsertMembers(ctx, tx, teamID, update.AddMembers); err != nil {
+		return nil, err
+	}
+
+	released := make([]*model.ReleasedReview, 0)
+	if len(update.RemoveMembers) > 0 {
+		removeQuery := `
+			DELETE FROM team_members
+			WHERE team_id = $1 AND user_id = ANY($2)
+			RETURNING user_id
+		`
+		removed, err := removeMembers(ctx, tx, removeQuery, teamID, pq.Array(update.RemoveMembers))
+		if err != nil {
+			return nil, err
+		}
+
+		released, err = releaseOpenReviews(ctx, tx, teamID, removed)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return released, nil
+}
+
+func (r *repository) Delete(ctx context.Context, teamName string) ([]*model.ReleasedReview, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := lockTeam(ctx, tx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	removeQuery := `
+		DELETE FROM team_members
+		WHERE team_id = $1
+		RETURNING user_id
+	`
+	removed, err := removeMembers(ctx, tx, removeQuery, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := releaseOpenReviews(ctx, tx, teamID, removed)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteTeamQuery := `
+		DELETE FROM teams
+		WHERE team_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, deleteTeamQuery, teamID); err != nil {
+		return nil, fmt.Errorf("failed to delete team: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return released, nil
+}
+
+// SetOwnershipRules replaces the team's ownership rules as a whole.
+func (r *repository) SetOwnershipRules(ctx context.Context, teamName string, rules []*model.OwnershipRule) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := lockTeam(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+
+	clearRulesQuery := `
+		DELETE FROM team_ownership_rules
+		WHERE team_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, clearRulesQuery, teamID); err != nil {
+		return fmt.Errorf("failed to clear ownership rules: %v", err)
+	}
+
+	addRuleQuery := `
+		INSERT INTO team_ownership_rules (team_id, position, pattern, owners)
 		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (user_id) 
-		DO UPDATE SET 
+	`
+	for _, rule := range rules {
+		_, err := tx.ExecContext(ctx, addRuleQuery,
+			teamID,
+			rule.Line,
+			rule.Pattern,
+			strings.Join(rule.Owners, " "))
+		if err != nil {
+			return fmt.Errorf("failed to add ownership rule: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+func (r *repository) GetOwnershipRules(ctx context.Context, teamName string) ([]*model.OwnershipRule, error) {
+	query := `
+		SELECT
+			r.position,
+			r.pattern,
+			r.owners
+		FROM teams t
+		LEFT JOIN team_ownership_rules r
+			ON r.team_id = t.team_id
+		WHERE t.team_name = $1
+		ORDER BY r.position
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ownership rules: %v", err)
+	}
+	defer rows.Close()
+
+	found := false
+	rules := make([]*model.OwnershipRule, 0)
+	for rows.Next() {
+		var (
+			line            sql.NullInt64
+			pattern, owners sql.NullString
+		)
+		if err := rows.Scan(&line, &pattern, &owners); err != nil {
+			return nil, fmt.Errorf("failed to scan ownership rule: %v", err)
+		}
+		found = true
+		if line.Valid {
+			rules = append(rules, &model.OwnershipRule{
+				Line:    int(line.Int64),
+				Pattern: pattern.String,
+				Owners:  strings.Fields(owners.String),
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ownership rules: %v", err)
+	}
+
+	if !found {
+		return nil, model.ErrNotFound
+	}
+	return rules, nil
+}
+
+// SetFallbackTeams replaces the chain of teams that lend reviewers when the
+// team itself cannot fill the reviewer seats. Unknown teams are reported as
+// not found.
+func (r *repository) SetFallbackTeams(ctx context.Context, teamName string, fallbacks []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := lockTeam(ctx, tx, teamName)
+	if err != nil {
+		return err
+	}
+
+	clearFallbacksQuery := `
+		DELETE FROM team_fallbacks
+		WHERE team_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, clearFallbacksQuery, teamID); err != nil {
+		return fmt.Errorf("failed to clear fallback teams: %v", err)
+	}
+
+	addFallbackQuery := `
+		INSERT INTO team_fallbacks (team_id, position, fallback_team_id)
+		SELECT $1, $2, team_id
+		FROM teams
+		WHERE team_name = $3
+	`
+	for i, fallback := range fallbacks {
+		result, err := tx.ExecContext(ctx, addFallbackQuery, teamID, i+1, fallback)
+		if err != nil {
+			return fmt.Errorf("failed to add fallback team: %v", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return model.ErrNotFound
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+func (r *repository) GetFallbackTeams(ctx context.Context, teamName string) ([]string, error) {
+	query := `
+		SELECT f.team_name
+		FROM teams t
+		LEFT JOIN team_fallbacks tf
+			ON tf.team_id = t.team_id
+		LEFT JOIN teams f
+			ON f.team_id = tf.fallback_team_id
+		WHERE t.team_name = $1
+		ORDER BY tf.position
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fallback teams: %v", err)
+	}
+	defer rows.Close()
+
+	found := false
+	fallbacks := make([]string, 0)
+	for rows.Next() {
+		var fallback sql.NullString
+		if err := rows.Scan(&fallback); err != nil {
+			return nil, fmt.Errorf("failed to scan fallback team: %v", err)
+		}
+		found = true
+		if fallback.Valid {
+			fallbacks = append(fallbacks, fallback.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fallback teams: %v", err)
+	}
+
+	if !found {
+		return nil, model.ErrNotFound
+	}
+	return fallbacks, nil
+}
+
+// SetReviewSLA stores the team's review SLA. Zero minutes turn it off.
+func (r *repository) SetReviewSLA(ctx context.Context, sla *model.ReviewSLA) error {
+	query := `
+		UPDATE teams
+		SET review_sla_minutes = NULLIF($1, 0), sla_auto_reassign = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE team_name = $3
+	`
+	result, err := r.db.ExecContext(ctx, query, sla.Minutes, sla.AutoReassign, sla.TeamName)
+	if err != nil {
+		return fmt.Errorf("failed to set review sla: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetReviewSLA(ctx context.Context, teamName string) (*model.ReviewSLA, error) {
+	query := `
+		SELECT COALESCE(review_sla_minutes, 0), sla_auto_reassign
+		FROM teams
+		WHERE team_name = $1
+	`
+	sla := &model.ReviewSLA{TeamName: teamName}
+	err := r.db.QueryRowContext(ctx, query, teamName).Scan(&sla.Minutes, &sla.AutoReassign)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review sla: %v", err)
+	}
+	return sla, nil
+}
+
+// SetChat points the team's messages at an incoming webhook. An empty URL
+// stops them.
+func (r *repository) SetChat(ctx context.Context, chat *model.TeamChat) error {
+	query := `
+		UPDATE teams
+		SET chat_webhook_url = NULLIF($1, ''), chat_direct_messages = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE team_name = $3
+	`
+	result, err := r.db.ExecContext(ctx, query, chat.WebhookURL, chat.DirectMessages, chat.TeamName)
+	if err != nil {
+		return fmt.Errorf("failed to set team chat: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %v", err)
+	}
+
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repository) GetChat(ctx context.Context, teamName string) (*model.TeamChat, error) {
+	query := `
+		SELECT COALESCE(chat_webhook_url, ''), chat_direct_messages
+		FROM teams
+		WHERE team_name = $1
+	`
+	chat := &model.TeamChat{TeamName: teamName}
+	err := r.db.QueryRowContext(ctx, query, teamName).Scan(&chat.WebhookURL, &chat.DirectMessages)
+	if err == sql.ErrNoRows {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team chat: %v", err)
+	}
+	return chat, nil
+}
+
+// SetHolidays replaces the team's holiday calendar.
+func (r *repository) SetHolidays(ctx context.Context, holidays *model.TeamHolidays) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := lockTeam(ctx, tx, holidays.TeamName)
+	if err != nil {
+		return err
+	}
+
+	clearHolidaysQuery := `
+		DELETE FROM team_holidays
+		WHERE team_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, clearHolidaysQuery, teamID); err != nil {
+		return fmt.Errorf("failed to clear holidays: %v", err)
+	}
+
+	addHolidayQuery := `
+		INSERT INTO team_holidays (team_id, holiday, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (team_id, holiday)
+		DO UPDATE SET name = EXCLUDED.name
+	`
+	for _, holiday := range holidays.Holidays {
+		if _, err := tx.ExecContext(ctx, addHolidayQuery, teamID, holiday.Date, holiday.Name); err != nil {
+			return fmt.Errorf("failed to add holiday: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	return nil
+}
+
+func (r *repository) GetHolidays(ctx context.Context, teamName string) (*model.TeamHolidays, error) {
+	query := `
+		SELECT to_char(h.holiday, 'YYYY-MM-DD'), h.name
+		FROM teams t
+		LEFT JOIN team_holidays h
+			ON h.team_id = t.team_id
+		WHERE t.team_name = $1
+		ORDER BY h.holiday
+	`
+	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holidays: %v", err)
+	}
+	defer rows.Close()
+
+	var holidays *model.TeamHolidays
+	for rows.Next() {
+		var date, name sql.NullString
+		if err := rows.Scan(&date, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan holiday: %v", err)
+		}
+		if holidays == nil {
+			holidays = &model.TeamHolidays{TeamName: teamName, Holidays: make([]*model.Holiday, 0)}
+		}
+		if date.Valid {
+			holidays.Holidays = append(holidays.Holidays, &model.Holiday{Date: date.String, Name: name.String})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate holidays: %v", err)
+	}
+
+	if holidays == nil {
+		return nil, model.ErrNotFound
+	}
+	return holidays, nil
+}
+
+// Import replaces the members of the roster's teams in one transaction,
+// creating the teams that do not exist yet and changing the seniority rules
+// the roster sets. Users dropped from a team lose their reviews on its open
+// pull requests; other teams are left untouched. The diff is taken from the
+// locked teams, so it is exactly what the import changes. With dryRun the
+// transaction is rolled back, and the result only previews the import.
+func (r *repository) Import(ctx context.Context, teams []*model.RosterTeam, dryRun bool) (*model.RosterDiff, []*model.ReleasedReview, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin tx: %v", err)
+	}
+	defer tx.Rollback()
+
+	// teams are locked in name order, so that concurrent imports do not
+	// deadlock
+	names := make([]string, 0, len(teams))
+	for _, team := range teams {
+		names = append(names, team.TeamName)
+	}
+	sort.Strings(names)
+	current := make([]*model.Team, 0, len(teams))
+	teamIDs := make(map[string]int, len(teams))
+	rules := make(map[string]int, len(teams))
+	for _, name := range names {
+		team, teamID, err := lockRosterTeam(ctx, tx, name)
+		if errors.Is(err, model.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		current = append(current, team)
+		teamIDs[name] = teamID
+		rules[name] = team.MinSeniorReviewers
+	}
+	diff := roster.Diff(current, teams)
+
+	createTeamQuery := `
+		INSERT INTO teams (team_name, min_senior_reviewers)
+		VALUES ($1, $2)
+		RETURNING team_id
+	`
+	setRuleQuery := `
+		UPDATE teams
+		SET min_senior_reviewers = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE team_id = $2
+	`
+	removeQuery := `
+		DELETE FROM team_members
+		WHERE team_id = $1 AND NOT (user_id = ANY($2))
+		RETURNING user_id
+	`
+	released := make([]*model.ReleasedReview, 0)
+	for _, team := range teams {
+		teamID, exists := teamIDs[team.TeamName]
+		switch {
+		case !exists:
+			minSeniors := 0
+			if team.MinSeniorReviewers != nil {
+				minSeniors = *team.MinSeniorReviewers
+			}
+			if err := tx.QueryRowContext(ctx, createTeamQuery, team.TeamName, minSeniors).Scan(&teamID); err != nil {
+				return nil, nil, fmt.Errorf("failed to create team: %v", err)
+			}
+		case team.MinSeniorReviewers != nil && *team.MinSeniorReviewers != rules[team.TeamName]:
+			if _, err := tx.ExecContext(ctx, setRuleQuery, *team.MinSeniorReviewers, teamID); err != nil {
+				return nil, nil, fmt.Errorf("failed to update seniority rule: %v", err)
+			}
+		}
+
+		if err := upsertMembers(ctx, tx, teamID, team.Members); err != nil {
+			return nil, nil, err
+		}
+
+		kept := make([]string, 0, len(team.Members))
+		for _, m := range team.Members {
+			kept = append(kept, m.UserID)
+		}
+		removed, err := removeMembers(ctx, tx, removeQuery, teamID, pq.Array(kept))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rr, err := releaseOpenReviews(ctx, tx, teamID, removed)
+		if err != nil {
+			return nil, nil, err
+		}
+		released = append(released, rr...)
+	}
+
+	if dryRun {
+		return diff, released, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit: %v", err)
+	}
+	return diff, released, nil
+}
+
+// lockRosterTeam locks the team and reads its seniority rule and the
+// activity of its members.
+func lockRosterTeam(ctx context.Context, tx *sql.Tx, teamName string) (*model.Team, int, error) {
+	getTeamQuery := `
+		SELECT team_id, min_senior_reviewers
+		FROM teams
+		WHERE team_name = $1
+		FOR UPDATE
+	`
+	team := &model.Team{TeamName: teamName, Members: make([]*model.TeamMember, 0)}
+	var teamID int
+	err := tx.QueryRowContext(ctx, getTeamQuery, teamName).Scan(&teamID, &team.MinSeniorReviewers)
+	if err == sql.ErrNoRows {
+		return nil, 0, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get team: %v", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT user_id, is_active FROM team_members WHERE team_id = $1", teamID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get team members: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		m := &model.TeamMember{}
+		if err := rows.Scan(&m.UserID, &m.IsActive); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan team member: %v", err)
+		}
+		team.Members = append(team.Members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate team members: %v", err)
+	}
+	return team, teamID, nil
+}
+
+func lockTeam(ctx context.Context, tx *sql.Tx, teamName string) (int, error) {
+	getTeamQuery := `
+		SELECT team_id
+		FROM teams
+		WHERE team_name = $1
+		FOR UPDATE
+	`
+	var teamID int
+	err := tx.QueryRowContext(ctx, getTeamQuery, teamName).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		return 0, model.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get team: %v", err)
+	}
+	return teamID, nil
+}
+
+// upsertMembers creates missing users, brings deleted ones back as active
+// users and adds them to the team. Users that already belong to other teams
+// keep those memberships.
+func upsertMembers(ctx context.Context, tx *sql.Tx, teamID int, members []*model.TeamMember) error {
+	updateOrInsertQuery := `
+		INSERT INTO users (user_id, username, level)
+		VALUES ($1, $2, NULLIF($3, ''))
+		ON CONFLICT (user_id)
+		DO UPDATE SET
 			username = EXCLUDED.username,
-			team_name = EXCLUDED.team_name,
-			is_active = EXCLUDED.is_active,
+			level = COALESCE(EXCLUDED.level, users.level),
+			is_active = users.is_active OR users.deleted_at IS NOT NULL,
+			deleted_at = NULL,
 			updated_at = CURRENT_TIMESTAMP
 	`
+	addMembershipQuery := `
+		INSERT INTO team_members (team_id, user_id, is_active)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (team_id, user_id)
+		DO UPDATE SET is_active = EXCLUDED.is_active
+	`
 
-	for _, member := range team.Members {
+	for _, member := range members {
 		_, err := tx.ExecContext(ctx, updateOrInsertQuery,
 			member.UserID,
 			member.Username,
-			team.TeamName,
+			member.Level)
+		if err != nil {
+			return fmt.Errorf("failed to add member to team: %v", err)
+		}
+
+		_, err = tx.ExecContext(ctx, addMembershipQuery,
+			teamID,
+			member.UserID,
 			member.IsActive)
 		if err != nil {
 			return fmt.Errorf("failed to add member to team: %v", err)
 		}
-	}
 
-	return tx.Commit()
+		if member.Skills != nil {
+			if err := replaceSkills(ctx, tx, member.UserID, member.Skills); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (r *repository) Get(ctx context.Context, teamName string) (*model.Team, error) {
-	query := `
-		SELECT 
-			user_id,
-			username,
-			is_active
-		FROM users
-		WHERE team_name = $1
+// replaceSkills overwrites the user's skill tags.
+func replaceSkills(ctx context.Context, tx *sql.Tx, userID string, skills []string) error {
+	clearSkillsQuery := `
+		DELETE FROM user_skills
+		WHERE user_id = $1
 	`
-	rows, err := r.db.QueryContext(ctx, query, teamName)
+	if _, err := tx.ExecContext(ctx, clearSkillsQuery, userID); err != nil {
+		return fmt.Errorf("failed to clear skills: %v", err)
+	}
+
+	addSkillQuery := `
+		INSERT INTO user_skills (user_id, skill)
+		VALUES ($1, $2)
+	`
+	for _, skill := range skills {
+		if _, err := tx.ExecContext(ctx, addSkillQuery, userID, skill); err != nil {
+			return fmt.Errorf("failed to add skill: %v", err)
+		}
+	}
+	return nil
+}
+
+// removeMembers runs a membership delete query returning the ids of the users
+// that left the team.
+func removeMembers(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get users: %v", err)
+		return nil, fmt.Errorf("failed to remove members: %v", err)
 	}
 	defer rows.Close()
 
-	members := make([]*model.TeamMember, 0)
+	userIDs := make([]string, 0)
 	for rows.Next() {
-		m := &model.TeamMember{}
-		if err := rows.Scan(&m.UserID, &m.Username, &m.IsActive); err != nil {
+		var id string
+		if err := rows.Scan(&id); err != nil {
 			return nil, fmt.Errorf("failed to scan user: %v", err)
 		}
-		members = append(members, m)
+		userIDs = append(userIDs, id)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %v", err)
+	}
+	return userIDs, nil
+}
 
-	if len(members) == 0 {
-		return nil, model.ErrNotFound
+// releaseOpenReviews drops the users' assignments on OPEN pull requests filed
+// against the team. Assignments on merged pull requests are kept as history.
+func releaseOpenReviews(ctx context.Context, tx *sql.Tx, teamID int, userIDs []string) ([]*model.ReleasedReview, error) {
+	released := make([]*model.ReleasedReview, 0)
+	if len(userIDs) == 0 {
+		return released, nil
 	}
 
-	team := &model.Team{
-		TeamName: teamName,
-		Members:  members,
+	releaseQuery := `
+		WITH released AS (
+			DELETE FROM pull_request_reviewers prr
+			USING pull_requests pr
+			WHERE prr.pull_request_id = pr.pull_request_id
+				AND pr.status_id = 1
+				AND pr.team_id = $1
+				AND prr.reviewer_user_id = ANY($2)
+			RETURNING prr.pull_request_id, prr.reviewer_user_id
+		), logged AS (
+			INSERT INTO reviewer_history (pull_request_id, reviewer_user_id, action)
+			SELECT pull_request_id, reviewer_user_id, 'removed'
+			FROM released
+		)
+		SELECT pull_request_id, reviewer_user_id
+		FROM released
+	`
+	rows, err := tx.QueryContext(ctx, releaseQuery, teamID, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to release open reviews: %v", err)
 	}
-	return team, nil
+	defer rows.Close()
+
+	for rows.Next() {
+		rr := &model.ReleasedReview{}
+		if err := rows.Scan(&rr.PullRequestID, &rr.UserID); err != nil {
+			return nil, fmt.Errorf("failed to scan released review: %v", err)
+		}
+		released = append(released, rr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate released reviews: %v", err)
+	}
+	return released, nil
 }
@@ -31,17 +31,27 @@ func TestAddSuccess(t *testing.T) {
 
 	mock.
 		ExpectQuery("INSERT INTO teams").
-		WithArgs("backend").
+		WithArgs("backend", 0).
 		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(1))
 
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs("u1", "Alice", "backend", true).
+		WithArgs("u1", "Alice", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.
+		ExpectExec("INSERT INTO team_members").
+		WithArgs(1, "u1", true).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.
 		ExpectExec("INSERT INTO users").
-		WithArgs("u2", "Bob", "backend", true).
+		WithArgs("u2", "Bob", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.
+		ExpectExec("INSERT INTO team_members").
+		WithArgs(1, "u2", true).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.
@@ -77,7 +87,7 @@ func TestAddTeamExists(t *testing.T) {
 
 	mock.
 		ExpectQuery("INSERT INTO teams").
-		WithArgs("backend").
+		WithArgs("backend", 0).
 		WillReturnRows(sqlmock.NewRows([]string{"team_id"}))
 
 	mock.
@@ -142,7 +152,7 @@ func TestAddCreateTeamError(t *testing.T) {
 
 	mock.
 		ExpectQuery("INSERT INTO teams").
-		WithArgs("backend").
+		WithArgs("backend", 0).
 		WillReturnError(errors.New("database error"))
 
 	mock.
@@ -170,12 +180,12 @@ func TestGetSuccess(t *testing.T) {
 
 	teamName := "backend"
 
-	rows := sqlmock.NewRows([]string{"user_id", "username", "is_active"}).
-		AddRow("u1", "Alice", true).
-		AddRow("u2", "Bob", true)
+	rows := sqlmock.NewRows([]string{"min_senior_reviewers", "user_id", "username", "is_active", "skills", "level"}).
+		AddRow(1, "u1", "Alice", true, "{go,sql}", "senior").
+		AddRow(1, "u2", "Bob", true, "{}", "")
 
 	mock.
-		ExpectQuery("SELECT user_id, username, is_active FROM users WHERE team_name").
+		ExpectQuery("SELECT t.min_senior_reviewers, u.user_id, u.username, tm.is_active AND u.is_active, ARRAY\\(SELECT s.skill FROM user_skills s").
 		WithArgs(teamName).
 		WillReturnRows(rows)
 
@@ -187,6 +197,38 @@ func TestGetSuccess(t *testing.T) {
 	if team == nil {
 		t.Fatalf("expected non-nil team")
 	}
+	if team.MinSeniorReviewers != 1 || team.Members[0].Level != model.LevelSenior {
+		t.Errorf("unexpected seniority data: %+v", team)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetEmptyTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	rows := sqlmock.NewRows([]string{"min_senior_reviewers", "user_id", "username", "is_active", "skills", "level"}).
+		AddRow(1, nil, nil, nil, "{}", "")
+
+	mock.
+		ExpectQuery("SELECT t.min_senior_reviewers, .+ FROM teams t LEFT JOIN team_members tm ON tm.team_id = t.team_id LEFT JOIN users u").
+		WithArgs("backend").
+		WillReturnRows(rows)
+
+	team, err := repo.Get(context.Background(), "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(team.Members) != 0 || team.MinSeniorReviewers != 1 {
+		t.Errorf("expected an empty team keeping its rule, got %+v", team)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
@@ -203,10 +245,10 @@ func TestGetTeamNotFound(t *testing.T) {
 
 	teamName := "unknownteam"
 
-	emptyRows := sqlmock.NewRows([]string{"user_id", "username", "is_active"})
+	emptyRows := sqlmock.NewRows([]string{"min_senior_reviewers", "user_id", "username", "is_active", "skills", "level"})
 
 	mock.
-		ExpectQuery("SELECT user_id, username, is_active FROM users WHERE team_name").
+		ExpectQuery("SELECT t.min_senior_reviewers, u.user_id, u.username, tm.is_active AND u.is_active, ARRAY\\(SELECT s.skill FROM user_skills s").
 		WithArgs(teamName).
 		WillReturnRows(emptyRows)
 
@@ -234,7 +276,7 @@ func TestGetQueryError(t *testing.T) {
 	teamName := "backend"
 
 	mock.
-		ExpectQuery("SELECT user_id, username, is_active FROM users WHERE team_name").
+		ExpectQuery("SELECT t.min_senior_reviewers, u.user_id, u.username, tm.is_active AND u.is_active, ARRAY\\(SELECT s.skill FROM user_skills s").
 		WithArgs(teamName).
 		WillReturnError(errors.New("connection lost"))
 
@@ -246,3 +288,465 @@ func TestGetQueryError(t *testing.T) {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
+
+func TestListSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	rows := sqlmock.NewRows([]string{"team_name", "min_senior_reviewers", "user_id", "username", "is_active", "skills", "level"}).
+		AddRow("backend", 0, "u1", "Alice", true, "{go}", "").
+		AddRow("backend", 0, "u2", "Bob", false, "{}", "").
+		AddRow("frontend", 0, nil, nil, nil, "{}", "")
+
+	mock.
+		ExpectQuery("SELECT t.team_name, t.min_senior_reviewers, u.user_id, u.username, tm.is_active AND u.is_active, ARRAY\\(SELECT s.skill FROM user_skills s").
+		WillReturnRows(rows)
+
+	teams, err := repo.List(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(teams) != 2 {
+		t.Fatalf("expected 2 teams, got %d", len(teams))
+	}
+	if len(teams[0].Members) != 2 || teams[0].Members[1].IsActive {
+		t.Errorf("wrong backend members: %+v", teams[0].Members)
+	}
+	if teams[1].TeamName != "frontend" || len(teams[1].Members) != 0 {
+		t.Errorf("expected empty frontend team, got %+v", teams[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateRenameAndRemove(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	update := &model.TeamUpdate{
+		TeamName:      "backend",
+		NewTeamName:   "platform",
+		AddMembers:    []*model.TeamMember{{UserID: "u3", Username: "Carol", IsActive: true}},
+		RemoveMembers: []string{"u2"},
+	}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id FROM teams WHERE team_name = \\$1 FOR UPDATE").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(1))
+	mock.
+		ExpectExec("UPDATE teams SET team_name").
+		WithArgs("platform", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO users").
+		WithArgs("u3", "Carol", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO team_members").
+		WithArgs(1, "u3", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectQuery("DELETE FROM team_members WHERE team_id = \\$1 AND user_id = ANY").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u2"))
+	mock.
+		ExpectQuery("DELETE FROM pull_request_reviewers").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "reviewer_user_id"}).AddRow("pr-1", "u2"))
+	mock.ExpectCommit()
+
+	released, err := repo.Update(context.Background(), update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(released) != 1 || released[0].PullRequestID != "pr-1" || released[0].UserID != "u2" {
+		t.Errorf("wrong released reviews: %+v", released)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateRenameConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id FROM teams").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(1))
+	mock.
+		ExpectExec("UPDATE teams SET team_name").
+		WithArgs("frontend", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = repo.Update(context.Background(), &model.TeamUpdate{TeamName: "backend", NewTeamName: "frontend"})
+	if !errors.Is(err, model.ErrTeamExists) {
+		t.Errorf("expected ErrTeamExists, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateTeamNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id FROM teams").
+		WithArgs("none").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}))
+	mock.ExpectRollback()
+
+	_, err = repo.Update(context.Background(), &model.TeamUpdate{TeamName: "none", RemoveMembers: []string{"u1"}})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDeleteSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id FROM teams WHERE team_name = \\$1 FOR UPDATE").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(1))
+	mock.
+		ExpectQuery("DELETE FROM team_members WHERE team_id = \\$1 RETURNING user_id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u1").AddRow("u2"))
+	mock.
+		ExpectQuery("DELETE FROM pull_request_reviewers").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "reviewer_user_id"}))
+	mock.
+		ExpectExec("DELETE FROM teams WHERE team_id").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	released, err := repo.Delete(context.Background(), "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(released) != 0 {
+		t.Errorf("expected no released reviews, got %+v", released)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDeleteTeamNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id FROM teams WHERE team_name").
+		WithArgs("none").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}))
+	mock.ExpectRollback()
+
+	_, err = repo.Delete(context.Background(), "none")
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetOwnershipRulesSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id FROM teams WHERE team_name = \\$1 FOR UPDATE").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(1))
+	mock.
+		ExpectExec("DELETE FROM team_ownership_rules WHERE team_id = \\$1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.
+		ExpectExec("INSERT INTO team_ownership_rules").
+		WithArgs(1, 2, "*.go", "@u2 @acme/platform").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.SetOwnershipRules(context.Background(), "backend", []*model.OwnershipRule{
+		{Line: 2, Pattern: "*.go", Owners: []string{"@u2", "@acme/platform"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetOwnershipRulesTeamNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectQuery("SELECT r.position, r.pattern, r.owners FROM teams t LEFT JOIN team_ownership_rules r").
+		WithArgs("none").
+		WillReturnRows(sqlmock.NewRows([]string{"position", "pattern", "owners"}))
+
+	_, err = repo.GetOwnershipRules(context.Background(), "none")
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetFallbackTeamsUnknownTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id FROM teams WHERE team_name = \\$1 FOR UPDATE").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(1))
+	mock.
+		ExpectExec("DELETE FROM team_fallbacks WHERE team_id = \\$1").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO team_fallbacks \\(team_id, position, fallback_team_id\\) SELECT \\$1, \\$2, team_id FROM teams").
+		WithArgs(1, 1, "platform").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO team_fallbacks").
+		WithArgs(1, 2, "none").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = repo.SetFallbackTeams(context.Background(), "backend", []string{"platform", "none"})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetReviewSLATeamNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectExec("UPDATE teams SET review_sla_minutes = NULLIF\\(\\$1, 0\\), sla_auto_reassign = \\$2").
+		WithArgs(60, true, "none").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.SetReviewSLA(context.Background(), &model.ReviewSLA{TeamName: "none", Minutes: 60, AutoReassign: true})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetChatTeamNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectExec("UPDATE teams SET chat_webhook_url = NULLIF\\(\\$1, ''\\), chat_direct_messages = \\$2").
+		WithArgs("https://chat.example.com/hooks/1", false, "none").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.SetChat(context.Background(), &model.TeamChat{TeamName: "none", WebhookURL: "https://chat.example.com/hooks/1"})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestImportCreatesTeamAndRemovesMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	// the teams are locked and read in name order before anything changes
+	mock.
+		ExpectQuery("SELECT team_id, min_senior_reviewers FROM teams WHERE team_name = \\$1 FOR UPDATE").
+		WithArgs("backend").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "min_senior_reviewers"}).AddRow(1, 0))
+	mock.
+		ExpectQuery("SELECT user_id, is_active FROM team_members WHERE team_id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "is_active"}).AddRow("u1", true).AddRow("u2", true))
+	mock.
+		ExpectQuery("SELECT team_id, min_senior_reviewers FROM teams WHERE team_name = \\$1 FOR UPDATE").
+		WithArgs("data").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "min_senior_reviewers"}))
+	mock.
+		ExpectQuery("INSERT INTO teams").
+		WithArgs("data", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(2))
+	mock.
+		ExpectQuery("DELETE FROM team_members WHERE team_id = \\$1").
+		WithArgs(2, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.
+		ExpectExec("UPDATE teams SET min_senior_reviewers = \\$1").
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO users").
+		WithArgs("u1", "Alice", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO team_members").
+		WithArgs(1, "u1", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectQuery("DELETE FROM team_members WHERE team_id = \\$1 AND NOT \\(user_id = ANY\\(\\$2\\)\\)").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u2"))
+	mock.
+		ExpectQuery("DELETE FROM pull_request_reviewers").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "reviewer_user_id"}).AddRow("pr-1", "u2"))
+	mock.ExpectCommit()
+
+	one := 1
+	diff, released, err := repo.Import(context.Background(), []*model.RosterTeam{
+		{TeamName: "data", Members: []*model.TeamMember{}},
+		{TeamName: "backend", MinSeniorReviewers: &one, Members: []*model.TeamMember{{UserID: "u1", Username: "Alice", IsActive: true}}},
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(released) != 1 || released[0].PullRequestID != "pr-1" {
+		t.Errorf("wrong released reviews: %+v", released)
+	}
+	if len(diff.CreatedTeams) != 1 || len(diff.Changes) != 1 || diff.Changes[0].UserID != "u2" ||
+		len(diff.SeniorityChanges) != 1 || diff.SeniorityChanges[0].To != 1 {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestImportDryRunRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT team_id, min_senior_reviewers FROM teams WHERE team_name = \\$1 FOR UPDATE").
+		WithArgs("data").
+		WillReturnRows(sqlmock.NewRows([]string{"team_id", "min_senior_reviewers"}))
+	mock.
+		ExpectQuery("INSERT INTO teams").
+		WithArgs("data", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(2))
+	mock.
+		ExpectQuery("DELETE FROM team_members WHERE team_id = \\$1").
+		WithArgs(2, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectRollback()
+
+	diff, _, err := repo.Import(context.Background(), []*model.RosterTeam{{TeamName: "data", Members: []*model.TeamMember{}}}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diff.CreatedTeams) != 1 || diff.CreatedTeams[0] != "data" {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
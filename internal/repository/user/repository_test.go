@@ -6,6 +6,7 @@ import (
 	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
 	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v1"
 	"testing"
+	"time"
 )
 
 func TestSetIsActiveSuccess(t *testing.T) {
@@ -23,16 +24,20 @@ func TestSetIsActiveSuccess(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	mock.
-		ExpectQuery("SELECT user_id, username, team_name, is_active FROM users WHERE user_id = \\$1").
+		ExpectQuery("SELECT u.user_id, u.username, u.is_active, t.team_name, tm.is_active, ARRAY\\(SELECT s.skill FROM user_skills s").
 		WithArgs("user123").
-		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active"}).
-			AddRow("user123", "Bob", "backend", false))
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "is_active", "team_name", "is_active", "skills", "level", "max_open_reviews", "email", "chat_handle"}).
+			AddRow("user123", "Bob", false, "backend", true, "{go}", "", nil, "", "").
+			AddRow("user123", "Bob", false, "platform", false, "{go}", "", nil, "", ""))
 
-	_, err = repo.SetIsActive(context.Background(), "user123", false)
+	user, err := repo.SetIsActive(context.Background(), "user123", false)
 
 	if err != nil {
 		t.Errorf("unexpected err: %v", err)
 	}
+	if user.TeamName != "backend" || len(user.Teams) != 2 {
+		t.Errorf("unexpected teams: %q %+v", user.TeamName, user.Teams)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
@@ -64,6 +69,144 @@ func TestSetIsActiveUserNotFound(t *testing.T) {
 	}
 }
 
+func TestSetTeamIsActiveSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectExec("UPDATE team_members tm SET is_active").
+		WithArgs(false, "user123", "platform").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.
+		ExpectQuery("SELECT u.user_id, u.username, u.is_active, t.team_name, tm.is_active, ARRAY\\(SELECT s.skill FROM user_skills s").
+		WithArgs("user123").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "is_active", "team_name", "is_active", "skills", "level", "max_open_reviews", "email", "chat_handle"}).
+			AddRow("user123", "Bob", true, "backend", true, "{}", "", nil, "", "").
+			AddRow("user123", "Bob", true, "platform", false, "{}", "", nil, "", ""))
+
+	user, err := repo.SetTeamIsActive(context.Background(), "user123", "platform", false)
+
+	if err != nil {
+		t.Errorf("unexpected err: %v", err)
+	}
+	if !user.IsActive || user.Teams[1].IsActive {
+		t.Errorf("unexpected user: %+v", user)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetTeamIsActiveNotMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectExec("UPDATE team_members tm SET is_active").
+		WithArgs(true, "user123", "frontend").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = repo.SetTeamIsActive(context.Background(), "user123", "frontend", true)
+
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec("UPDATE users SET username = COALESCE\\(NULLIF\\(\\$1, ''\\), username\\), level = COALESCE\\(NULLIF\\(\\$2, ''\\), level\\)").
+		WithArgs("", "", "user123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("DELETE FROM user_skills WHERE user_id = \\$1").
+		WithArgs("user123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO user_skills").
+		WithArgs("user123", "go").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO user_skills").
+		WithArgs("user123", "terraform").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.
+		ExpectQuery("SELECT u.user_id, u.username, u.is_active, t.team_name, tm.is_active, ARRAY\\(SELECT s.skill FROM user_skills s").
+		WithArgs("user123").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "is_active", "team_name", "is_active", "skills", "level", "max_open_reviews", "email", "chat_handle"}).
+			AddRow("user123", "Bob", true, "backend", true, "{go,terraform}", "", nil, "", ""))
+
+	user, _, err := repo.Update(context.Background(), &model.UserUpdate{
+		UserID: "user123",
+		Skills: []string{"go", "terraform"},
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(user.Skills) != 2 || user.Skills[1] != "terraform" {
+		t.Errorf("unexpected skills: %v", user.Skills)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUpdateUserNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec("UPDATE users SET username").
+		WithArgs("Robert", "", "none").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, _, err = repo.Update(context.Background(), &model.UserUpdate{UserID: "none", Username: "Robert"})
+
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
 func TestSetIsActiveDatabaseError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -98,26 +241,342 @@ func TestGetReviewSuccess(t *testing.T) {
 
 	repo := &repository{db: db}
 
+	assignedAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
 	prRows := sqlmock.NewRows([]string{
 		"pull_request_id", "pull_request_name", "author_id",
-		"status_name",
+		"status_name", "assigned_at",
 	}).
-		AddRow("pr-1001", "Add search", "u1", "OPEN").
-		AddRow("pr-1002", "Add readme", "u2", "MERGED")
+		AddRow("pr-1001", "Add search", "u1", "OPEN", assignedAt).
+		AddRow("pr-1002", "Add readme", "u2", "OPEN", assignedAt.Add(time.Hour))
 
 	mock.
-		ExpectQuery("SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, ps.status_name FROM pull_requests pr").
-		WithArgs("reviewer1").
+		ExpectQuery("SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, ps.status_name, prr.assigned_at FROM pull_requests pr").
+		WithArgs("reviewer1", "OPEN", 2).
 		WillReturnRows(prRows)
 
-	prs, err := repo.GetReview(context.Background(), "reviewer1")
+	page, err := repo.GetReview(context.Background(), "reviewer1", model.ReviewFilter{Status: "OPEN", Limit: 1})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(page.Reviews) != 1 {
+		t.Errorf("expected 1 pr, got %d", len(page.Reviews))
+	}
+	if page.Next == nil || page.Next.ID != "pr-1001" || page.Next.Key != "2024-01-01T10:00:00Z" {
+		t.Errorf("unexpected cursor: %+v", page.Next)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetReviewLoad(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectQuery("SELECT open_reviews, max_open_reviews FROM reviewer_load WHERE user_id = \\$1").
+		WithArgs("reviewer1").
+		WillReturnRows(sqlmock.NewRows([]string{"open_reviews", "max_open_reviews"}).AddRow(2, 3))
+
+	mock.
+		ExpectQuery("SELECT open_reviews, max_open_reviews FROM reviewer_load").
+		WithArgs("none").
+		WillReturnRows(sqlmock.NewRows([]string{"open_reviews", "max_open_reviews"}))
+
+	load, err := repo.GetReviewLoad(context.Background(), "reviewer1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if load.OpenReviews != 2 || load.MaxOpenReviews == nil || *load.MaxOpenReviews != 3 {
+		t.Errorf("unexpected load: %+v", load)
+	}
+
+	if _, err := repo.GetReviewLoad(context.Background(), "none"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetReviewerPreferences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM users WHERE user_id = ANY\\(\\$1\\)").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.
+		ExpectExec("DELETE FROM reviewer_preferences WHERE author_id = \\$1").
+		WithArgs("u1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.
+		ExpectExec("INSERT INTO reviewer_preferences").
+		WithArgs("u1", "u2", "blocked").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.
+		ExpectExec("INSERT INTO reviewer_preferences").
+		WithArgs("u1", "u3", "preferred").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.SetReviewerPreferences(context.Background(), &model.ReviewerPreferences{
+		UserID:    "u1",
+		Blocked:   []string{"u2"},
+		Preferred: []string{"u3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
 
+func TestSetReviewerPreferencesUnknownUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT COUNT\\(\\*\\) FROM users").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err = repo.SetReviewerPreferences(context.Background(), &model.ReviewerPreferences{
+		UserID:  "u1",
+		Blocked: []string{"none"},
+	})
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateUserExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectExec("INSERT INTO users \\(user_id, username, is_active, level, max_open_reviews, email, chat_handle\\)").
+		WithArgs("u1", "Alice", true, "", nil, "", "").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err = repo.Create(context.Background(), &model.User{
+		TeamMember: model.TeamMember{UserID: "u1", Username: "Alice", IsActive: true},
+	})
+
+	if !errors.Is(err, model.ErrUserExists) {
+		t.Errorf("expected ErrUserExists, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDeleteUserHasOpenPrs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT EXISTS \\(.+\\) FROM users u WHERE u.user_id = \\$1 AND u.deleted_at IS NULL FOR UPDATE").
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	_, err = repo.Delete(context.Background(), "u1")
+
+	if !errors.Is(err, model.ErrHasOpenPrs) {
+		t.Errorf("expected ErrHasOpenPrs, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDeleteUserSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT EXISTS \\(.+\\) FROM users u WHERE u.user_id = \\$1 AND u.deleted_at IS NULL FOR UPDATE").
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.
+		ExpectQuery("DELETE FROM pull_request_reviewers prr USING pull_requests pr .+ INSERT INTO reviewer_history").
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"pull_request_id", "reviewer_user_id"}).AddRow("pr-1", "u1"))
+	mock.
+		ExpectExec("DELETE FROM team_members WHERE user_id = \\$1").
+		WithArgs("u1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.
+		ExpectExec("UPDATE users SET is_active = FALSE, deleted_at = CURRENT_TIMESTAMP").
+		WithArgs("u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deletion, err := repo.Delete(context.Background(), "u1")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if len(prs) != 2 {
-		t.Errorf("expected 2 prs, got %d", len(prs))
+	if len(deletion.ReleasedReviews) != 1 {
+		t.Errorf("unexpected deletion: %+v", deletion)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDeleteUserAlreadyDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.ExpectBegin()
+	mock.
+		ExpectQuery("SELECT EXISTS \\(.+\\) FROM users u WHERE u.user_id = \\$1 AND u.deleted_at IS NULL FOR UPDATE").
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}))
+	mock.ExpectRollback()
+
+	_, err = repo.Delete(context.Background(), "u1")
+
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSetScheduleUserNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+
+	mock.
+		ExpectExec("UPDATE users SET timezone = COALESCE\\(NULLIF\\(\\$1, ''\\), 'UTC'\\)").
+		WithArgs("Europe/Berlin", "09:00", "17:00", "none").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.SetSchedule(context.Background(), &model.WorkSchedule{
+		UserID: "none", Timezone: "Europe/Berlin", WorkStart: "09:00", WorkEnd: "17:00",
+	})
+
+	if !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMarkRemindedHeldBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	now := time.Now()
+
+	mock.
+		ExpectQuery("UPDATE users u SET last_reminded_at = \\$1 FROM \\( SELECT user_id, last_reminded_at FROM users WHERE user_id = \\$2 FOR UPDATE \\) prev WHERE u.user_id = prev.user_id AND \\(prev.last_reminded_at IS NULL OR prev.last_reminded_at <= \\$3\\) RETURNING prev.last_reminded_at").
+		WithArgs(now, "u1", now.Add(-time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"last_reminded_at"}))
+
+	marked, previous, err := repo.MarkReminded(context.Background(), "u1", now, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marked || previous != nil {
+		t.Error("expected the reminder to be held back")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUnmarkRemindedRestoresPrevious(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &repository{db: db}
+	now := time.Now()
+	previous := now.Add(-48 * time.Hour)
+
+	mock.
+		ExpectExec("UPDATE users SET last_reminded_at = \\$1 WHERE user_id = \\$2 AND last_reminded_at = \\$3").
+		WithArgs(previous, "u1", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.UnmarkReminded(context.Background(), "u1", now, &previous); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -0,0 +1,299 @@
+// Package roster reads team rosters kept outside the service, as YAML or
+// CSV, and compares them with the stored teams.
+package roster
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	FormatYAML = "yaml"
+	FormatCSV  = "csv"
+)
+
+// Parse reads a roster in the given format. Members default to active, and
+// members without skills keep their stored tags. Teams without
+// min_senior_reviewers keep their seniority rule.
+func Parse(content []byte, format string) ([]*model.RosterTeam, error) {
+	var (
+		teams []*model.RosterTeam
+		err   error
+	)
+	switch format {
+	case FormatYAML:
+		teams, err = parseYAML(content)
+	case FormatCSV:
+		teams, err = parseCSV(content)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", model.ErrInvalidRoster, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(teams); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// parseYAML reads a document of the form
+//
+//	teams:
+//	  - team_name: backend
+//	    min_senior_reviewers: 1
+//	    members:
+//	      - user_id: u1
+//	        username: Alice
+//	        is_active: true
+//	        level: senior
+//	        skills: [go, sql]
+func parseYAML(content []byte) ([]*model.RosterTeam, error) {
+	var doc struct {
+		Teams []struct {
+			TeamName           string `yaml:"team_name"`
+			MinSeniorReviewers *int   `yaml:"min_senior_reviewers"`
+			Members            []struct {
+				UserID   string   `yaml:"user_id"`
+				Username string   `yaml:"username"`
+				IsActive *bool    `yaml:"is_active"`
+				Level    string   `yaml:"level"`
+				Skills   []string `yaml:"skills"`
+			} `yaml:"members"`
+		} `yaml:"teams"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidRoster, err)
+	}
+
+	teams := make([]*model.RosterTeam, 0, len(doc.Teams))
+	for _, t := range doc.Teams {
+		team := &model.RosterTeam{
+			TeamName:           strings.TrimSpace(t.TeamName),
+			Members:            make([]*model.TeamMember, 0, len(t.Members)),
+			MinSeniorReviewers: t.MinSeniorReviewers,
+		}
+		for _, m := range t.Members {
+			team.Members = append(team.Members, &model.TeamMember{
+				UserID:   strings.TrimSpace(m.UserID),
+				Username: strings.TrimSpace(m.Username),
+				IsActive: m.IsActive == nil || *m.IsActive,
+				Level:    strings.TrimSpace(m.Level),
+				Skills:   m.Skills,
+			})
+		}
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+// parseCSV reads one member per row under a header naming the columns
+// team_name, user_id and username, and optionally is_active, level, skills
+// and min_senior_reviewers. Skills are separated by semicolons. A team's
+// min_senior_reviewers may be left empty on some of its rows but must not
+// differ between them. Teams come in the order they first appear.
+func parseCSV(content []byte) ([]*model.RosterTeam, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", model.ErrInvalidRoster, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"team_name", "user_id", "username"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", model.ErrInvalidRoster, required)
+		}
+	}
+
+	teams := make([]*model.RosterTeam, 0)
+	byName := make(map[string]*model.RosterTeam)
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", model.ErrInvalidRoster, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		member := &model.TeamMember{
+			UserID:   field("user_id"),
+			Username: field("username"),
+			IsActive: true,
+			Level:    field("level"),
+		}
+		if value := field("is_active"); value != "" {
+			member.IsActive, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: bad is_active %q", model.ErrInvalidRoster, line, value)
+			}
+		}
+		if value := field("skills"); value != "" {
+			member.Skills = strings.Split(value, ";")
+		}
+
+		teamName := field("team_name")
+		team := byName[teamName]
+		if team == nil {
+			team = &model.RosterTeam{TeamName: teamName, Members: make([]*model.TeamMember, 0)}
+			byName[teamName] = team
+			teams = append(teams, team)
+		}
+		team.Members = append(team.Members, member)
+
+		if value := field("min_senior_reviewers"); value != "" {
+			minSeniors, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: bad min_senior_reviewers %q", model.ErrInvalidRoster, line, value)
+			}
+			if team.MinSeniorReviewers != nil && *team.MinSeniorReviewers != minSeniors {
+				return nil, fmt.Errorf("%w: line %d: team %q has conflicting min_senior_reviewers", model.ErrInvalidRoster, line, teamName)
+			}
+			team.MinSeniorReviewers = &minSeniors
+		}
+	}
+	return teams, nil
+}
+
+func validate(teams []*model.RosterTeam) error {
+	if len(teams) == 0 {
+		return fmt.Errorf("%w: no teams", model.ErrInvalidRoster)
+	}
+	seenTeams := make(map[string]bool, len(teams))
+	for _, team := range teams {
+		if team.TeamName == "" {
+			return fmt.Errorf("%w: team without team_name", model.ErrInvalidRoster)
+		}
+		if seenTeams[team.TeamName] {
+			return fmt.Errorf("%w: team %q listed twice", model.ErrInvalidRoster, team.TeamName)
+		}
+		seenTeams[team.TeamName] = true
+		if rule := team.MinSeniorReviewers; rule != nil && *rule < 0 {
+			return fmt.Errorf("%w: team %q: negative min_senior_reviewers", model.ErrInvalidRoster, team.TeamName)
+		}
+
+		seenUsers := make(map[string]bool, len(team.Members))
+		for _, m := range team.Members {
+			if m.UserID == "" || m.Username == "" {
+				return fmt.Errorf("%w: team %q: member without user_id or username", model.ErrInvalidRoster, team.TeamName)
+			}
+			if seenUsers[m.UserID] {
+				return fmt.Errorf("%w: team %q: user %q listed twice", model.ErrInvalidRoster, team.TeamName, m.UserID)
+			}
+			seenUsers[m.UserID] = true
+
+			switch m.Level {
+			case "", model.LevelJunior, model.LevelMiddle, model.LevelSenior:
+			default:
+				return fmt.Errorf("%w: user %q: bad level %q", model.ErrInvalidRoster, m.UserID, m.Level)
+			}
+		}
+	}
+	return nil
+}
+
+// Diff compares the roster with the current teams. Only the teams named in
+// the roster are compared. A user who leaves one of them and joins another
+// is reported as moved rather than removed and added. Changes are ordered by
+// team and user.
+func Diff(current []*model.Team, roster []*model.RosterTeam) *model.RosterDiff {
+	stored := make(map[string]map[string]*model.TeamMember, len(current))
+	rules := make(map[string]int, len(current))
+	for _, team := range current {
+		members := make(map[string]*model.TeamMember, len(team.Members))
+		for _, m := range team.Members {
+			members[m.UserID] = m
+		}
+		stored[team.TeamName] = members
+		rules[team.TeamName] = team.MinSeniorReviewers
+	}
+
+	diff := &model.RosterDiff{
+		CreatedTeams:     make([]string, 0),
+		Changes:          make([]*model.RosterChange, 0),
+		SeniorityChanges: make([]*model.SeniorityChange, 0),
+	}
+	var removed, added []*model.RosterChange
+	for _, team := range roster {
+		before, exists := stored[team.TeamName]
+		if !exists {
+			diff.CreatedTeams = append(diff.CreatedTeams, team.TeamName)
+		} else if rule := team.MinSeniorReviewers; rule != nil && *rule != rules[team.TeamName] {
+			diff.SeniorityChanges = append(diff.SeniorityChanges, &model.SeniorityChange{
+				TeamName: team.TeamName,
+				From:     rules[team.TeamName],
+				To:       *rule,
+			})
+		}
+
+		kept := make(map[string]bool, len(team.Members))
+		for _, m := range team.Members {
+			kept[m.UserID] = true
+			old, ok := before[m.UserID]
+			switch {
+			case !ok:
+				added = append(added, &model.RosterChange{Action: model.RosterAdded, UserID: m.UserID, TeamName: team.TeamName, IsActive: m.IsActive})
+			case old.IsActive != m.IsActive:
+				diff.Changes = append(diff.Changes, &model.RosterChange{Action: model.RosterActivityChanged, UserID: m.UserID, TeamName: team.TeamName, IsActive: m.IsActive})
+			}
+		}
+		for userID, old := range before {
+			if !kept[userID] {
+				removed = append(removed, &model.RosterChange{Action: model.RosterRemoved, UserID: userID, TeamName: team.TeamName, IsActive: old.IsActive})
+			}
+		}
+	}
+
+	sortChanges(removed)
+	sortChanges(added)
+	for _, change := range added {
+		for i, left := range removed {
+			if left.UserID == change.UserID {
+				change.Action = model.RosterMoved
+				change.FromTeam = left.TeamName
+				removed = append(removed[:i], removed[i+1:]...)
+				break
+			}
+		}
+	}
+
+	diff.Changes = append(diff.Changes, removed...)
+	diff.Changes = append(diff.Changes, added...)
+	sortChanges(diff.Changes)
+	sort.Strings(diff.CreatedTeams)
+	sort.Slice(diff.SeniorityChanges, func(i, j int) bool {
+		return diff.SeniorityChanges[i].TeamName < diff.SeniorityChanges[j].TeamName
+	})
+	return diff
+}
+
+func sortChanges(changes []*model.RosterChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].TeamName != changes[j].TeamName {
+			return changes[i].TeamName < changes[j].TeamName
+		}
+		return changes[i].UserID < changes[j].UserID
+	})
+}
@@ -0,0 +1,140 @@
+package roster
+
+import (
+	"errors"
+	"testing"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func TestParseYAML(t *testing.T) {
+	content := `
+teams:
+  - team_name: backend
+    min_senior_reviewers: 1
+    members:
+      - user_id: u1
+        username: Alice
+        level: senior
+        skills: [go, sql]
+      - user_id: u2
+        username: Bob
+        is_active: false
+  - team_name: frontend
+    members: []
+`
+	teams, err := Parse([]byte(content), FormatYAML)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(teams) != 2 || teams[0].TeamName != "backend" || len(teams[0].Members) != 2 || len(teams[1].Members) != 0 {
+		t.Fatalf("unexpected teams: %+v", teams)
+	}
+	if rule := teams[0].MinSeniorReviewers; rule == nil || *rule != 1 || teams[1].MinSeniorReviewers != nil {
+		t.Errorf("expected only backend to set its seniority rule, got %v and %v", rule, teams[1].MinSeniorReviewers)
+	}
+	alice, bob := teams[0].Members[0], teams[0].Members[1]
+	if !alice.IsActive || alice.Level != model.LevelSenior || len(alice.Skills) != 2 {
+		t.Errorf("unexpected member: %+v", alice)
+	}
+	if bob.IsActive || bob.Skills != nil {
+		t.Errorf("unexpected member: %+v", bob)
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	content := "team_name,user_id,username,is_active,skills,min_senior_reviewers\n" +
+		"backend,u1,Alice,,go;sql,2\n" +
+		"frontend,u3,Carol,true,,\n" +
+		"backend,u2,Bob,false,,\n"
+
+	teams, err := Parse([]byte(content), FormatCSV)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(teams) != 2 || teams[0].TeamName != "backend" || len(teams[0].Members) != 2 || teams[1].TeamName != "frontend" {
+		t.Fatalf("unexpected teams: %+v", teams)
+	}
+	if alice := teams[0].Members[0]; !alice.IsActive || len(alice.Skills) != 2 || alice.Skills[1] != "sql" {
+		t.Errorf("unexpected member: %+v", alice)
+	}
+	if bob := teams[0].Members[1]; bob.IsActive || bob.Skills != nil {
+		t.Errorf("unexpected member: %+v", bob)
+	}
+	if rule := teams[0].MinSeniorReviewers; rule == nil || *rule != 2 || teams[1].MinSeniorReviewers != nil {
+		t.Errorf("unexpected seniority rules: %v and %v", rule, teams[1].MinSeniorReviewers)
+	}
+}
+
+func TestParseRejectsMalformedRosters(t *testing.T) {
+	cases := map[string]struct {
+		content, format string
+	}{
+		"unknown yaml field":  {"teams:\n  - team_name: backend\n    member: []\n", FormatYAML},
+		"duplicate team":      {"teams:\n  - team_name: a\n  - team_name: a\n", FormatYAML},
+		"duplicate member":    {"team_name,user_id,username\na,u1,Alice\na,u1,Alice\n", FormatCSV},
+		"missing column":      {"team_name,user_id\na,u1\n", FormatCSV},
+		"bad activity":        {"team_name,user_id,username,is_active\na,u1,Alice,maybe\n", FormatCSV},
+		"bad level":           {"team_name,user_id,username,level\na,u1,Alice,lead\n", FormatCSV},
+		"member without name": {"team_name,user_id,username\na,u1,\n", FormatCSV},
+		"negative seniors":    {"teams:\n  - team_name: a\n    min_senior_reviewers: -1\n", FormatYAML},
+		"conflicting seniors": {"team_name,user_id,username,min_senior_reviewers\na,u1,Alice,1\na,u2,Bob,2\n", FormatCSV},
+		"empty roster":        {"", FormatYAML},
+		"unknown format":      {"teams: []", "json"},
+	}
+	for name, c := range cases {
+		if _, err := Parse([]byte(c.content), c.format); !errors.Is(err, model.ErrInvalidRoster) {
+			t.Errorf("%s: expected ErrInvalidRoster, got: %v", name, err)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := []*model.Team{
+		{TeamName: "backend", Members: []*model.TeamMember{
+			{UserID: "u1", IsActive: true},
+			{UserID: "u2", IsActive: true},
+			{UserID: "u3", IsActive: true},
+		}},
+		{TeamName: "frontend", MinSeniorReviewers: 1, Members: []*model.TeamMember{{UserID: "u4", IsActive: true}}},
+		{TeamName: "mobile", Members: []*model.TeamMember{{UserID: "u5", IsActive: true}}},
+	}
+	one, two := 1, 2
+	roster := []*model.RosterTeam{
+		{TeamName: "backend", MinSeniorReviewers: &two, Members: []*model.TeamMember{
+			{UserID: "u1", IsActive: false},
+			{UserID: "u6", IsActive: true},
+		}},
+		{TeamName: "frontend", MinSeniorReviewers: &one, Members: []*model.TeamMember{
+			{UserID: "u2", IsActive: true},
+			{UserID: "u4", IsActive: true},
+		}},
+		{TeamName: "data", MinSeniorReviewers: &two, Members: []*model.TeamMember{}},
+	}
+
+	diff := Diff(current, roster)
+
+	want := []model.RosterChange{
+		{Action: model.RosterActivityChanged, UserID: "u1", TeamName: "backend", IsActive: false},
+		{Action: model.RosterRemoved, UserID: "u3", TeamName: "backend", IsActive: true},
+		{Action: model.RosterAdded, UserID: "u6", TeamName: "backend", IsActive: true},
+		{Action: model.RosterMoved, UserID: "u2", TeamName: "frontend", FromTeam: "backend", IsActive: true},
+	}
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), diff.Changes)
+	}
+	for i := range want {
+		if *diff.Changes[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], *diff.Changes[i])
+		}
+	}
+	if len(diff.CreatedTeams) != 1 || diff.CreatedTeams[0] != "data" {
+		t.Errorf("unexpected created teams: %v", diff.CreatedTeams)
+	}
+	// frontend keeps its rule and data is created with it
+	if len(diff.SeniorityChanges) != 1 || *diff.SeniorityChanges[0] != (model.SeniorityChange{TeamName: "backend", From: 0, To: 2}) {
+		t.Errorf("unexpected seniority changes: %+v", diff.SeniorityChanges)
+	}
+}
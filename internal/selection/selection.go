@@ -0,0 +1,361 @@
+// Package selection decides which candidates review a pull request. The
+// storage backends only load candidates; the choice itself lives here so that
+// both backends pick reviewers the same way.
+package selection
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/calendar"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+const (
+	// ModeRandom picks among equally suitable candidates at random.
+	ModeRandom = "random"
+	// ModeRotate puts reviewers who recently reviewed the same author last.
+	ModeRotate = "rotate"
+)
+
+// Config selects how ties between candidates are broken. HistoryWindow is
+// how many of the author's latest pull requests ModeRotate looks at.
+type Config struct {
+	Mode          string
+	HistoryWindow int
+}
+
+// Validate rejects unknown modes and a rotation without history.
+func (c Config) Validate() error {
+	switch c.Mode {
+	case "", ModeRandom:
+		return nil
+	case ModeRotate:
+		if c.HistoryWindow <= 0 {
+			return fmt.Errorf("selection history window must be positive, got %d", c.HistoryWindow)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown selection mode %q", c.Mode)
+}
+
+// Window returns how many recent pull requests of the author to load the
+// review history for; zero means the history is not used.
+func (c Config) Window() int {
+	if c.Mode != ModeRotate {
+		return 0
+	}
+	return c.HistoryWindow
+}
+
+// Candidate is a possible reviewer with the skills they carry. Preferred
+// marks reviewers the author asked for. Schedule and Holidays tell when the
+// candidate is at work.
+type Candidate struct {
+	UserID    string
+	Skills    []string
+	Senior    bool
+	Preferred bool
+	Schedule  model.WorkSchedule
+	Holidays  []string
+}
+
+// Owners are the active owners of one matched ownership rule, in random
+// order.
+type Owners struct {
+	Rule       *model.OwnershipRule
+	Candidates []Candidate
+}
+
+// Fallback is the pool of a team that lends reviewers when the primary team
+// cannot fill the seats.
+type Fallback struct {
+	Team       string
+	Candidates []Candidate
+}
+
+// Input holds everything Pick needs. Pool is the general team pool in random
+// order.
+type Input struct {
+	Limit          int
+	Owners         []Owners
+	Pool           []Candidate
+	RequiredSkills []string
+	// MinSeniors is the team's seniority rule. It is capped at the number of
+	// reviewers picked, the same way Reassign caps it at the number assigned.
+	MinSeniors int
+	// History counts how many of the author's recent pull requests each user
+	// reviewed. It is nil unless the rotate mode is on.
+	History map[string]int
+	// Fallbacks are tried in order for the seats owners and Pool left empty.
+	Fallbacks []Fallback
+	// Now is when the reviewers are picked. Candidates in their working hours
+	// at Now go before those off work; a zero Now ignores working hours.
+	Now time.Time
+}
+
+// Pick chooses up to Limit reviewers. Owners of the touched code come first,
+// round-robin across the matched rules so every rule gets a reviewer before
+// any gets a second one. Remaining seats go to pool members covering required
+// skills nobody picked has yet, then to the rest of the pool and finally to
+// the fallback teams, one team after another. Among equally
+// suitable candidates preferred ones go first, then those at work right now,
+// then those who reviewed the author least recently, then the input order
+// decides. Finally the latest non-senior
+// picks give way to seniors until the seniority rule holds. The rule never
+// asks for more seniors than there are reviewers picked, so a small team is
+// not refused for lack of seats; but non-seniors do not stand in for missing
+// seniors, and model.ErrNoSenior is returned when neither the team nor its
+// fallbacks have enough of them. The required skills left uncovered by the
+// final picks are returned alongside.
+func Pick(in Input) ([]*model.ReviewerMatch, []string, error) {
+	owners := make([]Owners, 0, len(in.Owners))
+	for _, group := range in.Owners {
+		owners = append(owners, Owners{Rule: group.Rule, Candidates: Rank(group.Candidates, in.History, in.Now)})
+	}
+	in.Owners = owners
+	in.Pool = Rank(in.Pool, in.History, in.Now)
+
+	fallbacks := make([]Fallback, 0, len(in.Fallbacks))
+	for _, fb := range in.Fallbacks {
+		fallbacks = append(fallbacks, Fallback{Team: fb.Team, Candidates: Rank(fb.Candidates, in.History, in.Now)})
+	}
+	in.Fallbacks = fallbacks
+
+	p := newPicker(in)
+
+	for len(p.picked) < in.Limit {
+		progress := false
+		for _, group := range in.Owners {
+			if len(p.picked) == in.Limit {
+				break
+			}
+			if c, ok := p.best(group.Candidates, false); ok {
+				p.take(c, group.Rule, "")
+				progress = true
+			}
+		}
+		if !progress {
+			break
+		}
+	}
+
+	for len(p.picked) < in.Limit && len(p.uncovered) > 0 {
+		c, ok := p.best(in.Pool, true)
+		if !ok {
+			break
+		}
+		p.take(c, nil, "")
+	}
+
+	for _, c := range in.Pool {
+		if len(p.picked) == in.Limit {
+			break
+		}
+		if !p.chosen[c.UserID] {
+			p.take(c, nil, "")
+		}
+	}
+
+	for _, fb := range in.Fallbacks {
+		for len(p.picked) < in.Limit {
+			c, ok := p.best(fb.Candidates, false)
+			if !ok {
+				break
+			}
+			p.take(c, nil, fb.Team)
+		}
+	}
+
+	if err := p.enforceSeniors(in); err != nil {
+		return nil, nil, err
+	}
+
+	covered := make(map[string]bool)
+	for _, m := range p.picked {
+		for _, skill := range m.MatchedSkills {
+			covered[skill] = true
+		}
+	}
+	uncovered := make([]string, 0)
+	for skill := range p.required {
+		if !covered[skill] {
+			uncovered = append(uncovered, skill)
+		}
+	}
+	sort.Strings(uncovered)
+	return p.picked, uncovered, nil
+}
+
+// Rank returns a copy of candidates with the preferred ones first, then those
+// at work at now, then ordered by how many recent pull requests of the author
+// they reviewed, keeping the order otherwise. A zero now skips the working
+// hours.
+func Rank(candidates []Candidate, history map[string]int, now time.Time) []Candidate {
+	working := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		working[c.UserID] = Working(c, now)
+	}
+
+	sorted := append([]Candidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Preferred != b.Preferred {
+			return a.Preferred
+		}
+		if working[a.UserID] != working[b.UserID] {
+			return working[a.UserID]
+		}
+		return history[a.UserID] < history[b.UserID]
+	})
+	return sorted
+}
+
+// Working tells whether the candidate is at work at now. A zero now and a
+// schedule that does not parse count as at work.
+func Working(c Candidate, now time.Time) bool {
+	if now.IsZero() {
+		return true
+	}
+	cal, err := calendar.New(c.Schedule, c.Holidays)
+	if err != nil {
+		return true
+	}
+	return cal.Working(now)
+}
+
+// ReviewerIDs lists the user ids of the picked reviewers.
+func ReviewerIDs(matches []*model.ReviewerMatch) []string {
+	ids := make([]string, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
+
+type picker struct {
+	required  map[string]bool
+	uncovered map[string]bool
+	chosen    map[string]bool
+	seniors   map[string]bool
+	picked    []*model.ReviewerMatch
+}
+
+func newPicker(in Input) *picker {
+	p := &picker{
+		required:  make(map[string]bool),
+		uncovered: make(map[string]bool),
+		chosen:    make(map[string]bool),
+		seniors:   make(map[string]bool),
+		picked:    make([]*model.ReviewerMatch, 0, in.Limit),
+	}
+	for _, skill := range in.RequiredSkills {
+		p.required[skill] = true
+		p.uncovered[skill] = true
+	}
+	return p
+}
+
+// best returns the first not yet chosen candidate covering the most
+// uncovered skills. With needGain set candidates covering nothing new are
+// skipped.
+func (p *picker) best(candidates []Candidate, needGain bool) (Candidate, bool) {
+	return p.bestOf(candidates, needGain, false)
+}
+
+func (p *picker) bestOf(candidates []Candidate, needGain, seniorOnly bool) (Candidate, bool) {
+	var (
+		found    Candidate
+		ok       bool
+		bestGain = -1
+	)
+	for _, c := range candidates {
+		if p.chosen[c.UserID] || (seniorOnly && !c.Senior) {
+			continue
+		}
+		gain := 0
+		for _, skill := range c.Skills {
+			if p.uncovered[skill] {
+				gain++
+			}
+		}
+		if needGain && gain == 0 {
+			continue
+		}
+		if gain > bestGain {
+			found, ok, bestGain = c, true, gain
+		}
+	}
+	return found, ok
+}
+
+func (p *picker) take(c Candidate, rule *model.OwnershipRule, fallbackTeam string) {
+	match := &model.ReviewerMatch{UserID: c.UserID, MatchedRule: rule, FallbackTeam: fallbackTeam}
+	for _, skill := range c.Skills {
+		if p.required[skill] {
+			match.MatchedSkills = append(match.MatchedSkills, skill)
+			delete(p.uncovered, skill)
+		}
+	}
+	p.chosen[c.UserID] = true
+	if c.Senior {
+		p.seniors[c.UserID] = true
+	}
+	p.picked = append(p.picked, match)
+}
+
+func (p *picker) enforceSeniors(in Input) error {
+	need := min(in.MinSeniors, in.Limit, len(p.picked)) - len(p.seniors)
+	for ; need > 0; need-- {
+		if len(p.picked) == in.Limit {
+			for i := len(p.picked) - 1; i >= 0; i-- {
+				if !p.seniors[p.picked[i].UserID] {
+					p.drop(i)
+					break
+				}
+			}
+		}
+
+		c, rule, team, ok := p.bestSenior(in)
+		if !ok {
+			return model.ErrNoSenior
+		}
+		p.take(c, rule, team)
+	}
+	return nil
+}
+
+// drop removes the i-th pick and gives the required skills only it covered
+// back to the uncovered ones. The dropped candidate is not picked again.
+func (p *picker) drop(i int) {
+	p.picked = append(p.picked[:i], p.picked[i+1:]...)
+
+	for skill := range p.required {
+		p.uncovered[skill] = true
+	}
+	for _, m := range p.picked {
+		for _, skill := range m.MatchedSkills {
+			delete(p.uncovered, skill)
+		}
+	}
+}
+
+// bestSenior looks for an unpicked senior among the owners first, then in
+// the pool and the fallback teams.
+func (p *picker) bestSenior(in Input) (Candidate, *model.OwnershipRule, string, bool) {
+	for _, group := range in.Owners {
+		if c, ok := p.bestOf(group.Candidates, false, true); ok {
+			return c, group.Rule, "", true
+		}
+	}
+	if c, ok := p.bestOf(in.Pool, false, true); ok {
+		return c, nil, "", true
+	}
+	for _, fb := range in.Fallbacks {
+		if c, ok := p.bestOf(fb.Candidates, false, true); ok {
+			return c, nil, fb.Team, true
+		}
+	}
+	return Candidate{}, nil, "", false
+}
@@ -0,0 +1,339 @@
+package selection
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+)
+
+func ids(userIDs ...string) []Candidate {
+	candidates := make([]Candidate, 0, len(userIDs))
+	for _, id := range userIDs {
+		candidates = append(candidates, Candidate{UserID: id})
+	}
+	return candidates
+}
+
+func TestPickOwnersRoundRobin(t *testing.T) {
+	goRule := &model.OwnershipRule{Line: 1, Pattern: "*.go"}
+	docsRule := &model.OwnershipRule{Line: 2, Pattern: "/docs/"}
+
+	picked, _, _ := Pick(Input{
+		Limit: 2,
+		Owners: []Owners{
+			{Rule: goRule, Candidates: ids("u2", "u3")},
+			{Rule: docsRule, Candidates: ids("u2", "u5")},
+		},
+		Pool: ids("u7", "u8"),
+	})
+
+	if len(picked) != 2 {
+		t.Fatalf("expected 2 reviewers, got %d", len(picked))
+	}
+	if picked[0].UserID != "u2" || picked[0].MatchedRule != goRule {
+		t.Errorf("unexpected first reviewer: %+v", picked[0])
+	}
+	if picked[1].UserID != "u5" || picked[1].MatchedRule != docsRule {
+		t.Errorf("unexpected second reviewer: %+v", picked[1])
+	}
+}
+
+func TestPickFallsBackToPool(t *testing.T) {
+	rule := &model.OwnershipRule{Line: 1, Pattern: "*.go"}
+
+	picked, _, _ := Pick(Input{
+		Limit:  2,
+		Owners: []Owners{{Rule: rule, Candidates: ids("u2")}},
+		Pool:   ids("u2", "u3"),
+	})
+
+	got := ReviewerIDs(picked)
+	if len(got) != 2 || got[0] != "u2" || got[1] != "u3" {
+		t.Fatalf("unexpected reviewers: %v", got)
+	}
+	if picked[1].MatchedRule != nil {
+		t.Errorf("pool reviewer must not report a rule: %+v", picked[1])
+	}
+}
+
+func TestPickCoversRequiredSkills(t *testing.T) {
+	picked, uncovered, _ := Pick(Input{
+		Limit: 2,
+		Pool: []Candidate{
+			{UserID: "u2", Skills: []string{"css"}},
+			{UserID: "u3", Skills: []string{"go"}},
+			{UserID: "u4", Skills: []string{"go", "terraform"}},
+			{UserID: "u5", Skills: []string{"terraform"}},
+		},
+		RequiredSkills: []string{"go", "terraform"},
+	})
+
+	if len(picked) != 2 || picked[0].UserID != "u4" {
+		t.Fatalf("expected u4 first, got %v", ReviewerIDs(picked))
+	}
+	if len(picked[0].MatchedSkills) != 2 {
+		t.Errorf("unexpected skills: %v", picked[0].MatchedSkills)
+	}
+	// nobody covers anything new, so the rest of the pool keeps its order
+	if picked[1].UserID != "u2" || len(picked[1].MatchedSkills) != 0 {
+		t.Errorf("unexpected second reviewer: %+v", picked[1])
+	}
+	if len(uncovered) != 0 {
+		t.Errorf("expected all skills covered, left %v", uncovered)
+	}
+}
+
+func TestPickReportsUncoveredSkills(t *testing.T) {
+	picked, uncovered, _ := Pick(Input{
+		Limit: 2,
+		Pool: []Candidate{
+			{UserID: "u2", Skills: []string{"css"}},
+			{UserID: "u3"},
+		},
+		RequiredSkills: []string{"rust", "css"},
+	})
+
+	got := ReviewerIDs(picked)
+	if len(got) != 2 || got[0] != "u2" || got[1] != "u3" {
+		t.Fatalf("unexpected reviewers: %v", got)
+	}
+	if len(uncovered) != 1 || uncovered[0] != "rust" {
+		t.Errorf("expected rust uncovered, got %v", uncovered)
+	}
+}
+
+func TestPickOwnerWithSkillsFirst(t *testing.T) {
+	rule := &model.OwnershipRule{Line: 1, Pattern: "infra/"}
+
+	picked, _, _ := Pick(Input{
+		Limit: 1,
+		Owners: []Owners{{Rule: rule, Candidates: []Candidate{
+			{UserID: "u2"},
+			{UserID: "u3", Skills: []string{"terraform"}},
+		}}},
+		RequiredSkills: []string{"terraform"},
+	})
+
+	if len(picked) != 1 || picked[0].UserID != "u3" || picked[0].MatchedRule != rule {
+		t.Errorf("unexpected reviewers: %+v", picked)
+	}
+}
+
+func TestPickReplacesJuniorWithSenior(t *testing.T) {
+	rule := &model.OwnershipRule{Line: 1, Pattern: "*.go"}
+
+	picked, _, err := Pick(Input{
+		Limit:  2,
+		Owners: []Owners{{Rule: rule, Candidates: ids("u2")}},
+		Pool: []Candidate{
+			{UserID: "u3"},
+			{UserID: "u4", Senior: true},
+		},
+		MinSeniors: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := ReviewerIDs(picked)
+	if len(got) != 2 || got[0] != "u2" || got[1] != "u4" {
+		t.Errorf("expected the owner and the senior, got %v", got)
+	}
+}
+
+func TestPickNoSenior(t *testing.T) {
+	_, _, err := Pick(Input{
+		Limit:      2,
+		Pool:       ids("u2", "u3"),
+		MinSeniors: 1,
+	})
+	if !errors.Is(err, model.ErrNoSenior) {
+		t.Errorf("expected ErrNoSenior, got: %v", err)
+	}
+}
+
+func TestPickSeniorRuleCappedAtLimit(t *testing.T) {
+	picked, _, err := Pick(Input{
+		Limit: 1,
+		Pool: []Candidate{
+			{UserID: "u2"},
+			{UserID: "u3", Senior: true},
+		},
+		MinSeniors: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(picked) != 1 || picked[0].UserID != "u3" {
+		t.Errorf("unexpected reviewers: %v", ReviewerIDs(picked))
+	}
+}
+
+func TestPickSeniorRuleCappedAtPicked(t *testing.T) {
+	picked, _, err := Pick(Input{
+		Limit:      2,
+		Pool:       []Candidate{{UserID: "u3", Senior: true}},
+		MinSeniors: 2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(picked) != 1 || picked[0].UserID != "u3" {
+		t.Errorf("expected the only candidate, got %v", ReviewerIDs(picked))
+	}
+
+	_, _, err = Pick(Input{
+		Limit:      2,
+		Pool:       ids("u2"),
+		MinSeniors: 1,
+	})
+	if !errors.Is(err, model.ErrNoSenior) {
+		t.Errorf("expected a junior not to stand in for a senior, got: %v", err)
+	}
+}
+
+func TestPickSeniorSwapRecomputesCoverage(t *testing.T) {
+	picked, uncovered, err := Pick(Input{
+		Limit: 2,
+		Pool: []Candidate{
+			{UserID: "u2", Skills: []string{"go"}},
+			{UserID: "u3", Skills: []string{"sql"}},
+			{UserID: "u5", Senior: true},
+			{UserID: "u4", Senior: true, Skills: []string{"sql"}},
+		},
+		RequiredSkills: []string{"go", "sql"},
+		MinSeniors:     1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := ReviewerIDs(picked)
+	if len(got) != 2 || got[0] != "u2" || got[1] != "u4" {
+		t.Errorf("expected the senior covering the dropped skill, got %v", got)
+	}
+	if len(uncovered) != 0 {
+		t.Errorf("expected every skill covered, got %v", uncovered)
+	}
+}
+
+func TestPickPreferredFirst(t *testing.T) {
+	picked, _, err := Pick(Input{
+		Limit: 2,
+		Pool: []Candidate{
+			{UserID: "u2"},
+			{UserID: "u3", Skills: []string{"go"}},
+			{UserID: "u4", Preferred: true},
+		},
+		RequiredSkills: []string{"go"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// skill coverage still beats preference
+	got := ReviewerIDs(picked)
+	if len(got) != 2 || got[0] != "u3" || got[1] != "u4" {
+		t.Errorf("unexpected reviewers: %v", got)
+	}
+}
+
+func TestPickRotatesAwayFromRecentReviewers(t *testing.T) {
+	picked, _, err := Pick(Input{
+		Limit: 2,
+		Pool:  ids("u2", "u3", "u4"),
+		History: map[string]int{
+			"u2": 5,
+			"u3": 1,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := ReviewerIDs(picked)
+	if len(got) != 2 || got[0] != "u4" || got[1] != "u3" {
+		t.Errorf("expected the least recent reviewers, got %v", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := []Config{{}, {Mode: ModeRandom}, {Mode: ModeRotate, HistoryWindow: 5}}
+	for _, cfg := range valid {
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error: %v", cfg, err)
+		}
+	}
+
+	invalid := []Config{{Mode: "fair"}, {Mode: ModeRotate}}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+	}
+
+	if (Config{Mode: ModeRandom, HistoryWindow: 5}).Window() != 0 {
+		t.Errorf("random mode must not load history")
+	}
+}
+
+func TestPickFillsFromFallbacks(t *testing.T) {
+	picked, _, err := Pick(Input{
+		Limit: 2,
+		Pool:  ids("u2"),
+		Fallbacks: []Fallback{
+			{Team: "platform", Candidates: ids("u2", "u5")},
+			{Team: "sre", Candidates: ids("u6")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := ReviewerIDs(picked)
+	if len(got) != 2 || got[0] != "u2" || got[1] != "u5" {
+		t.Fatalf("unexpected reviewers: %v", got)
+	}
+	if picked[0].FallbackTeam != "" || picked[1].FallbackTeam != "platform" {
+		t.Errorf("unexpected fallback marks: %+v %+v", picked[0], picked[1])
+	}
+}
+
+func TestPickSeniorFromFallback(t *testing.T) {
+	picked, _, err := Pick(Input{
+		Limit:      2,
+		Pool:       ids("u2", "u3"),
+		MinSeniors: 1,
+		Fallbacks: []Fallback{
+			{Team: "platform", Candidates: []Candidate{{UserID: "u5", Senior: true}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if picked[1].UserID != "u5" || picked[1].FallbackTeam != "platform" {
+		t.Errorf("expected the fallback senior, got %+v", picked[1])
+	}
+}
+
+func TestPickPrefersCandidatesAtWork(t *testing.T) {
+	// 03:00 UTC is night in Berlin and the middle of the working day in Tokyo
+	now := time.Date(2024, 3, 5, 3, 0, 0, 0, time.UTC)
+	berlin := model.WorkSchedule{Timezone: "Europe/Berlin", WorkStart: "09:00", WorkEnd: "17:00"}
+	tokyo := model.WorkSchedule{Timezone: "Asia/Tokyo", WorkStart: "09:00", WorkEnd: "17:00"}
+
+	picked, _, _ := Pick(Input{
+		Limit: 1,
+		Pool: []Candidate{
+			{UserID: "u2", Schedule: berlin},
+			{UserID: "u3", Schedule: tokyo},
+		},
+		Now: now,
+	})
+
+	if got := ReviewerIDs(picked); len(got) != 1 || got[0] != "u3" {
+		t.Fatalf("expected the reviewer at work, got %v", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package sla watches review seats against their team's review SLA. A
+// background Worker flags the seats whose reviewer missed the deadline,
+// optionally hands them over to another candidate and emits an event for
+// every miss. The SLA counts business time: only the reviewer's working
+// hours outside their team's holidays.
+package sla
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/calendar"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/notify"
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/repository"
+)
+
+// Deadline is when a review assigned at assignedAt is due under the SLA,
+// counting only the working time of cal. A nil cal counts wall clock time.
+func Deadline(assignedAt time.Time, sla model.ReviewSLA, cal *calendar.Calendar) time.Time {
+	return cal.Add(assignedAt, time.Duration(sla.Minutes)*time.Minute)
+}
+
+// Emitter publishes review events.
+type Emitter interface {
+	Emit(ctx context.Context, event *model.ReviewEvent) error
+}
+
+// LogEmitter writes review events to the log.
+type LogEmitter struct {
+	Logger slog.Logger
+}
+
+func (e *LogEmitter) Emit(ctx context.Context, event *model.ReviewEvent) error {
+	e.Logger.Warn("review event",
+		slog.String("kind", event.Kind),
+		slog.String("pull_request_id", event.PullRequestID),
+		slog.String("reviewer_id", event.ReviewerID),
+		slog.String("team_name", event.TeamName),
+		slog.String("reassigned_to", event.ReassignedTo),
+	)
+	return nil
+}
+
+// Worker flags the reviews that missed their team's SLA.
+type Worker struct {
+	Logger   slog.Logger
+	Repo     repository.PullRequestRepository
+	Emitter  Emitter
+	Interval time.Duration
+	// Now is the clock the deadlines are checked against.
+	Now func() time.Time
+	// Notifier tells the reviewers about automatic reassignments; nil sends
+	// nothing.
+	Notifier notify.Notifier
+}
+
+func NewWorker(logger slog.Logger, repo repository.PullRequestRepository, emitter Emitter, interval time.Duration) *Worker {
+	return &Worker{
+		Logger:   logger,
+		Repo:     repo,
+		Emitter:  emitter,
+		Interval: interval,
+		Now:      time.Now,
+	}
+}
+
+// Run checks the reviews every Interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Check(ctx); err != nil {
+			w.Logger.Error("review SLA check failed", slog.String("error", err.Error()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check makes one pass over the pending reviews. Every review past its
+// deadline is flagged as overdue, reassigned when its team asks for it, and
+// reported to the Emitter.
+func (w *Worker) Check(ctx context.Context) error {
+	pending, err := w.Repo.PendingReviews(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := w.Now()
+	for _, p := range pending {
+		cal, err := calendar.New(p.Schedule, p.Holidays)
+		if err != nil {
+			w.Logger.Warn("reviewer schedule ignored",
+				slog.String("reviewer_id", p.ReviewerID),
+				slog.String("error", err.Error()))
+		}
+		if now.Before(Deadline(p.AssignedAt, p.SLA, cal)) {
+			continue
+		}
+
+		marked, err := w.Repo.MarkOverdue(ctx, p.PullRequestID, p.ReviewerID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			continue
+		}
+
+		event := &model.ReviewEvent{
+			Kind: model.EventReviewOverdue,
+			OverdueReview: model.OverdueReview{
+				PullRequestID:   p.PullRequestID,
+				PullRequestName: p.PullRequestName,
+				ReviewerID:      p.ReviewerID,
+				TeamName:        p.SLA.TeamName,
+				AssignedAt:      p.AssignedAt,
+				OverdueAt:       now,
+			},
+		}
+
+		if p.SLA.AutoReassign {
+			event.ReassignedTo, err = w.reassign(ctx, p)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := w.Emitter.Emit(ctx, event); err != nil {
+			w.Logger.Error("failed to emit review event",
+				slog.String("pull_request_id", p.PullRequestID),
+				slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
+
+// reassign hands the overdue seat over to another candidate. When nobody can
+// take it, or the pull request changed in the meantime, the seat stays with
+// its reviewer and no one is returned.
+func (w *Worker) reassign(ctx context.Context, p *model.PendingReview) (string, error) {
+	pr, newReviewer, err := w.Repo.Reassign(ctx, p.PullRequestID, p.ReviewerID, "")
+	switch {
+	case err == nil:
+		w.notify(ctx, &model.Announcement{
+			Kind:        model.AnnounceReassigned,
+			PullRequest: pr,
+			Replaced:    p.ReviewerID,
+			ReplacedBy:  newReviewer,
+		}, notify.Reassigned(pr, p.ReviewerID, newReviewer))
+		return newReviewer, nil
+	case errors.Is(err, model.ErrNoCandidate),
+		errors.Is(err, model.ErrNoSenior),
+		errors.Is(err, model.ErrNotAssigned),
+		errors.Is(err, model.ErrPrMerged):
+		w.Logger.Info("overdue review kept",
+			slog.String("pull_request_id", p.PullRequestID),
+			slog.String("reviewer_id", p.ReviewerID),
+			slog.String("reason", err.Error()))
+		return "", nil
+	default:
+		return "", err
+	}
+}
+
+func (w *Worker) notify(ctx context.Context, announcement *model.Announcement, notices []*model.Notice) {
+	if w.Notifier == nil {
+		return
+	}
+	if err := w.Notifier.Announce(ctx, announcement); err != nil {
+		w.Logger.Error("failed to send announcement",
+			slog.String("kind", announcement.Kind),
+			slog.String("pull_request_id", announcement.PullRequest.PullRequestID),
+			slog.String("error", err.Error()))
+	}
+	for _, notice := range notices {
+		if err := w.Notifier.Notify(ctx, notice); err != nil {
+			w.Logger.Error("failed to send notice",
+				slog.String("kind", notice.Kind),
+				slog.String("user_id", notice.UserID),
+				slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sla
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/mocks"
+	model "github.com/evakaiing/PR-Reviewer-Assignment-Service/internal/model"
+	"go.uber.org/mock/gomock"
+)
+
+type recorder struct {
+	events []*model.ReviewEvent
+}
+
+func (r *recorder) Emit(ctx context.Context, event *model.ReviewEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func newTestWorker(repo *mocks.MockPullRequestRepository, now time.Time) (*Worker, *recorder) {
+	events := &recorder{}
+	w := NewWorker(*slog.New(slog.NewTextHandler(io.Discard, nil)), repo, events, time.Minute)
+	w.Now = func() time.Time { return now }
+	return w, events
+}
+
+func TestCheckFlagsReviewsPastDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockPullRequestRepository(ctrl)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sla := model.ReviewSLA{TeamName: "backend", Minutes: 60}
+
+	repo.EXPECT().PendingReviews(gomock.Any()).Return([]*model.PendingReview{
+		{PullRequestID: "pr-1", ReviewerID: "u1", AssignedAt: now.Add(-2 * time.Hour), SLA: sla},
+		{PullRequestID: "pr-2", ReviewerID: "u2", AssignedAt: now.Add(-time.Hour), SLA: sla},
+		{PullRequestID: "pr-3", ReviewerID: "u3", AssignedAt: now.Add(-30 * time.Minute), SLA: sla},
+	}, nil)
+	repo.EXPECT().MarkOverdue(gomock.Any(), "pr-1", "u1", now).Return(true, nil)
+	repo.EXPECT().MarkOverdue(gomock.Any(), "pr-2", "u2", now).Return(false, nil)
+
+	w, events := newTestWorker(repo, now)
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(events.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(events.events))
+	}
+	event := events.events[0]
+	if event.Kind != model.EventReviewOverdue || event.PullRequestID != "pr-1" || event.TeamName != "backend" || event.ReassignedTo != "" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
+
+func TestCheckReassignsOverdueReview(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockPullRequestRepository(ctrl)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	sla := model.ReviewSLA{TeamName: "backend", Minutes: 60, AutoReassign: true}
+
+	repo.EXPECT().PendingReviews(gomock.Any()).Return([]*model.PendingReview{
+		{PullRequestID: "pr-1", ReviewerID: "u1", AssignedAt: now.Add(-2 * time.Hour), SLA: sla},
+		{PullRequestID: "pr-2", ReviewerID: "u2", AssignedAt: now.Add(-2 * time.Hour), SLA: sla},
+	}, nil)
+	repo.EXPECT().MarkOverdue(gomock.Any(), "pr-1", "u1", now).Return(true, nil)
+	repo.EXPECT().Reassign(gomock.Any(), "pr-1", "u1", "").Return(&model.PullRequest{}, "u5", nil)
+	repo.EXPECT().MarkOverdue(gomock.Any(), "pr-2", "u2", now).Return(true, nil)
+	repo.EXPECT().Reassign(gomock.Any(), "pr-2", "u2", "").Return(nil, "", model.ErrNoCandidate)
+
+	w, events := newTestWorker(repo, now)
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(events.events) != 2 {
+		t.Fatalf("expected two events, got %d", len(events.events))
+	}
+	if events.events[0].ReassignedTo != "u5" {
+		t.Errorf("expected pr-1 to go to u5, got %q", events.events[0].ReassignedTo)
+	}
+	if events.events[1].ReassignedTo != "" {
+		t.Errorf("expected pr-2 to stay with its reviewer, got %q", events.events[1].ReassignedTo)
+	}
+}
+
+func TestCheckCountsBusinessTime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockPullRequestRepository(ctrl)
+	// Monday 10:00 UTC
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	sla := model.ReviewSLA{TeamName: "backend", Minutes: 120}
+	schedule := model.WorkSchedule{Timezone: "UTC", WorkStart: "09:00", WorkEnd: "17:00"}
+
+	repo.EXPECT().PendingReviews(gomock.Any()).Return([]*model.PendingReview{
+		// Friday 16:00: one hour on Friday and one on Monday morning
+		{PullRequestID: "pr-1", ReviewerID: "u1", AssignedAt: time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC), SLA: sla, Schedule: schedule},
+		// Friday 16:30, but Monday is a team holiday
+		{PullRequestID: "pr-2", ReviewerID: "u2", AssignedAt: time.Date(2024, 3, 1, 16, 30, 0, 0, time.UTC), SLA: sla, Schedule: schedule, Holidays: []string{"2024-03-04"}},
+	}, nil)
+	repo.EXPECT().MarkOverdue(gomock.Any(), "pr-1", "u1", now).Return(true, nil)
+
+	w, events := newTestWorker(repo, now)
+	if err := w.Check(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+
+	if len(events.events) != 1 || events.events[0].PullRequestID != "pr-1" {
+		t.Fatalf("expected only pr-1 to be overdue, got %+v", events.events)
+	}
+}
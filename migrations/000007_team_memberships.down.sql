@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN team_name VARCHAR(255) NOT NULL DEFAULT '';
+
+UPDATE users u
+SET team_name = COALESCE((
+    SELECT t.team_name
+    FROM team_members tm
+    JOIN teams t ON t.team_id = tm.team_id
+    WHERE tm.user_id = u.user_id
+    ORDER BY tm.joined_at, t.team_name
+    LIMIT 1
+), '');
+
+CREATE INDEX idx_users_team_name ON users(team_name);
+
+DROP INDEX idx_pull_requests_team_id;
+ALTER TABLE pull_requests DROP COLUMN team_id;
\ No newline at end of file
@@ -0,0 +1,27 @@
+INSERT INTO teams (team_name)
+SELECT DISTINCT team_name FROM users WHERE team_name <> ''
+ON CONFLICT (team_name) DO NOTHING;
+
+INSERT INTO team_members (team_id, user_id, is_active)
+SELECT t.team_id, u.user_id, TRUE
+FROM users u
+JOIN teams t ON t.team_name = u.team_name
+ON CONFLICT (team_id, user_id) DO NOTHING;
+
+ALTER TABLE pull_requests
+    ADD COLUMN team_id INTEGER
+    , ADD CONSTRAINT pull_requests_team_id_fkey
+        FOREIGN KEY (team_id)
+        REFERENCES teams(team_id)
+        ON DELETE SET NULL;
+
+UPDATE pull_requests pr
+SET team_id = t.team_id
+FROM users u
+JOIN teams t ON t.team_name = u.team_name
+WHERE u.user_id = pr.author_id;
+
+CREATE INDEX idx_pull_requests_team_id ON pull_requests(team_id);
+
+DROP INDEX idx_users_team_name;
+ALTER TABLE users DROP COLUMN team_name;
\ No newline at end of file
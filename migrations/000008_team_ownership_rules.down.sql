@@ -0,0 +1 @@
+DROP TABLE team_ownership_rules;
\ No newline at end of file
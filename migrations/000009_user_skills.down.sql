@@ -0,0 +1 @@
+DROP TABLE user_skills;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE users
+    ADD COLUMN level VARCHAR(16)
+        CHECK (level IN ('junior', 'middle', 'senior'));
+
+ALTER TABLE teams
+    ADD COLUMN min_senior_reviewers INTEGER NOT NULL DEFAULT 0
+        CHECK (min_senior_reviewers >= 0);
@@ -0,0 +1,2 @@
+DROP VIEW reviewer_load;
+ALTER TABLE users DROP COLUMN max_open_reviews;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE reviewer_preferences;
\ No newline at end of file
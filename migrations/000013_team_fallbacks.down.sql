@@ -0,0 +1 @@
+DROP TABLE team_fallbacks;
\ No newline at end of file
@@ -0,0 +1,20 @@
+CREATE TABLE team_fallbacks (
+    team_id INTEGER NOT NULL
+    , position INTEGER NOT NULL
+    , fallback_team_id INTEGER NOT NULL
+    , created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+
+    , PRIMARY KEY (team_id, position)
+    , UNIQUE (team_id, fallback_team_id)
+    , CHECK (fallback_team_id <> team_id)
+
+    , CONSTRAINT team_fallbacks_team_id_fkey
+        FOREIGN KEY (team_id)
+        REFERENCES teams(team_id)
+        ON DELETE CASCADE
+
+    , CONSTRAINT team_fallbacks_fallback_team_id_fkey
+        FOREIGN KEY (fallback_team_id)
+        REFERENCES teams(team_id)
+        ON DELETE CASCADE
+);
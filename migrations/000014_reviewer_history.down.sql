@@ -0,0 +1 @@
+DROP TABLE reviewer_history;
\ No newline at end of file
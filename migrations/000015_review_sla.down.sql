@@ -0,0 +1,3 @@
+DROP INDEX idx_pr_reviewers_overdue_at;
+ALTER TABLE pull_request_reviewers DROP COLUMN overdue_at, DROP COLUMN reviewed_at;
+ALTER TABLE teams DROP COLUMN sla_auto_reassign, DROP COLUMN review_sla_minutes;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE teams
+    ADD COLUMN review_sla_minutes INTEGER
+        CHECK (review_sla_minutes > 0)
+    , ADD COLUMN sla_auto_reassign BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN reviewed_at TIMESTAMP WITH TIME ZONE
+    , ADD COLUMN overdue_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_pr_reviewers_overdue_at ON pull_request_reviewers(overdue_at);
@@ -0,0 +1,2 @@
+DROP TABLE team_holidays;
+ALTER TABLE users DROP CONSTRAINT users_work_hours_check, DROP COLUMN work_end, DROP COLUMN work_start, DROP COLUMN timezone;
\ No newline at end of file
@@ -0,0 +1,19 @@
+ALTER TABLE users
+    ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC'
+    , ADD COLUMN work_start TEXT
+    , ADD COLUMN work_end TEXT
+    , ADD CONSTRAINT users_work_hours_check
+        CHECK ((work_start IS NULL) = (work_end IS NULL));
+
+CREATE TABLE team_holidays (
+    team_id INTEGER NOT NULL
+    , holiday DATE NOT NULL
+    , name TEXT NOT NULL DEFAULT ''
+
+    , PRIMARY KEY (team_id, holiday)
+
+    , CONSTRAINT team_holidays_team_id_fkey
+        FOREIGN KEY (team_id)
+        REFERENCES teams(team_id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN last_reminded_at;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE notification_opt_outs;
+ALTER TABLE users DROP COLUMN email;
\ No newline at end of file
@@ -0,0 +1,14 @@
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(255);
+
+CREATE TABLE notification_opt_outs (
+    user_id VARCHAR(255) NOT NULL
+    , kind VARCHAR(16) NOT NULL CHECK (kind IN ('assigned', 'replaced', 'digest'))
+
+    , PRIMARY KEY (user_id, kind)
+
+    , CONSTRAINT notification_opt_outs_user_id_fkey
+        FOREIGN KEY (user_id)
+        REFERENCES users(user_id)
+        ON DELETE CASCADE
+);
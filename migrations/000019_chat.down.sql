@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN chat_handle;
+ALTER TABLE teams DROP COLUMN chat_direct_messages;
+ALTER TABLE teams DROP COLUMN chat_webhook_url;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE teams
+    ADD COLUMN chat_webhook_url VARCHAR(2048)
+    , ADD COLUMN chat_direct_messages BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE users
+    ADD COLUMN chat_handle VARCHAR(255);
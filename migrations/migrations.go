@@ -0,0 +1,10 @@
+package migrations
+
+import "embed"
+
+// SQLite holds the schema for the SQLite storage backend. It is applied by the
+// application itself on startup, unlike the Postgres migrations which are run
+// by the migrate container.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -0,0 +1,12 @@
+CREATE TABLE users (
+    user_id TEXT PRIMARY KEY
+    , username TEXT NOT NULL UNIQUE
+    , team_name TEXT NOT NULL
+    , is_active BOOLEAN NOT NULL DEFAULT TRUE
+    , created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    , updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_users_username ON users(username);
+CREATE INDEX idx_users_is_active ON users(is_active);
+CREATE INDEX idx_users_team_name ON users(team_name);
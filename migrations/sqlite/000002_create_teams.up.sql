@@ -0,0 +1,8 @@
+CREATE TABLE teams (
+    team_id INTEGER PRIMARY KEY AUTOINCREMENT
+    , team_name TEXT NOT NULL UNIQUE
+    , created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    , updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_teams_team_name ON teams(team_name);
@@ -0,0 +1,11 @@
+CREATE TABLE pull_request_statuses (
+    status_id INTEGER PRIMARY KEY AUTOINCREMENT
+    , status_name TEXT NOT NULL UNIQUE
+    , created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO pull_request_statuses (status_name) VALUES
+    ('OPEN'),
+    ('MERGED');
+
+CREATE INDEX idx_pr_statuses_name ON pull_request_statuses(status_name);
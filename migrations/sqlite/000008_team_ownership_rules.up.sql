@@ -0,0 +1,14 @@
+CREATE TABLE team_ownership_rules (
+    team_id INTEGER NOT NULL
+    , position INTEGER NOT NULL
+    , pattern TEXT NOT NULL
+    , owners TEXT NOT NULL DEFAULT ''
+    , created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+
+    , PRIMARY KEY (team_id, position)
+
+    , CONSTRAINT team_ownership_rules_team_id_fkey
+        FOREIGN KEY (team_id)
+        REFERENCES teams(team_id)
+        ON DELETE CASCADE
+);
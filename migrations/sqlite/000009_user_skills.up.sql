@@ -0,0 +1,13 @@
+CREATE TABLE user_skills (
+    user_id TEXT NOT NULL
+    , skill TEXT NOT NULL
+
+    , PRIMARY KEY (user_id, skill)
+
+    , CONSTRAINT user_skills_user_id_fkey
+        FOREIGN KEY (user_id)
+        REFERENCES users(user_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_user_skills_skill ON user_skills(skill);
@@ -0,0 +1,2 @@
+ALTER TABLE teams DROP COLUMN min_senior_reviewers;
+ALTER TABLE users DROP COLUMN level;
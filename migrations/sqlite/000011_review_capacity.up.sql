@@ -0,0 +1,16 @@
+ALTER TABLE users
+    ADD COLUMN max_open_reviews INTEGER
+        CHECK (max_open_reviews > 0);
+
+CREATE VIEW reviewer_load AS
+SELECT
+    u.user_id
+    , u.max_open_reviews
+    , (
+        SELECT COUNT(*)
+        FROM pull_request_reviewers prr
+        JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+        JOIN pull_request_statuses ps ON ps.status_id = pr.status_id
+        WHERE prr.reviewer_user_id = u.user_id AND ps.status_name = 'OPEN'
+    ) AS open_reviews
+FROM users u;
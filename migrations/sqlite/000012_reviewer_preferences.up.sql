@@ -0,0 +1,18 @@
+CREATE TABLE reviewer_preferences (
+    author_id TEXT NOT NULL
+    , reviewer_id TEXT NOT NULL
+    , kind TEXT NOT NULL CHECK (kind IN ('blocked', 'preferred'))
+    , created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+
+    , PRIMARY KEY (author_id, reviewer_id)
+
+    , CONSTRAINT reviewer_preferences_author_id_fkey
+        FOREIGN KEY (author_id)
+        REFERENCES users(user_id)
+        ON DELETE CASCADE
+
+    , CONSTRAINT reviewer_preferences_reviewer_id_fkey
+        FOREIGN KEY (reviewer_id)
+        REFERENCES users(user_id)
+        ON DELETE CASCADE
+);
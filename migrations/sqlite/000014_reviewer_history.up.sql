@@ -0,0 +1,15 @@
+CREATE TABLE reviewer_history (
+    event_id INTEGER PRIMARY KEY AUTOINCREMENT
+    , pull_request_id TEXT NOT NULL
+    , reviewer_user_id TEXT NOT NULL
+    , action TEXT NOT NULL CHECK (action IN ('added', 'removed', 'reassigned'))
+    , replaced_user_id TEXT
+    , created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+
+    , CONSTRAINT reviewer_history_pull_request_id_fkey
+        FOREIGN KEY (pull_request_id)
+        REFERENCES pull_requests(pull_request_id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_reviewer_history_pull_request_id ON reviewer_history(pull_request_id, created_at);
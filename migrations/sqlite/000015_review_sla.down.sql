@@ -0,0 +1,5 @@
+DROP INDEX idx_pr_reviewers_overdue_at;
+ALTER TABLE pull_request_reviewers DROP COLUMN overdue_at;
+ALTER TABLE pull_request_reviewers DROP COLUMN reviewed_at;
+ALTER TABLE teams DROP COLUMN sla_auto_reassign;
+ALTER TABLE teams DROP COLUMN review_sla_minutes;
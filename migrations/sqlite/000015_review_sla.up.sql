@@ -0,0 +1,14 @@
+ALTER TABLE teams
+    ADD COLUMN review_sla_minutes INTEGER
+        CHECK (review_sla_minutes > 0);
+
+ALTER TABLE teams
+    ADD COLUMN sla_auto_reassign BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN reviewed_at TIMESTAMP;
+
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN overdue_at TIMESTAMP;
+
+CREATE INDEX idx_pr_reviewers_overdue_at ON pull_request_reviewers(overdue_at);
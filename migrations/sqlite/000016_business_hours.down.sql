@@ -0,0 +1,4 @@
+DROP TABLE team_holidays;
+ALTER TABLE users DROP COLUMN work_end;
+ALTER TABLE users DROP COLUMN work_start;
+ALTER TABLE users DROP COLUMN timezone;
@@ -0,0 +1,21 @@
+ALTER TABLE users
+    ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+ALTER TABLE users
+    ADD COLUMN work_start TEXT;
+
+ALTER TABLE users
+    ADD COLUMN work_end TEXT;
+
+CREATE TABLE team_holidays (
+    team_id INTEGER NOT NULL
+    , holiday TEXT NOT NULL
+    , name TEXT NOT NULL DEFAULT ''
+
+    , PRIMARY KEY (team_id, holiday)
+
+    , CONSTRAINT team_holidays_team_id_fkey
+        FOREIGN KEY (team_id)
+        REFERENCES teams(team_id)
+        ON DELETE CASCADE
+);
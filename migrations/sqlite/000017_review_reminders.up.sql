@@ -0,0 +1,2 @@
+ALTER TABLE users
+    ADD COLUMN last_reminded_at TIMESTAMP;
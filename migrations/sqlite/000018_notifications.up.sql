@@ -0,0 +1,14 @@
+ALTER TABLE users
+    ADD COLUMN email TEXT;
+
+CREATE TABLE notification_opt_outs (
+    user_id TEXT NOT NULL
+    , kind TEXT NOT NULL CHECK (kind IN ('assigned', 'replaced', 'digest'))
+
+    , PRIMARY KEY (user_id, kind)
+
+    , CONSTRAINT notification_opt_outs_user_id_fkey
+        FOREIGN KEY (user_id)
+        REFERENCES users(user_id)
+        ON DELETE CASCADE
+);
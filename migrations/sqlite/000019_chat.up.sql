@@ -0,0 +1,8 @@
+ALTER TABLE teams
+    ADD COLUMN chat_webhook_url TEXT;
+
+ALTER TABLE teams
+    ADD COLUMN chat_direct_messages BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE users
+    ADD COLUMN chat_handle TEXT;
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN deleted_at;
\ No newline at end of file